@@ -1,84 +1,251 @@
 package api
 
 import (
-	_ "crud/api/docs"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
 	"crud/api/handler"
+	"crud/api/http"
 	"crud/config"
-	"crud/pkg/helper"
+	"crud/models"
+	"crud/pkg/audit"
+	"crud/pkg/errs"
+	"crud/pkg/logging"
+	"crud/pkg/mail"
+	"crud/pkg/metrics"
+	"crud/pkg/token"
+	"crud/pkg/validation"
+	"crud/service"
 	"crud/storage"
-	"errors"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetUpApi(cfg *config.Config, r *gin.Engine, storage storage.StorageI, cache storage.CacheI) {
+func SetUpApi(cfg *config.Config, log *slog.Logger, metrics *metrics.Metrics, r *gin.Engine, storage storage.StorageI, cache storage.CacheI, tokens *token.Issuer, mailer mail.Mailer) error {
 
-	handlerV1 := handler.NewHandlerV1(cfg, storage, cache)
+	err := validation.Register()
+	if err != nil {
+		return err
+	}
 
-	r.Use(customCORSMiddleware())
+	// gin trusts every proxy by default, which lets clients pick their IP
+	// with X-Forwarded-For.
+	err = r.SetTrustedProxies(strings.Fields(cfg.TrustedProxies))
+	if err != nil {
+		return fmt.Errorf("trusted proxies: %w", err)
+	}
+
+	services := service.NewService(cfg, log, metrics, storage, cache, tokens, mailer)
 
-	// v1 := r.Group("/v1")
-	// v2 := r.Group("/v2")
+	handlerV1 := handler.NewHandlerV1(cfg, log, services)
 
-	r.POST("/login", handlerV1.Login)
-	r.POST("/loginsuper", handlerV1.LoginSuper)
+	r.Use(requestIDMiddleware())
+	r.Use(auditMiddleware())
+	r.Use(tracingMiddleware())
+	r.Use(accessLogMiddleware(log))
+	r.Use(metricsMiddleware(metrics))
+	r.Use(recoveryMiddleware())
+	r.Use(customCORSMiddleware())
+	r.Use(timeoutMiddleware(cfg))
 
-	r.POST("/refreshclienttoken")
+	r.GET("/healthz", handlerV1.Healthz)
+	r.GET("/readyz", handlerV1.Readyz)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	r.GET("/.well-known/jwks.json", handlerV1.JWKS)
 
-	r.Use(checkTokenSuper())
-	r.Use(checkTokenClient())
-	r.POST("/book", handlerV1.CreateBook)
-	r.GET("/book/:id", handlerV1.GetBookById)
-	r.GET("/book", handlerV1.GetBookList)
-	r.PUT("/book/:id", handlerV1.UpdateBook)
-	r.DELETE("/book/:id", handlerV1.DeleteBook)
+	v1 := r.Group("/v1")
+	routesV1(v1, cfg, handlerV1, services, cache)
+	swaggerRoute(v1, swaggerV1)
 
-	r.POST("/user", handlerV1.CreateUser)
-	r.GET("/user/:id", handlerV1.GetUserById)
-	r.GET("/user", handlerV1.GetUserList)
-	r.PUT("/user/:id", handlerV1.UpdateUser)
-	r.DELETE("/user/:id", handlerV1.DeleteUser)
+	v2 := r.Group("/v2")
+	routesV2(v2, cfg, handlerV1, services, cache)
+	swaggerRoute(v2, swaggerV2)
 
-	r.POST("/order", handlerV1.CreateOrder)
-	r.GET("/order/:id", handlerV1.GetOrderById)
-	r.GET("/order", handlerV1.GetOrderList)
-	r.PUT("/order/:id", handlerV1.UpdateOrder)
-	r.DELETE("/order/:id", handlerV1.DeleteOrder)
+	// The unversioned routes are the v1 contract kept for existing clients.
+	legacy := r.Group("", deprecatedMiddleware(legacyDeprecatedAt, cfg.LegacyRoutesSunset.Time(), "/v1"))
+	routesV1(legacy, cfg, handlerV1, services, cache)
+	swaggerRoute(legacy, swaggerV1)
 
-	url := ginSwagger.URL("swagger/doc.json") // The url pointing to API definition
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
+	return nil
 }
 
-func checkTokenSuper() gin.HandlerFunc {
+// checkToken authenticates requests carrying an access token or an API key
+// in X-API-Key, and refuses requests with neither. Super admin tokens are
+// refused once their user is no longer an admin.
+func checkToken(auth *service.AuthService, apiKeys *service.APIKeyService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		if _, ok := ctx.Request.Header["Authorization"]; ok {
-			_, err := helper.ExtractClaims(ctx.Request.Header["Authorization"][0], config.Load().AuthSecretKey)
-			_, err2 := helper.ExtractClaims(ctx.Request.Header["Authorization"][0], config.Load().SuperAdmin)
-			if err != nil && err2 != nil {
-				ctx.AbortWithError(http.StatusForbidden, errors.New("not found password"))
+
+		if key := ctx.GetHeader(APIKeyHeader); key != "" {
+			checkAPIKey(ctx, apiKeys, key)
+			return
+		}
+
+		header := ctx.GetHeader("Authorization")
+		if header == "" {
+			httpapi.Error(ctx, errs.Unauthorized("authorization required"))
+			return
+		}
+
+		claims, err := auth.ParseToken(header)
+		if err != nil {
+			httpapi.Error(ctx, err)
+			return
+		}
+
+		if claims.Role == token.RoleSuper {
+			err = auth.CheckSuper(ctx.Request.Context(), claims.UserID)
+			if err != nil {
+				httpapi.Error(ctx, err)
 				return
-			} else {
-				ctx.Next()
 			}
 		}
+
+		setUser(ctx, claims)
+		ctx.Next()
 	}
 }
 
-func checkTokenClient() gin.HandlerFunc {
+// apiKeyResources maps the first segment of a route to the resource its
+// scopes are named after. Routes outside these cannot be called with a key.
+var apiKeyResources = map[string]string{
+	"book":  "books",
+	"user":  "users",
+	"order": "orders",
+}
+
+// checkAPIKey authenticates the request with the API key key, which must
+// grant <resource>:read for GET and HEAD and <resource>:write otherwise.
+func checkAPIKey(ctx *gin.Context, apiKeys *service.APIKeyService, key string) {
+
+	apiKey, err := apiKeys.Authenticate(ctx.Request.Context(), key)
+	if err != nil {
+		httpapi.Error(ctx, err)
+		return
+	}
+
+	segment, _, _ := strings.Cut(strings.TrimPrefix(routeKey(ctx), "/"), "/")
+
+	resource, ok := apiKeyResources[segment]
+	if !ok {
+		httpapi.Error(ctx, errs.Forbidden("api keys cannot access this route"))
+		return
+	}
+
+	scope := resource + ":write"
+	if ctx.Request.Method == http.MethodGet || ctx.Request.Method == http.MethodHead {
+		scope = resource + ":read"
+	}
+
+	if !service.HasScope(apiKey, scope) {
+		httpapi.Error(ctx, errs.Forbidden("api key lacks the %s scope", scope))
+		return
+	}
+
+	ctx.Set(httpapi.APIKeyIDKey, apiKey.Id)
+
+	actor := audit.ActorFrom(ctx.Request.Context())
+	actor.APIKeyID = apiKey.Id
+	ctx.Request = ctx.Request.WithContext(audit.WithActor(logging.With(ctx.Request.Context(), slog.String(httpapi.APIKeyIDKey, apiKey.Id)), actor))
+
+	ctx.Next()
+}
+
+// requireSuper only lets through requests with a super admin token of a
+// user that is still an admin.
+func requireSuper(auth *service.AuthService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		if _, ok := ctx.Request.Header["Authorization"]; ok {
-			_, err := helper.ExtractClaims(ctx.Request.Header["Authorization"][0], config.Load().AuthSecretKey)
-			_, err2 := helper.ExtractClaims(ctx.Request.Header["Authorization"][0], config.Load().Client)
-			if err != nil && err2 != nil {
-				ctx.AbortWithError(http.StatusForbidden, errors.New("not found password"))
-				return
-			} else {
-				ctx.Next()
-			}
+
+		header := ctx.GetHeader("Authorization")
+		if header == "" {
+			httpapi.Error(ctx, errs.Unauthorized("authorization required"))
+			return
+		}
+
+		claims, err := auth.ParseToken(header)
+		if err != nil {
+			httpapi.Error(ctx, err)
+			return
+		}
+
+		if claims.Role != token.RoleSuper {
+			httpapi.Error(ctx, errs.Forbidden("super admin token required"))
+			return
+		}
+
+		err = auth.CheckSuper(ctx.Request.Context(), claims.UserID)
+		if err != nil {
+			httpapi.Error(ctx, err)
+			return
+		}
+
+		setUser(ctx, claims)
+		ctx.Next()
+	}
+}
+
+// checkRevoked rejects tokens revoked by a logout, a password change or the
+// deletion of their user. It runs after the token checks and lets requests
+// made with an API key through. Unlike rate limiting it fails closed: a revoked token
+// must not work while the cache is down.
+func checkRevoked(auth *service.AuthService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		token, ok := ctx.Get(httpapi.TokenKey)
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		err := auth.CheckToken(ctx.Request.Context(), token.(*models.Token))
+		if err != nil {
+			httpapi.Error(ctx, err)
+			return
 		}
+
+		ctx.Next()
+	}
+}
+
+// setUser records the authenticated user for the handlers and the logs.
+func setUser(ctx *gin.Context, claims *token.Claims) {
+
+	if claims.Role == token.RoleSuper {
+		ctx.Set(httpapi.SuperAdminKey, true)
+	}
+
+	ctx.Set(httpapi.UserIDKey, claims.UserID)
+	ctx.Set(httpapi.TokenKey, &models.Token{
+		Id:        claims.ID,
+		User_id:   claims.UserID,
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+
+	actor := audit.ActorFrom(ctx.Request.Context())
+	actor.UserID = claims.UserID
+	ctx.Request = ctx.Request.WithContext(audit.WithActor(logging.With(ctx.Request.Context(), slog.String(httpapi.UserIDKey, claims.UserID)), actor))
+}
+
+// timeoutMiddleware puts the route's deadline on the request context, which
+// is passed down to storage and the cache. Queries still running when it
+// expires are cancelled and the request fails with TIMEOUT.
+func timeoutMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		timeout := cfg.Timeout(c.Request.Method, routeKey(c))
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
 	}
 }
 
@@ -89,7 +256,8 @@ func customCORSMiddleware() gin.HandlerFunc {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE, HEAD")
-		c.Header("Access-Control-Allow-Headers", "Platform-Id, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Platform-Id, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, traceparent, tracestate, If-Match, If-None-Match, Idempotency-Key, X-API-Key")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID, ETag, Idempotent-Replayed, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining")
 		c.Header("Access-Control-Max-Age", "3600")
 
 		if c.Request.Method == "OPTIONS" {
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+
+	"crud/api/http"
+	"crud/models"
+	"crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Register godoc
+// @ID register
+// @Router /register [POST]
+// @Summary Register
+// @Description Create an account and email a link to verify it. Login is refused until the email is verified.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param user body models.Register true "RegisterRequestBody"
+// @Success 201 {object} models.User "GetUserBody"
+// @Header 201 {string} ETag "Entity tag of the created version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 409 {object} httpapi.Response "Login or email is taken"
+// @Response 429 {object} httpapi.Response "Too many requests"
+// @Header 429 {string} Retry-After "Seconds until the request may be retried"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) Register(c *gin.Context) {
+	var req models.Register
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	resp, err := h.services.Account().Register(c.Request.Context(), &req)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	httpapi.SetETag(c, resp.Version)
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// VerifyEmail godoc
+// @ID verify_email
+// @Router /verify-email [POST]
+// @Summary Verify Email
+// @Description Verify the email with the token from the link sent on registration. A token can be used once.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param token body models.VerifyEmail true "VerifyEmailRequestBody"
+// @Success 204 "No Content"
+// @Response 400 {object} httpapi.Response "Invalid or expired token"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmail
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	err = h.services.Account().VerifyEmail(c.Request.Context(), &req)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ForgotPassword godoc
+// @ID forgot_password
+// @Router /password/forgot [POST]
+// @Summary Forgot Password
+// @Description Email a password reset link to the user with the email. The response is the same whether the email is registered or not.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param email body models.ForgotPassword true "ForgotPasswordRequestBody"
+// @Success 202 "Accepted"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 429 {object} httpapi.Response "Too many requests"
+// @Header 429 {string} Retry-After "Seconds until the request may be retried"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPassword
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	err = h.services.Account().ForgotPassword(c.Request.Context(), &req)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword godoc
+// @ID reset_password
+// @Router /password/reset [POST]
+// @Summary Reset Password
+// @Description Set a new password with the token from the reset link. All sessions of the user are revoked and a login lockout is lifted.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param password body models.ResetPassword true "ResetPasswordRequestBody"
+// @Success 204 "No Content"
+// @Response 400 {object} httpapi.Response "Invalid or expired token"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) ResetPassword(c *gin.Context) {
+	var req models.ResetPassword
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	err = h.services.Account().ResetPassword(c.Request.Context(), &req)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"net/http"
+
+	"crud/api/http"
+	"crud/models"
+	"crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKey godoc
+// @ID create_api_key
+// @Router /api-keys [POST]
+// @Summary Create API Key
+// @Description Create an API key for back-office scripts, sent in the X-API-Key header. The key is only returned once; only its hash is stored. Requires a super admin token.
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Param api_key body models.CreateAPIKeySwagger true "CreateAPIKeyRequestBody"
+// @Success 201 {object} models.CreateAPIKeyResponse "CreateAPIKeyBody"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Super admin token required"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) CreateAPIKey(c *gin.Context) {
+	var apiKey models.CreateAPIKey
+
+	err := c.ShouldBindJSON(&apiKey)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	apiKey.Created_by = c.GetString(httpapi.UserIDKey)
+
+	resp, err := h.services.APIKey().Create(c.Request.Context(), &apiKey)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetByIdAPIKey godoc
+// @ID get_by_id_api_key
+// @Router /api-keys/{id} [GET]
+// @Summary Get By Id API Key
+// @Description Get an API key, without the key itself. Requires a super admin token.
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Success 200 {object} models.APIKey "GetAPIKeyBody"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Super admin token required"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) GetAPIKeyById(c *gin.Context) {
+
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	resp, err := h.services.APIKey().GetByPKey(
+		c.Request.Context(),
+		&models.APIKeyPrimarKey{Id: param.Id},
+	)
+
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetListAPIKey godoc
+// @ID get_list_api_key
+// @Router /api-keys [GET]
+// @Summary Get List API Key
+// @Description List the API keys, without the keys themselves. Requires a super admin token.
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Param offset query integer false "offset" minimum(0)
+// @Param limit query integer false "limit" minimum(0) maximum(1000)
+// @Success 200 {object} models.GetListAPIKeyResponse "GetAPIKeysBody"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Super admin token required"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) GetAPIKeyList(c *gin.Context) {
+	var params models.ListParams
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	resp, err := h.services.APIKey().GetList(
+		c.Request.Context(),
+		&models.GetListAPIKeyRequest{
+			Limit:  params.Limit,
+			Offset: params.Offset,
+		},
+	)
+
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteByIdAPIKey godoc
+// @ID delete_by_id_api_key
+// @Router /api-keys/{id} [DELETE]
+// @Summary Delete By Id API Key
+// @Description Revoke an API key. Requests made with it fail from then on. Requires a super admin token.
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Success 204 "No Content"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Super admin token required"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) DeleteAPIKey(c *gin.Context) {
+
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	err = h.services.APIKey().Delete(
+		c.Request.Context(),
+		&models.APIKeyPrimarKey{Id: param.Id},
+	)
+
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+
+	"crud/api/http"
+	"crud/models"
+	"crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogList godoc
+// @ID get_list_audit_log
+// @Router /audit [GET]
+// @Summary Get List Audit Log
+// @Description List the creates, updates, deletes, restores and erasures of books, users, orders and API keys, newest first. Each entry has who made the write and the fields it changed; passwords are recorded as changed without their values, and the values of erased users are dropped. Requires a super admin token.
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Param offset query integer false "offset" minimum(0)
+// @Param limit query integer false "limit" minimum(0) maximum(1000)
+// @Param actor_id query string false "User the writes were made by" format(uuid)
+// @Param action query string false "Action" Enums(create, update, delete, restore, erase)
+// @Param entity_type query string false "Entity type" Enums(book, user, order, api_key)
+// @Param entity_id query string false "Entity id" format(uuid)
+// @Param from query string false "Entries at or after, RFC 3339" format(date-time)
+// @Param to query string false "Entries before, RFC 3339" format(date-time)
+// @Success 200 {object} models.GetListAuditLogResponse "GetAuditLogsBody"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Super admin token required"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) GetAuditLogList(c *gin.Context) {
+	var params models.AuditLogParams
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	resp, err := h.services.Audit().GetList(
+		c.Request.Context(),
+		&models.GetListAuditLogRequest{
+			Limit:      params.Limit,
+			Offset:     params.Offset,
+			Actor_id:   params.Actor_id,
+			Action:     params.Action,
+			EntityType: params.EntityType,
+			Entity_id:  params.Entity_id,
+			From:       params.From,
+			To:         params.To,
+		},
+	)
+
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -1,14 +1,15 @@
 package handler
 
 import (
-	"context"
-	"crud/config"
-	"crud/models"
-	"crud/pkg/helper"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 
+	"crud/api/http"
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/validation"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,50 +17,46 @@ import (
 // @ID login
 // @Router /login [POST]
 // @Summary Create Login
-// @Description Create Login
+// @Description Create Login. Users with TOTP enabled get an mfa_token instead of the access token and finish the login with POST /login/mfa.
 // @Tags Login
 // @Accept json
 // @Produce json
 // @Param Login body models.Login true "LoginRequestBody"
 // @Success 201 {object} models.LoginResponse "GetLoginBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Success 202 {object} models.LoginResponse "MFA required: continue with POST /login/mfa"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 401 {object} httpapi.Response "Wrong login or password"
+// @Response 403 {object} httpapi.Response "Email is not verified"
+// @Response 429 {object} httpapi.Response "Too many requests or too many failed logins"
+// @Header 429 {string} Retry-After "Seconds until the login may be retried"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) Login(c *gin.Context) {
 	var login models.Login
 
 	err := c.ShouldBindJSON(&login)
 	if err != nil {
-		log.Printf("error whiling create: %v\n", err)
-		c.JSON(http.StatusBadRequest, err.Error())
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	resp, err := h.storage.User().GetByPKey(
-		context.Background(),
-		&models.UserPrimarKey{Login: login.Login},
-	)
+	login.UserAgent = c.Request.UserAgent()
+	login.IP = c.ClientIP()
 
-	if err != nil {
-		log.Printf("error whiling GetByPKey: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GetByPKey").Error())
-		return
+	resp, err := h.services.Auth().Login(c.Request.Context(), &login)
+	if errors.Is(err, errs.ErrUnauthorized) {
+		h.log.WarnContext(c.Request.Context(), "failed login", slog.String("login", login.Login))
 	}
 
-	if login.Password != resp.Password {
-		c.JSON(http.StatusInternalServerError, errors.New("error password is not correct").Error())
+	if err != nil {
+		httpapi.Error(c, err)
 		return
 	}
 
-	data := map[string]interface{}{
-		"user_id": resp.Id,
-	}
-
-	token, err := helper.GenerateJWT(data, config.TimeExpiredAt, h.cfg.AuthSecretKey, h.cfg.Client)
-	if err != nil {
-		log.Printf("error whiling GenerateJWT: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GenerateJWT").Error())
+	if resp.MFARequired {
+		c.JSON(http.StatusAccepted, resp)
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.LoginResponse{AccessToken: token})
+	c.JSON(http.StatusCreated, resp)
 }
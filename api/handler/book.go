@@ -1,13 +1,11 @@
 package handler
 
 import (
-	"context"
-	"errors"
-	"log"
 	"net/http"
-	"strconv"
 
+	"crud/api/http"
 	"crud/models"
+	"crud/pkg/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,36 +19,30 @@ import (
 // @Accept json
 // @Produce json
 // @Param book body models.CreateBook true "CreatebookRequestBody"
+// @Param Idempotency-Key header string false "Unique key that makes retries return the first response"
 // @Success 201 {object} models.Book "GetbookBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Header 201 {string} ETag "Entity tag of the created version"
+// @Header 201 {string} Idempotent-Replayed "true when the response is a replay"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 409 {object} httpapi.Response "Request with the same Idempotency-Key in progress"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) CreateBook(c *gin.Context) {
 	var book models.CreateBook
 
 	err := c.ShouldBindJSON(&book)
 	if err != nil {
-		log.Printf("error whiling create: %v\n", err)
-		c.JSON(http.StatusBadRequest, err.Error())
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	id, err := h.storage.Book().Create(context.Background(), &book)
+	resp, err := h.services.Book().Create(c.Request.Context(), &book)
 	if err != nil {
-		log.Printf("error whiling Create: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling Create").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	resp, err := h.storage.Book().GetByPKey(
-		context.Background(),
-		&models.BookPrimarKey{Id: id},
-	)
-
-	if err != nil {
-		log.Printf("error whiling GetByPKey: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GetByPKey").Error())
-		return
-	}
+	httpapi.SetETag(c, resp.Version)
 
 	c.JSON(http.StatusCreated, resp)
 }
@@ -63,25 +55,49 @@ func (h *HandlerV1) CreateBook(c *gin.Context) {
 // @Tags Book
 // @Accept json
 // @Produce json
-// @Param id path string true "id"
+// @Param id path string true "id" format(uuid)
+// @Param include_deleted query boolean false "Find the book even if it is deleted; super admin only"
+// @Param If-None-Match header string false "ETag of a cached version"
 // @Success 200 {object} models.Book "GetBookBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 304 "Not Modified"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 403 {object} httpapi.Response "include_deleted requires a super admin token"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) GetBookById(c *gin.Context) {
 
-	id := c.Param("id")
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
 
-	resp, err := h.storage.Book().GetByPKey(
-		context.Background(),
-		&models.BookPrimarKey{Id: id},
+	includeDeleted, err := bindDeleted(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	resp, err := h.services.Book().GetByPKey(
+		c.Request.Context(),
+		&models.BookPrimarKey{Id: param.Id, IncludeDeleted: includeDeleted},
 	)
 
 	if err != nil {
-		log.Printf("error whiling GetByPKey: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GetByPKey").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
+	if httpapi.NotModified(c, resp.Version) {
+		return
+	}
+
+	httpapi.SetETag(c, resp.Version)
+
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -93,49 +109,40 @@ func (h *HandlerV1) GetBookById(c *gin.Context) {
 // @Tags Book
 // @Accept json
 // @Produce json
-// @Param offset query string false "offset"
-// @Param limit query string false "limit"
+// @Param offset query integer false "offset" minimum(0)
+// @Param limit query integer false "limit" minimum(0) maximum(1000)
+// @Param include_deleted query boolean false "List deleted books as well; super admin only"
 // @Success 200 {object} models.GetListBookResponse "GetBookBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 403 {object} httpapi.Response "include_deleted requires a super admin token"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) GetBookList(c *gin.Context) {
-	var (
-		limit  int
-		offset int
-		err    error
-	)
+	var params models.ListParams
 
-	limitStr := c.Query("limit")
-	if limitStr != "" {
-		limit, err = strconv.Atoi(limitStr)
-		if err != nil {
-			log.Printf("error whiling limit: %v\n", err)
-			c.JSON(http.StatusBadRequest, err.Error())
-			return
-		}
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
 	}
 
-	offsetStr := c.Query("offset")
-	if offsetStr != "" {
-		offset, err = strconv.Atoi(offsetStr)
-		if err != nil {
-			log.Printf("error whiling limit: %v\n", err)
-			c.JSON(http.StatusBadRequest, err.Error())
-			return
-		}
+	includeDeleted, err := bindDeleted(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
 	}
 
-	resp, err := h.storage.Book().GetList(
-		context.Background(),
+	resp, err := h.services.Book().GetList(
+		c.Request.Context(),
 		&models.GetListBookRequest{
-			Limit:  int32(limit),
-			Offset: int32(offset),
+			Limit:          params.Limit,
+			Offset:         params.Offset,
+			IncludeDeleted: includeDeleted,
 		},
 	)
 
 	if err != nil {
-		log.Printf("error whiling get list: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling get list").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
@@ -150,62 +157,89 @@ func (h *HandlerV1) GetBookList(c *gin.Context) {
 // @Tags Book
 // @Accept json
 // @Produce json
-// @Param id path string true "id"
+// @Param id path string true "id" format(uuid)
+// @Param If-Match header string false "ETag of the version being replaced"
 // @Param book body models.UpdateBookSwagger true "CreateBookRequestBody"
 // @Success 200 {object} models.Book "GetBooksBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Response 412 {object} httpapi.Response "Precondition Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) UpdateBook(c *gin.Context) {
 
 	var (
-		book models.UpdateBook
+		book  models.UpdateBook
+		param models.IdParam
 	)
 
-	id := c.Param("id")
-
-	if id == "" {
-		log.Printf("error whiling update: %v\n", errors.New("required book id").Error())
-		c.JSON(http.StatusBadRequest, errors.New("required book id").Error())
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	err := c.ShouldBindJSON(&book)
+	err = c.ShouldBindJSON(&book)
 	if err != nil {
-		log.Printf("error whiling update: %v\n", err)
-		c.JSON(http.StatusBadRequest, err.Error())
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	book.Id = id
-
-	rowsAffected, err := h.storage.Book().Update(
-		context.Background(),
-		&book,
-	)
+	book.Id = param.Id
 
+	book.Version, err = httpapi.IfMatch(c)
 	if err != nil {
-		log.Printf("error whiling update: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling update").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	if rowsAffected == 0 {
-		log.Printf("error whiling update rows affected: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling update rows affected").Error())
+	resp, err := h.services.Book().Update(c.Request.Context(), &book)
+	if err != nil {
+		httpapi.Error(c, err)
 		return
 	}
 
-	resp, err := h.storage.Book().GetByPKey(
-		context.Background(),
-		&models.BookPrimarKey{Id: id},
-	)
+	httpapi.SetETag(c, resp.Version)
+
+	c.JSON(http.StatusOK, resp)
+}
 
+// PatchBook godoc
+// @ID patch_book
+// @Router /book/{id} [PATCH]
+// @Summary Patch Book
+// @Description Update only the fields present in an RFC 7396 merge patch, or apply an RFC 6902 JSON patch. A null isbn or author clears it.
+// @Tags Book
+// @Accept application/merge-patch+json,application/json-patch+json,json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Param If-Match header string false "ETag of the version being replaced"
+// @Param book body models.PatchBookSwagger true "PatchBookRequestBody"
+// @Success 200 {object} models.Book "GetBookBody"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Response 415 {object} httpapi.Response "Unsupported Media Type"
+// @Response 412 {object} httpapi.Response "Precondition Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) PatchBook(c *gin.Context) {
+
+	req, err := bindPatch(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	resp, err := h.services.Book().Patch(c.Request.Context(), req)
 	if err != nil {
-		log.Printf("error whiling GetByPKey: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GetByPKey").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
+	httpapi.SetETag(c, resp.Version)
+
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -213,35 +247,88 @@ func (h *HandlerV1) UpdateBook(c *gin.Context) {
 // @ID delete_by_id_book
 // @Router /book/{id} [DELETE]
 // @Summary Delete By Id Book
-// @Description Delete By Id Book
+// @Description Delete the book. It can be restored by a super admin with POST /book/{id}/restore until it is purged.
 // @Tags Book
 // @Accept json
 // @Produce json
-// @Param id path string true "id"
+// @Param id path string true "id" format(uuid)
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {object} models.Book "GetBookBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Response 412 {object} httpapi.Response "Precondition Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) DeleteBook(c *gin.Context) {
 
-	id := c.Param("id")
-	if id == "" {
-		log.Printf("error whiling update: %v\n", errors.New("required book id").Error())
-		c.JSON(http.StatusBadRequest, errors.New("required book id").Error())
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	version, err := httpapi.IfMatch(c)
+	if err != nil {
+		httpapi.Error(c, err)
 		return
 	}
 
-	err := h.storage.Book().Delete(
-		context.Background(),
+	err = h.services.Book().Delete(
+		c.Request.Context(),
 		&models.BookPrimarKey{
-			Id: id,
+			Id:      param.Id,
+			Version: version,
 		},
 	)
 
 	if err != nil {
-		log.Printf("error whiling delete: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling delete").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// RestoreBook godoc
+// @ID restore_book
+// @Router /book/{id}/restore [POST]
+// @Summary Restore Book
+// @Description Restore a deleted book. Restoring a book that is not deleted returns it unchanged. Requires a super admin token.
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Success 200 {object} models.Book "GetBookBody"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Super admin token required"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found, or already purged"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) RestoreBook(c *gin.Context) {
+
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	resp, err := h.services.Book().Restore(
+		c.Request.Context(),
+		&models.BookPrimarKey{Id: param.Id},
+	)
+
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	httpapi.SetETag(c, resp.Version)
+
+	c.JSON(http.StatusOK, resp)
+}
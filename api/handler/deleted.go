@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"crud/api/http"
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/validation"
+)
+
+// bindDeleted reads the include_deleted query parameter, which only super
+// admins may set.
+func bindDeleted(c *gin.Context) (bool, error) {
+
+	var params models.DeletedParams
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		return false, validation.Error(err)
+	}
+
+	if params.IncludeDeleted && !c.GetBool(httpapi.SuperAdminKey) {
+		return false, errs.Forbidden("include_deleted requires a super admin token")
+	}
+
+	return params.IncludeDeleted, nil
+}
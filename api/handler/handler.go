@@ -1,20 +1,22 @@
 package handler
 
 import (
+	"log/slog"
+
 	"crud/config"
-	"crud/storage"
+	"crud/service"
 )
 
 type HandlerV1 struct {
-	cfg     *config.Config
-	storage storage.StorageI
-	cache   storage.CacheI
+	cfg      *config.Config
+	log      *slog.Logger
+	services *service.Service
 }
 
-func NewHandlerV1(cfg *config.Config, storage storage.StorageI, cache storage.CacheI) *HandlerV1 {
+func NewHandlerV1(cfg *config.Config, log *slog.Logger, services *service.Service) *HandlerV1 {
 	return &HandlerV1{
-		cfg:     cfg,
-		storage: storage,
-		cache:   cache,
+		cfg:      cfg,
+		log:      log,
+		services: services,
 	}
 }
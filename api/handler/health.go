@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+
+	"crud/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz godoc
+// @ID healthz
+// @Router /healthz [GET]
+// @Summary Liveness
+// @Description Reports that the process is up. It does not check dependencies.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} models.Health "Alive"
+func (h *HandlerV1) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Health{Status: models.HealthOK})
+}
+
+// Readyz godoc
+// @ID readyz
+// @Router /readyz [GET]
+// @Summary Readiness
+// @Description Checks the database, Redis and the schema version.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} models.Readiness "Ready"
+// @Failure 503 {object} models.Readiness "Not ready"
+func (h *HandlerV1) Readyz(c *gin.Context) {
+
+	resp := h.services.Health().Ready(c.Request.Context())
+	if resp.Status != models.HealthOK {
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// JWKS godoc
+// @ID jwks
+// @Router /.well-known/jwks.json [GET]
+// @Summary JSON Web Key Set
+// @Description Public keys the access tokens are signed with, by kid. Other services verify tokens with them.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} token.JWKS "Keys"
+func (h *HandlerV1) JWKS(c *gin.Context) {
+
+	// Long enough to spare the endpoint, short next to the key rotation.
+	c.Header("Cache-Control", "public, max-age=300")
+
+	c.JSON(http.StatusOK, h.services.Auth().JWKS())
+}
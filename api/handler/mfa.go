@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"crud/api/http"
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginMFA godoc
+// @ID login_mfa
+// @Router /login/mfa [POST]
+// @Summary Login MFA
+// @Description Finish a login of a user with TOTP enabled, using the mfa_token of POST /login or /loginsuper and a code from the authenticator app or a recovery code.
+// @Tags Login
+// @Accept json
+// @Produce json
+// @Param Login body models.LoginMFA true "LoginMFARequestBody"
+// @Success 201 {object} models.LoginResponse "GetLoginBody"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 401 {object} httpapi.Response "Invalid mfa token or wrong code"
+// @Response 403 {object} httpapi.Response "The account of a super admin login is no longer an admin"
+// @Response 429 {object} httpapi.Response "Too many requests or too many failed logins"
+// @Header 429 {string} Retry-After "Seconds until the login may be retried"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) LoginMFA(c *gin.Context) {
+	var login models.LoginMFA
+
+	err := c.ShouldBindJSON(&login)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	login.UserAgent = c.Request.UserAgent()
+	login.IP = c.ClientIP()
+
+	resp, err := h.services.Auth().LoginMFA(c.Request.Context(), &login)
+	if errors.Is(err, errs.ErrUnauthorized) {
+		h.log.WarnContext(c.Request.Context(), "failed mfa login")
+	}
+
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetTOTP godoc
+// @ID get_totp
+// @Router /mfa/totp [GET]
+// @Summary Get TOTP
+// @Description Whether the authenticated user has TOTP enabled and how many recovery codes are left.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.TOTP "GetTOTPBody"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) GetTOTP(c *gin.Context) {
+
+	pkey, err := currentTOTP(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	resp, err := h.services.Auth().GetTOTP(c.Request.Context(), pkey)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// EnrollTOTP godoc
+// @ID enroll_totp
+// @Router /mfa/totp [POST]
+// @Summary Enroll TOTP
+// @Description Create a TOTP secret for the authenticated user. Add it to an authenticator app with the otpauth URL or GET /mfa/totp/qr, then enable it with POST /mfa/totp/confirm. Enrolling again replaces a secret that is not confirmed yet.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Success 201 {object} models.TOTPEnrollResponse "TOTPEnrollBody"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 409 {object} httpapi.Response "TOTP is already enabled"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) EnrollTOTP(c *gin.Context) {
+
+	pkey, err := currentTOTP(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	resp, err := h.services.Auth().EnrollTOTP(c.Request.Context(), pkey)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetTOTPQR godoc
+// @ID get_totp_qr
+// @Router /mfa/totp/qr [GET]
+// @Summary Get TOTP QR
+// @Description The secret of the enrollment in progress as a QR code for authenticator apps.
+// @Tags MFA
+// @Produce png
+// @Success 200 {file} file "PNG image"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 404 {object} httpapi.Response "No enrollment in progress"
+// @Response 409 {object} httpapi.Response "TOTP is already enabled"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) GetTOTPQR(c *gin.Context) {
+
+	pkey, err := currentTOTP(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	png, err := h.services.Auth().TOTPQR(c.Request.Context(), pkey)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	// The image holds the secret.
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// ConfirmTOTP godoc
+// @ID confirm_totp
+// @Router /mfa/totp/confirm [POST]
+// @Summary Confirm TOTP
+// @Description Enable the enrolled secret with a code from the authenticator app. The response holds the recovery codes, which are not shown again.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param code body models.TOTPCode true "TOTPCodeRequestBody"
+// @Success 200 {object} models.RecoveryCodesResponse "RecoveryCodesBody"
+// @Response 400 {object} httpapi.Response "Wrong code"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 404 {object} httpapi.Response "No enrollment in progress"
+// @Response 409 {object} httpapi.Response "TOTP is already enabled"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) ConfirmTOTP(c *gin.Context) {
+
+	pkey, code, err := bindTOTPCode(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	resp, err := h.services.Auth().ConfirmTOTP(c.Request.Context(), pkey, code)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// RegenerateRecoveryCodes godoc
+// @ID regenerate_recovery_codes
+// @Router /mfa/recovery-codes [POST]
+// @Summary Regenerate Recovery Codes
+// @Description Replace the recovery codes of the authenticated user. Requires a code from the authenticator app.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param code body models.TOTPCode true "TOTPCodeRequestBody"
+// @Success 200 {object} models.RecoveryCodesResponse "RecoveryCodesBody"
+// @Response 400 {object} httpapi.Response "Wrong code"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 404 {object} httpapi.Response "TOTP is not enabled"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) RegenerateRecoveryCodes(c *gin.Context) {
+
+	pkey, code, err := bindTOTPCode(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	resp, err := h.services.Auth().RegenerateRecoveryCodes(c.Request.Context(), pkey, code)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// DisableTOTP godoc
+// @ID disable_totp
+// @Router /mfa/totp/disable [POST]
+// @Summary Disable TOTP
+// @Description Remove the TOTP secret and the recovery codes of the authenticated user. Requires a code from the authenticator app.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param code body models.TOTPCode true "TOTPCodeRequestBody"
+// @Success 204 "No Content"
+// @Response 400 {object} httpapi.Response "Wrong code"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 404 {object} httpapi.Response "TOTP is not enabled"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) DisableTOTP(c *gin.Context) {
+
+	pkey, code, err := bindTOTPCode(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	err = h.services.Auth().DisableTOTP(c.Request.Context(), pkey, code)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// currentTOTP is the TOTP key of the authenticated user.
+func currentTOTP(c *gin.Context) (*models.TOTPPrimarKey, error) {
+
+	token, err := currentToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPPrimarKey{User_id: token.User_id}, nil
+}
+
+func bindTOTPCode(c *gin.Context) (*models.TOTPPrimarKey, *models.TOTPCode, error) {
+
+	pkey, err := currentTOTP(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var code models.TOTPCode
+
+	err = c.ShouldBindJSON(&code)
+	if err != nil {
+		return nil, nil, validation.Error(err)
+	}
+
+	return pkey, &code, nil
+}
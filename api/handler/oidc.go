@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"crud/api/http"
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginOIDC godoc
+// @ID login_oidc
+// @Router /login/oidc [GET]
+// @Summary Login With OIDC
+// @Description Start a login at the OpenID Connect identity provider: redirects to its authorization endpoint, which sends the user back to GET /login/oidc/callback.
+// @Tags Login
+// @Produce json
+// @Success 302 "Redirect to the identity provider"
+// @Header 302 {string} Location "Authorization URL of the identity provider"
+// @Response 404 {object} httpapi.Response "OIDC login is not enabled"
+// @Response 429 {object} httpapi.Response "Too many requests"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) LoginOIDC(c *gin.Context) {
+
+	authURL, err := h.services.Auth().OIDCAuthURL(c.Request.Context())
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// LoginOIDCCallback godoc
+// @ID login_oidc_callback
+// @Router /login/oidc/callback [GET]
+// @Summary Login With OIDC Callback
+// @Description Finish a login at the OpenID Connect identity provider. The user is matched by the provider account, then by its verified email, and created on the first login. Users with TOTP enabled get an mfa_token and finish the login with POST /login/mfa.
+// @Tags Login
+// @Produce json
+// @Param code query string false "Authorization code"
+// @Param state query string true "State of the login"
+// @Param error query string false "Error of the identity provider"
+// @Success 201 {object} models.LoginResponse "GetLoginBody"
+// @Success 202 {object} models.LoginResponse "MFA required: continue with POST /login/mfa"
+// @Response 401 {object} httpapi.Response "Invalid state, or the identity provider refused the login"
+// @Response 403 {object} httpapi.Response "No verified email"
+// @Response 404 {object} httpapi.Response "OIDC login is not enabled"
+// @Response 409 {object} httpapi.Response "An account with the email is not verified"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 429 {object} httpapi.Response "Too many requests"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) LoginOIDCCallback(c *gin.Context) {
+	var login models.LoginOIDC
+
+	err := c.ShouldBindQuery(&login)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	login.UserAgent = c.Request.UserAgent()
+	login.IP = c.ClientIP()
+
+	resp, err := h.services.Auth().LoginOIDC(c.Request.Context(), &login)
+	if errors.Is(err, errs.ErrUnauthorized) {
+		h.log.WarnContext(c.Request.Context(), "failed oidc login", slog.Any("error", err))
+	}
+
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	if resp.MFARequired {
+		c.JSON(http.StatusAccepted, resp)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
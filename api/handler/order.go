@@ -1,66 +1,61 @@
 package handler
 
 import (
-	"context"
-	"errors"
-	"fmt"
-	"log"
 	"net/http"
-	"strconv"
 
+	"crud/api/http"
 	"crud/models"
+	"crud/pkg/validation"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis"
 )
 
 // CreateOrder godoc
 // @ID create_order
 // @Router /order [POST]
 // @Summary Create Order
-// @Description Create Order
+// @Description Create Order. The book price is charged to the user balance. Users can only order for themselves; super admins and API keys with the orders:write scope for anyone.
 // @Tags Order
 // @Accept json
 // @Produce json
 // @Param order body models.CreateOrderSwagger true "CreateOrderRequestBody"
+// @Param Idempotency-Key header string false "Unique key that makes retries return the first response"
 // @Success 201 {object} models.Order "GetOrderBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Header 201 {string} ETag "Entity tag of the created version"
+// @Header 201 {string} Idempotent-Replayed "true when the response is a replay"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Ordering for another user"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "User or book not found"
+// @Response 402 {object} httpapi.Response "Insufficient funds"
+// @Response 409 {object} httpapi.Response "Request with the same Idempotency-Key in progress"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) CreateOrder(c *gin.Context) {
 	var order models.CreateOrder
 
 	err := c.ShouldBindJSON(&order)
 	if err != nil {
-		log.Printf("error whiling create: %v\n", err)
-		c.JSON(http.StatusBadRequest, err.Error())
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	id, err := h.storage.Order().Create(context.Background(), &order)
-	if err != nil {
-		log.Printf("error whiling Create: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling Create").Error())
-		return
+	// API keys with the orders:write scope order for any user.
+	if c.GetString(httpapi.APIKeyIDKey) == "" {
+		err = checkSelf(c, order.User_id, "order for it")
+		if err != nil {
+			httpapi.Error(c, err)
+			return
+		}
 	}
 
-	resp, err := h.storage.Order().GetByPKey(
-		context.Background(),
-		&models.OrderPrimarKey{Id: id},
-	)
-
+	resp, err := h.services.Order().Create(c.Request.Context(), &order)
 	if err != nil {
-		log.Printf("error whiling GetByPKey: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GetByPKey").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	err = h.cache.Order().Delete(context.Background())
-
-	if err != nil {
-		log.Printf("error whiling cache delete: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling cache delete").Error())
-		return
-	}
+	httpapi.SetETag(c, resp.Version)
 
 	c.JSON(http.StatusCreated, resp)
 }
@@ -73,25 +68,49 @@ func (h *HandlerV1) CreateOrder(c *gin.Context) {
 // @Tags Order
 // @Accept json
 // @Produce json
-// @Param id path string true "id"
+// @Param id path string true "id" format(uuid)
+// @Param include_deleted query boolean false "Find the order even if it is deleted; super admin only"
+// @Param If-None-Match header string false "ETag of a cached version"
 // @Success 200 {object} models.Order "GetOrderBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 304 "Not Modified"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 403 {object} httpapi.Response "include_deleted requires a super admin token"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) GetOrderById(c *gin.Context) {
 
-	id := c.Param("id")
+	var param models.IdParam
 
-	resp, err := h.storage.Order().GetByPKey(
-		context.Background(),
-		&models.OrderPrimarKey{Id: id},
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	includeDeleted, err := bindDeleted(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	resp, err := h.services.Order().GetByPKey(
+		c.Request.Context(),
+		&models.OrderPrimarKey{Id: param.Id, IncludeDeleted: includeDeleted},
 	)
 
 	if err != nil {
-		log.Printf("error whiling GetByPKey: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GetByPKey").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
+	if httpapi.NotModified(c, resp.Version) {
+		return
+	}
+
+	httpapi.SetETag(c, resp.Version)
+
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -103,78 +122,44 @@ func (h *HandlerV1) GetOrderById(c *gin.Context) {
 // @Tags Order
 // @Accept json
 // @Produce json
-// @Param offset query string false "offset"
-// @Param limit query string false "limit"
+// @Param offset query integer false "offset" minimum(0)
+// @Param limit query integer false "limit" minimum(0) maximum(1000)
+// @Param include_deleted query boolean false "List deleted orders as well; super admin only"
 // @Success 200 {object} models.GetListOrderResponse "GetOrderBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 403 {object} httpapi.Response "include_deleted requires a super admin token"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) GetOrderList(c *gin.Context) {
-	var (
-		limit  int
-		offset int
-		err    error
-	)
+	var params models.ListParams
 
-	limitStr := c.Query("limit")
-	if limitStr != "" {
-		limit, err = strconv.Atoi(limitStr)
-		if err != nil {
-			log.Printf("error whiling limit: %v\n", err)
-			c.JSON(http.StatusBadRequest, err.Error())
-			return
-		}
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
 	}
 
-	offsetStr := c.Query("offset")
-	if offsetStr != "" {
-		offset, err = strconv.Atoi(offsetStr)
-		if err != nil {
-			log.Printf("error whiling limit: %v\n", err)
-			c.JSON(http.StatusBadRequest, err.Error())
-			return
-		}
+	includeDeleted, err := bindDeleted(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
 	}
 
-	orders, err := h.cache.Order().GetList(context.Background())
-	if err == redis.Nil {
-
-		resp, err := h.storage.Order().GetList(
-			context.Background(),
-			&models.GetListOrderRequest{
-				Limit:  int32(limit),
-				Offset: int32(offset),
-			},
-		)
-
-		if err != nil {
-			log.Printf("error whiling get list: %v", err)
-			c.JSON(http.StatusInternalServerError, errors.New("error whiling get list").Error())
-			return
-		}
-
-		fmt.Println("POSTGRES")
-
-		err = h.cache.Order().Create(context.Background(), resp)
-
-		if err != nil {
-			log.Printf("error whiling create cache list: %v", err)
-			c.JSON(http.StatusInternalServerError, errors.New("error whiling create cache list").Error())
-			return
-		}
-
-		c.JSON(http.StatusOK, resp)
-	} else {
-
-		if err != nil {
-			log.Printf("error whiling get list cache: %v", err)
-			c.JSON(http.StatusInternalServerError, errors.New("error whiling get list cache").Error())
-			return
-		}
-
-		fmt.Println("REDIS")
+	resp, err := h.services.Order().GetList(
+		c.Request.Context(),
+		&models.GetListOrderRequest{
+			Limit:          params.Limit,
+			Offset:         params.Offset,
+			IncludeDeleted: includeDeleted,
+		},
+	)
 
-		c.JSON(http.StatusOK, orders)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
 	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 // UpdateOrder godoc
@@ -185,82 +170,91 @@ func (h *HandlerV1) GetOrderList(c *gin.Context) {
 // @Tags Order
 // @Accept json
 // @Produce json
-// @Param id path string true "id"
+// @Param id path string true "id" format(uuid)
+// @Param If-Match header string false "ETag of the version being replaced"
 // @Param order body models.UpdateOrderSwagger true "CreateOrderRequestBody"
 // @Success 200 {object} models.Order "GetOrdersBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Response 402 {object} httpapi.Response "Insufficient funds"
+// @Response 412 {object} httpapi.Response "Precondition Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) UpdateOrder(c *gin.Context) {
 
 	var (
 		order models.UpdateOrder
+		param models.IdParam
 	)
 
-	id := c.Param("id")
-
-	if id == "" {
-		log.Printf("error whiling update: %v\n", errors.New("required order id").Error())
-		c.JSON(http.StatusBadRequest, errors.New("required order id").Error())
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	err := c.ShouldBindJSON(&order)
+	err = c.ShouldBindJSON(&order)
 	if err != nil {
-		log.Printf("error whiling update: %v\n", err)
-		c.JSON(http.StatusBadRequest, err.Error())
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	order.Id = id
-
-	rowsAffected, err := h.storage.Order().Update(
-		context.Background(),
-		&order,
-	)
+	order.Id = param.Id
 
+	order.Version, err = httpapi.IfMatch(c)
 	if err != nil {
-		log.Printf("error whiling update: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling update").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	if rowsAffected == 0 {
-		log.Printf("error whiling update rows affected: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling update rows affected").Error())
+	resp, err := h.services.Order().Update(c.Request.Context(), &order)
+	if err != nil {
+		httpapi.Error(c, err)
 		return
 	}
 
-	resp, err := h.storage.Order().GetByPKey(
-		context.Background(),
-		&models.OrderPrimarKey{Id: id},
-	)
+	httpapi.SetETag(c, resp.Version)
 
-	if err != nil {
-		log.Printf("error whiling GetByPKey: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GetByPKey").Error())
-		return
-	}
+	c.JSON(http.StatusOK, resp)
+}
 
-	respList, err := h.storage.Order().GetList(
-		context.Background(),
-		&models.GetListOrderRequest{
-			Limit:  int32(0),
-			Offset: int32(0),
-		},
-	)
+// PatchOrder godoc
+// @ID patch_order
+// @Router /order/{id} [PATCH]
+// @Summary Patch Order
+// @Description Move the order to another user and/or book with an RFC 7396 merge patch or an RFC 6902 JSON patch. The order is repriced as in Update.
+// @Tags Order
+// @Accept application/merge-patch+json,application/json-patch+json,json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Param If-Match header string false "ETag of the version being replaced"
+// @Param order body models.PatchOrderSwagger true "PatchOrderRequestBody"
+// @Success 200 {object} models.Order "GetOrderBody"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Response 402 {object} httpapi.Response "Insufficient funds"
+// @Response 415 {object} httpapi.Response "Unsupported Media Type"
+// @Response 412 {object} httpapi.Response "Precondition Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) PatchOrder(c *gin.Context) {
+
+	req, err := bindPatch(c)
 	if err != nil {
-		log.Printf("error whiling get list: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling get list").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	err = h.cache.Order().Update(context.Background(), respList)
+	resp, err := h.services.Order().Patch(c.Request.Context(), req)
 	if err != nil {
-		log.Printf("error whiling update cache list: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling update cache list").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
+	httpapi.SetETag(c, resp.Version)
+
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -268,56 +262,89 @@ func (h *HandlerV1) UpdateOrder(c *gin.Context) {
 // @ID delete_by_id_order
 // @Router /order/{id} [DELETE]
 // @Summary Delete By Id Order
-// @Description Delete By Id Order
+// @Description Delete the order. It can be restored by a super admin with POST /order/{id}/restore until it is purged.
 // @Tags Order
 // @Accept json
 // @Produce json
-// @Param id path string true "id"
+// @Param id path string true "id" format(uuid)
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {object} models.Order "GetOrderBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Response 412 {object} httpapi.Response "Precondition Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) DeleteOrder(c *gin.Context) {
 
-	id := c.Param("id")
-	if id == "" {
-		log.Printf("error whiling update: %v\n", errors.New("required order id").Error())
-		c.JSON(http.StatusBadRequest, errors.New("required order id").Error())
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	version, err := httpapi.IfMatch(c)
+	if err != nil {
+		httpapi.Error(c, err)
 		return
 	}
 
-	err := h.storage.Order().Delete(
-		context.Background(),
+	err = h.services.Order().Delete(
+		c.Request.Context(),
 		&models.OrderPrimarKey{
-			Id: id,
+			Id:      param.Id,
+			Version: version,
 		},
 	)
 
 	if err != nil {
-		log.Printf("error whiling delete: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling delete").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	respList, err := h.storage.Order().GetList(
-		context.Background(),
-		&models.GetListOrderRequest{
-			Limit:  int32(0),
-			Offset: int32(0),
-		},
-	)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// RestoreOrder godoc
+// @ID restore_order
+// @Router /order/{id}/restore [POST]
+// @Summary Restore Order
+// @Description Restore a deleted order. Its user and book must not be deleted. Restoring a order that is not deleted returns it unchanged. Requires a super admin token.
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Success 200 {object} models.Order "GetOrderBody"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Super admin token required"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found, or already purged"
+// @Response 409 {object} httpapi.Response "The user or book of the order is deleted"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) RestoreOrder(c *gin.Context) {
+
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
 	if err != nil {
-		log.Printf("error whiling get list: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling get list").Error())
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	err = h.cache.Order().Update(context.Background(), respList)
+	resp, err := h.services.Order().Restore(
+		c.Request.Context(),
+		&models.OrderPrimarKey{Id: param.Id},
+	)
+
 	if err != nil {
-		log.Printf("error whiling update cache list: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling update cache list").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
+	httpapi.SetETag(c, resp.Version)
 
-	c.JSON(http.StatusNoContent, nil)
+	c.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"crud/api/http"
+	"crud/models"
+	"crud/pkg/validation"
+)
+
+// bindPatch reads the :id path parameter, If-Match and the raw patch
+// document of a PATCH request. The document is interpreted by the service.
+func bindPatch(c *gin.Context) (*models.PatchRequest, error) {
+
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		return nil, validation.Error(err)
+	}
+
+	version, err := httpapi.IfMatch(c)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := c.GetRawData()
+	if err != nil {
+		return nil, validation.Error(err)
+	}
+
+	return &models.PatchRequest{
+		Id:          param.Id,
+		ContentType: c.ContentType(),
+		Document:    document,
+		Version:     version,
+	}, nil
+}
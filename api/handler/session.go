@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"net/http"
+
+	"crud/api/http"
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logout godoc
+// @ID logout
+// @Router /logout [POST]
+// @Summary Logout
+// @Description Revoke the access token the request is made with.
+// @Tags Session
+// @Accept json
+// @Produce json
+// @Success 204 "No Content"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) Logout(c *gin.Context) {
+
+	token, err := currentToken(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	err = h.services.Auth().Logout(c.Request.Context(), token)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetMySessions godoc
+// @ID get_my_sessions
+// @Router /me/sessions [GET]
+// @Summary Get My Sessions
+// @Description List the live sessions of the authenticated user with the device and IP they were opened from.
+// @Tags Session
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetListSessionResponse "GetSessionsBody"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) GetMySessions(c *gin.Context) {
+
+	token, err := currentToken(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	resp, err := h.services.Auth().Sessions(c.Request.Context(), token)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeAllSessions godoc
+// @ID revoke_all_sessions
+// @Router /user/{id}/sessions/revoke-all [POST]
+// @Summary Revoke All Sessions
+// @Description Revoke every token issued to the user so far. Allowed for the user and super admins.
+// @Tags Session
+// @Accept json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Success 204 "No Content"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Forbidden"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) RevokeAllSessions(c *gin.Context) {
+
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	err = checkSelf(c, param.Id, "revoke its sessions")
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	err = h.services.Auth().RevokeAll(c.Request.Context(), &models.UserPrimarKey{Id: param.Id})
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// currentToken is the token of an authenticated request.
+func currentToken(c *gin.Context) (*models.Token, error) {
+
+	token, ok := c.Get(httpapi.TokenKey)
+	if !ok {
+		return nil, errs.Unauthorized("authorization required")
+	}
+
+	return token.(*models.Token), nil
+}
+
+// checkSelf allows the request only to the user with id and to super
+// admins.
+func checkSelf(c *gin.Context, id, action string) error {
+
+	token, err := currentToken(c)
+	if err != nil {
+		return err
+	}
+
+	if token.User_id != id && !c.GetBool(httpapi.SuperAdminKey) {
+		return errs.Forbidden("only the user or a super admin can " + action)
+	}
+
+	return nil
+}
@@ -1,66 +1,51 @@
 package handler
 
 import (
-	"context"
-	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"strconv"
 
+	"crud/api/http"
 	"crud/models"
+	"crud/pkg/validation"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis"
 )
 
 // CreateUser godoc
 // @ID create_user
 // @Router /user [POST]
 // @Summary Create User
-// @Description Create User
+// @Description Create a user without email verification. Requires a super admin token; anyone else signs up with POST /register.
 // @Tags User
 // @Accept json
 // @Produce json
 // @Param user body models.CreateUser true "CreateUserRequestBody"
+// @Param Idempotency-Key header string false "Unique key that makes retries return the first response"
 // @Success 201 {object} models.User "GetUserBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Header 201 {string} ETag "Entity tag of the created version"
+// @Header 201 {string} Idempotent-Replayed "true when the response is a replay"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Super admin token required"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 409 {object} httpapi.Response "Login is taken, or a request with the same Idempotency-Key is in progress"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) CreateUser(c *gin.Context) {
 	var user models.CreateUser
 
 	err := c.ShouldBindJSON(&user)
 	if err != nil {
-		log.Printf("error whiling create: %v\n", err)
-		c.JSON(http.StatusBadRequest, err.Error())
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	id, err := h.storage.User().Create(context.Background(), &user)
+	resp, err := h.services.User().Create(c.Request.Context(), &user)
 	if err != nil {
-		log.Printf("error whiling Create: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling Create").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	resp, err := h.storage.User().GetByPKey(
-		context.Background(),
-		&models.UserPrimarKey{Id: id},
-	)
-
-	if err != nil {
-		log.Printf("error whiling GetByPKey: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GetByPKey").Error())
-		return
-	}
-
-	err = h.cache.User().Delete(context.Background())
-
-	if err != nil {
-		log.Printf("error whiling cache delete: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling cache delete").Error())
-		return
-	}
+	httpapi.SetETag(c, resp.Version)
 
 	c.JSON(http.StatusCreated, resp)
 }
@@ -73,25 +58,49 @@ func (h *HandlerV1) CreateUser(c *gin.Context) {
 // @Tags User
 // @Accept json
 // @Produce json
-// @Param id path string true "id"
+// @Param id path string true "id" format(uuid)
+// @Param include_deleted query boolean false "Find the user even if it is deleted; super admin only"
+// @Param If-None-Match header string false "ETag of a cached version"
 // @Success 200 {object} models.User "GetUserBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 304 "Not Modified"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 403 {object} httpapi.Response "include_deleted requires a super admin token"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) GetUserById(c *gin.Context) {
 
-	id := c.Param("id")
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	includeDeleted, err := bindDeleted(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
 
-	resp, err := h.storage.User().GetByPKey(
-		context.Background(),
-		&models.UserPrimarKey{Id: id},
+	resp, err := h.services.User().GetByPKey(
+		c.Request.Context(),
+		&models.UserPrimarKey{Id: param.Id, IncludeDeleted: includeDeleted},
 	)
 
 	if err != nil {
-		log.Printf("error whiling GetByPKey: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GetByPKey").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
+	if httpapi.NotModified(c, resp.Version) {
+		return
+	}
+
+	httpapi.SetETag(c, resp.Version)
+
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -103,165 +112,156 @@ func (h *HandlerV1) GetUserById(c *gin.Context) {
 // @Tags User
 // @Accept json
 // @Produce json
-// @Param offset query string false "offset"
-// @Param limit query string false "limit"
+// @Param offset query integer false "offset" minimum(0)
+// @Param limit query integer false "limit" minimum(0) maximum(1000)
+// @Param include_deleted query boolean false "List deleted users as well; super admin only"
 // @Success 200 {object} models.GetListUserResponse "GetUserBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 403 {object} httpapi.Response "include_deleted requires a super admin token"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) GetUserList(c *gin.Context) {
-	var (
-		limit  int
-		offset int
-		err    error
-	)
+	var params models.ListParams
 
-	limitStr := c.Query("limit")
-	if limitStr != "" {
-		limit, err = strconv.Atoi(limitStr)
-		if err != nil {
-			log.Printf("error whiling limit: %v\n", err)
-			c.JSON(http.StatusBadRequest, err.Error())
-			return
-		}
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
 	}
 
-	offsetStr := c.Query("offset")
-	if offsetStr != "" {
-		offset, err = strconv.Atoi(offsetStr)
-		if err != nil {
-			log.Printf("error whiling limit: %v\n", err)
-			c.JSON(http.StatusBadRequest, err.Error())
-			return
-		}
+	includeDeleted, err := bindDeleted(c)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
 	}
 
-	users, err := h.cache.User().GetList(context.Background())
-	if err == redis.Nil {
-
-		resp, err := h.storage.User().GetList(
-			context.Background(),
-			&models.GetListUserRequest{
-				Limit:  int32(limit),
-				Offset: int32(offset),
-			},
-		)
-
-		if err != nil {
-			log.Printf("error whiling get list: %v", err)
-			c.JSON(http.StatusInternalServerError, errors.New("error whiling get list").Error())
-			return
-		}
-
-		fmt.Println("POSTGRES")
-
-		err = h.cache.User().Create(context.Background(), resp)
-
-		if err != nil {
-			log.Printf("error whiling create cache list: %v", err)
-			c.JSON(http.StatusInternalServerError, errors.New("error whiling create cache list").Error())
-			return
-		}
-
-		c.JSON(http.StatusOK, resp)
-	} else {
-
-		if err != nil {
-			log.Printf("error whiling get list cache: %v", err)
-			c.JSON(http.StatusInternalServerError, errors.New("error whiling get list cache").Error())
-			return
-		}
-
-		fmt.Println("REDIS")
+	resp, err := h.services.User().GetList(
+		c.Request.Context(),
+		&models.GetListUserRequest{
+			Limit:          params.Limit,
+			Offset:         params.Offset,
+			IncludeDeleted: includeDeleted,
+		},
+	)
 
-		c.JSON(http.StatusOK, users)
+	if err != nil {
+		httpapi.Error(c, err)
+		return
 	}
 
+	c.JSON(http.StatusOK, resp)
 }
 
 // UpdateUser godoc
 // @ID update_user
 // @Router /user/{id} [PUT]
 // @Summary Update User
-// @Description Update User
+// @Description Replace the user. Allowed for the user and super admins; only super admins can change the balance and password, others send the current ones.
 // @Tags User
 // @Accept json
 // @Produce json
-// @Param id path string true "id"
+// @Param id path string true "id" format(uuid)
+// @Param If-Match header string false "ETag of the version being replaced"
 // @Param user body models.UpdateUserSwagger true "CreateUserRequestBody"
 // @Success 200 {object} models.User "GetUsersBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Forbidden"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Response 412 {object} httpapi.Response "Precondition Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) UpdateUser(c *gin.Context) {
 
 	var (
-		user models.UpdateUser
+		user  models.UpdateUser
+		param models.IdParam
 	)
 
-	id := c.Param("id")
-
-	if id == "" {
-		log.Printf("error whiling update: %v\n", errors.New("required user id").Error())
-		c.JSON(http.StatusBadRequest, errors.New("required user id").Error())
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	err := c.ShouldBindJSON(&user)
+	err = c.ShouldBindJSON(&user)
 	if err != nil {
-		log.Printf("error whiling update: %v\n", err)
-		c.JSON(http.StatusBadRequest, err.Error())
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	user.Id = id
+	err = checkSelf(c, param.Id, "update it")
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
 
-	rowsAffected, err := h.storage.User().Update(
-		context.Background(),
-		&user,
-	)
+	user.Id = param.Id
+	user.Super = c.GetBool(httpapi.SuperAdminKey)
 
+	user.Version, err = httpapi.IfMatch(c)
 	if err != nil {
-		log.Printf("error whiling update: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling update").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	if rowsAffected == 0 {
-		log.Printf("error whiling update rows affected: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling update rows affected").Error())
+	resp, err := h.services.User().Update(c.Request.Context(), &user)
+	if err != nil {
+		httpapi.Error(c, err)
 		return
 	}
 
-	resp, err := h.storage.User().GetByPKey(
-		context.Background(),
-		&models.UserPrimarKey{Id: id},
-	)
+	httpapi.SetETag(c, resp.Version)
 
+	c.JSON(http.StatusOK, resp)
+}
+
+// PatchUser godoc
+// @ID patch_user
+// @Router /user/{id} [PATCH]
+// @Summary Patch User
+// @Description Update only the fields present in an RFC 7396 merge patch, or apply an RFC 6902 JSON patch. Omitted fields, e.g. balance and password, keep their values. Allowed for the user and super admins; only super admins can change the balance and password.
+// @Tags User
+// @Accept application/merge-patch+json,application/json-patch+json,json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Param If-Match header string false "ETag of the version being replaced"
+// @Param user body models.PatchUserSwagger true "PatchUserRequestBody"
+// @Success 200 {object} models.User "GetUserBody"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Forbidden"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Response 415 {object} httpapi.Response "Unsupported Media Type"
+// @Response 412 {object} httpapi.Response "Precondition Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) PatchUser(c *gin.Context) {
+
+	req, err := bindPatch(c)
 	if err != nil {
-		log.Printf("error whiling GetByPKey: %v\n", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling GetByPKey").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	respList, err := h.storage.User().GetList(
-		context.Background(),
-		&models.GetListUserRequest{
-			Limit:  int32(0),
-			Offset: int32(0),
-		},
-	)
+	err = checkSelf(c, req.Id, "update it")
 	if err != nil {
-		log.Printf("error whiling get list: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling get list").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	err = h.cache.User().Update(context.Background(), respList)
+	req.Super = c.GetBool(httpapi.SuperAdminKey)
+
+	resp, err := h.services.User().Patch(c.Request.Context(), req)
 	if err != nil {
-		log.Printf("error whiling update cache list: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling update cache list").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
+	httpapi.SetETag(c, resp.Version)
+
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -269,55 +269,215 @@ func (h *HandlerV1) UpdateUser(c *gin.Context) {
 // @ID delete_by_id_user
 // @Router /user/{id} [DELETE]
 // @Summary Delete By Id User
-// @Description Delete By Id User
+// @Description Delete the user. Allowed for the user and super admins. It can be restored by a super admin with POST /user/{id}/restore until it is purged.
 // @Tags User
 // @Accept json
 // @Produce json
-// @Param id path string true "id"
+// @Param id path string true "id" format(uuid)
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {object} models.User "GetUserBody"
-// @Response 400 {object} string "Invalid Argument"
-// @Failure 500 {object} string "Server Error"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Forbidden"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Response 412 {object} httpapi.Response "Precondition Failed"
+// @Failure 500 {object} httpapi.Response "Server Error"
 func (h *HandlerV1) DeleteUser(c *gin.Context) {
 
-	id := c.Param("id")
-	if id == "" {
-		log.Printf("error whiling update: %v\n", errors.New("required user id").Error())
-		c.JSON(http.StatusBadRequest, errors.New("required user id").Error())
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	err = checkSelf(c, param.Id, "delete it")
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	version, err := httpapi.IfMatch(c)
+	if err != nil {
+		httpapi.Error(c, err)
 		return
 	}
 
-	err := h.storage.User().Delete(
-		context.Background(),
+	err = h.services.User().Delete(
+		c.Request.Context(),
 		&models.UserPrimarKey{
-			Id: id,
+			Id:      param.Id,
+			Version: version,
 		},
 	)
 
 	if err != nil {
-		log.Printf("error whiling delete: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling delete").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	respList, err := h.storage.User().GetList(
-		context.Background(),
-		&models.GetListUserRequest{
-			Limit:  int32(0),
-			Offset: int32(0),
-		},
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// UnlockUser godoc
+// @ID unlock_user
+// @Router /user/{id}/unlock [POST]
+// @Summary Unlock User
+// @Description Lift the lockout after too many failed logins and forget the failures. Requires a super admin token.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Success 204 "No Content"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Super admin token required"
+// @Response 404 {object} httpapi.Response "Not Found"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) UnlockUser(c *gin.Context) {
+
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	err = h.services.Auth().Unlock(c.Request.Context(), &models.UserPrimarKey{Id: param.Id})
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreUser godoc
+// @ID restore_user
+// @Router /user/{id}/restore [POST]
+// @Summary Restore User
+// @Description Restore a deleted user. Restoring a user that is not deleted returns it unchanged. Requires a super admin token.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Success 200 {object} models.User "GetUserBody"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Super admin token required"
+// @Response 422 {object} httpapi.Response "Validation Failed"
+// @Response 404 {object} httpapi.Response "Not Found, or already purged"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) RestoreUser(c *gin.Context) {
+
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	resp, err := h.services.User().Restore(
+		c.Request.Context(),
+		&models.UserPrimarKey{Id: param.Id},
 	)
+
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	httpapi.SetETag(c, resp.Version)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ExportUser godoc
+// @ID export_user
+// @Router /user/{id}/export [GET]
+// @Summary Export User
+// @Description Download a zip archive of the data held about the user, deleted or not: its profile, orders, linked identity provider accounts and balance history (ledger), each as JSON and CSV. The ledger comes from the audit log and goes back as far as it is retained. Reviews are not included because the API does not store any. Allowed for the user and super admins.
+// @Tags User
+// @Accept json
+// @Produce application/zip
+// @Param id path string true "id" format(uuid)
+// @Success 200 {file} file "user-{id}.zip"
+// @Header 200 {string} Content-Disposition "attachment; filename=user-{id}.zip"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Forbidden"
+// @Response 404 {object} httpapi.Response "Not Found, or already purged"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) ExportUser(c *gin.Context) {
+
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
 	if err != nil {
-		log.Printf("error whiling get list: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling get list").Error())
+		httpapi.Error(c, validation.Error(err))
 		return
 	}
 
-	err = h.cache.User().Update(context.Background(), respList)
+	err = checkSelf(c, param.Id, "export its data")
 	if err != nil {
-		log.Printf("error whiling update cache list: %v", err)
-		c.JSON(http.StatusInternalServerError, errors.New("error whiling update cache list").Error())
+		httpapi.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusNoContent, nil)
+	archive, err := h.services.User().Export(c.Request.Context(), &models.UserPrimarKey{Id: param.Id})
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=user-%s.zip", param.Id))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// EraseUser godoc
+// @ID erase_user
+// @Router /user/{id}/erase [POST]
+// @Summary Erase User
+// @Description Replace the names, login, password, phone number and email of the user, deleted or not, with placeholders, and drop its TOTP, identity provider links, sessions and the values of its audit entries. The user and its orders are kept for accounting. Erasing an erased user returns it unchanged. Allowed for the user and super admins.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path string true "id" format(uuid)
+// @Success 200 {object} models.User "GetUserBody"
+// @Header 200 {string} ETag "Entity tag of the version"
+// @Response 400 {object} httpapi.Response "Invalid Argument"
+// @Response 401 {object} httpapi.Response "Unauthorized"
+// @Response 403 {object} httpapi.Response "Forbidden"
+// @Response 404 {object} httpapi.Response "Not Found, or already purged"
+// @Failure 500 {object} httpapi.Response "Server Error"
+func (h *HandlerV1) EraseUser(c *gin.Context) {
+
+	var param models.IdParam
+
+	err := c.ShouldBindUri(&param)
+	if err != nil {
+		httpapi.Error(c, validation.Error(err))
+		return
+	}
+
+	err = checkSelf(c, param.Id, "erase its data")
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	resp, err := h.services.User().Erase(c.Request.Context(), &models.UserPrimarKey{Id: param.Id})
+	if err != nil {
+		httpapi.Error(c, err)
+		return
+	}
+
+	httpapi.SetETag(c, resp.Version)
+
+	c.JSON(http.StatusOK, resp)
 }
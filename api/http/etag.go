@@ -0,0 +1,98 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"crud/pkg/errs"
+)
+
+const (
+	ETagHeader        = "ETag"
+	IfMatchHeader     = "If-Match"
+	IfNoneMatchHeader = "If-None-Match"
+)
+
+// ETag is the entity tag of a resource version. The version changes on
+// every write, so the tag is strong.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetETag writes the ETag header for version.
+func SetETag(c *gin.Context, version int64) {
+	c.Header(ETagHeader, ETag(version))
+}
+
+// NotModified answers 304 when If-None-Match names version (or is "*") and
+// reports whether it did. Tags are compared weakly, as RFC 9110 requires.
+func NotModified(c *gin.Context, version int64) bool {
+
+	header := c.GetHeader(IfNoneMatchHeader)
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range splitTags(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == ETag(version) {
+			SetETag(c, version)
+			c.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
+
+// IfMatch returns the version required by the If-Match header, or 0 when the
+// write is unconditional (no header or "*"). Tags that no version of a
+// resource can have, including weak ones, fail with PRECONDITION_FAILED.
+func IfMatch(c *gin.Context) (int64, error) {
+
+	header := c.GetHeader(IfMatchHeader)
+	if header == "" {
+		return 0, nil
+	}
+
+	var versions []int64
+
+	for _, tag := range splitTags(header) {
+
+		if tag == "*" {
+			return 0, nil
+		}
+
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if err != nil || version <= 0 {
+			continue
+		}
+
+		versions = append(versions, version)
+	}
+
+	switch len(versions) {
+	case 0:
+		return 0, errs.PreconditionFailed("If-Match %s does not match the current version", header)
+	case 1:
+		return versions[0], nil
+	}
+
+	return 0, errs.InvalidArgument("If-Match with several entity tags is not supported")
+}
+
+func splitTags(header string) []string {
+
+	tags := strings.Split(header, ",")
+	for i := range tags {
+		tags[i] = strings.TrimSpace(tags[i])
+	}
+
+	return tags
+}
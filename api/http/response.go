@@ -1,8 +1,95 @@
-package http
+// Package httpapi holds the HTTP envelope shared by handlers and middlewares.
+// It is named httpapi so it can be imported next to net/http.
+package httpapi
 
-// Response ...
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"crud/pkg/errs"
+	"crud/pkg/tracing"
+	"crud/pkg/validation"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	RequestIDKey    = "request_id"
+	// UserIDKey holds the user_id claim of an authenticated request.
+	UserIDKey = "user_id"
+	// TokenKey holds the *models.Token of an authenticated request.
+	TokenKey = "token"
+	// SuperAdminKey is true when the request carries a super admin token.
+	SuperAdminKey = "super_admin"
+	// APIKeyIDKey holds the id of the API key a request authenticated with.
+	APIKeyIDKey = "api_key_id"
+	// PlainErrorsKey is true on v1 routes, which answer failed requests with
+	// the message as a JSON string instead of a Response.
+	PlainErrorsKey = "plain_errors"
+)
+
+// Response is the envelope of every failed request.
 type Response struct {
-	Status      string      `json:"status"`
-	Description string      `json:"description"`
-	Data        interface{} `json:"data"`
+	Code      errs.Code   `json:"code" example:"NOT_FOUND"`
+	Message   string      `json:"message" example:"book not found"`
+	Details   interface{} `json:"details,omitempty" swaggertype:"object"`
+	RequestID string      `json:"request_id,omitempty"`
+	TraceID   string      `json:"trace_id,omitempty"`
+}
+
+// Error writes err as a Response, or as its message on v1 routes, and aborts
+// the request. Errors that are not an *errs.Error are reported as INTERNAL
+// without leaking their text; the full error is attached to the context and
+// written by the access log.
+func Error(c *gin.Context, err error) {
+
+	e := errs.From(err)
+
+	c.Error(err)
+
+	if e.RetryAfter > 0 {
+		// Whole seconds, rounded up so the client never retries too early.
+		c.Header("Retry-After", strconv.FormatInt(int64((e.RetryAfter+time.Second-1)/time.Second), 10))
+	}
+
+	if c.GetBool(PlainErrorsKey) {
+		c.AbortWithStatusJSON(errs.HTTPStatus(e.Code), plainMessage(e))
+		return
+	}
+
+	c.AbortWithStatusJSON(errs.HTTPStatus(e.Code), Response{
+		Code:      e.Code,
+		Message:   e.Message,
+		Details:   e.Details,
+		RequestID: RequestID(c),
+		TraceID:   tracing.TraceID(c.Request.Context()),
+	})
+}
+
+// plainMessage is the message of e with the field errors it has, if any.
+func plainMessage(e *errs.Error) string {
+
+	fields, ok := e.Details.([]validation.FieldError)
+	if !ok || len(fields) == 0 {
+		return e.Message
+	}
+
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field.Field+" "+field.Message)
+	}
+
+	return e.Message + ": " + strings.Join(messages, ", ")
+}
+
+// RequestID returns the id of the current request, if any.
+func RequestID(c *gin.Context) string {
+
+	if id := c.GetString(RequestIDKey); id != "" {
+		return id
+	}
+
+	return c.GetHeader(RequestIDHeader)
 }
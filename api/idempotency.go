@@ -0,0 +1,186 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"crud/api/http"
+	"crud/config"
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/validation"
+	"crud/storage"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response served from the store.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+var idempotencyKeyRegex = regexp.MustCompile(`^[\x21-\x7e]{1,255}$`)
+
+// replayedHeaders are stored with an idempotent response and sent again on
+// replays.
+var replayedHeaders = []string{"Content-Type", httpapi.ETagHeader}
+
+// idempotencyMiddleware makes retries of a POST with the same
+// Idempotency-Key return the first response instead of running the request
+// again. Keys are scoped by route and caller: the user, the API key or, for
+// anonymous requests, the client IP. A key reused with a different body
+// fails with VALIDATION_FAILED, and a retry while the first request is still
+// running with CONFLICT. The first final response is stored, errors such
+// as INSUFFICIENT_FUNDS included; only server errors and requests the client
+// abandoned are not, so they can be retried with the same key.
+//
+// When the store is unavailable the request is rejected rather than risking
+// a duplicate.
+func idempotencyMiddleware(cfg *config.Config, cache storage.CacheI) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if !idempotencyKeyRegex.MatchString(key) {
+			httpapi.Error(c, errs.InvalidArgument("%s must be 1 to 255 printable ASCII characters", IdempotencyKeyHeader))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			httpapi.Error(c, validation.Error(err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var (
+			ctx         = c.Request.Context()
+			scope       = c.Request.Method + " " + c.FullPath() + " " + idempotencyCaller(c) + " " + key
+			fingerprint = requestFingerprint(c.Request.Method, c.FullPath(), body)
+		)
+
+		// The pending record has to outlive the request, which runs until
+		// its route's timeout even after the write timeout cut the client
+		// off.
+		pendingTTL := max(cfg.LongestTimeout(), time.Duration(cfg.HTTPWriteTimeout))
+		if cfg.LongestTimeout() <= 0 {
+			pendingTTL = time.Duration(cfg.IdempotencyTTL)
+		}
+
+		stored, err := cache.Idempotency().Reserve(ctx, scope, fingerprint, pendingTTL)
+		if err != nil {
+			httpapi.Error(c, err)
+			return
+		}
+
+		if stored != nil {
+			replay(c, stored, fingerprint)
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		// The request context may have expired by now.
+		ctx = context.WithoutCancel(ctx)
+
+		if writer.Status() >= http.StatusInternalServerError || writer.Status() == errs.StatusClientClosedRequest {
+			err = cache.Idempotency().Delete(ctx, scope)
+		} else {
+			resp := models.IdempotentResponse{
+				Fingerprint: fingerprint,
+				Status:      writer.Status(),
+				Header:      map[string]string{},
+				Body:        writer.body.Bytes(),
+			}
+			for _, name := range replayedHeaders {
+				if value := writer.Header().Get(name); value != "" {
+					resp.Header[name] = value
+				}
+			}
+
+			err = cache.Idempotency().Save(ctx, scope, &resp, time.Duration(cfg.IdempotencyTTL))
+		}
+
+		if err != nil {
+			// The response is already sent; the access log reports the error.
+			c.Error(err)
+		}
+	}
+}
+
+// idempotencyCaller names who makes the request, so callers cannot replay
+// each other's responses.
+func idempotencyCaller(c *gin.Context) string {
+
+	if id := c.GetString(httpapi.UserIDKey); id != "" {
+		return "user:" + id
+	}
+
+	if id := c.GetString(httpapi.APIKeyIDKey); id != "" {
+		return "api_key:" + id
+	}
+
+	return "ip:" + c.ClientIP()
+}
+
+func replay(c *gin.Context, stored *models.IdempotentResponse, fingerprint string) {
+
+	if stored.Fingerprint != fingerprint {
+		httpapi.Error(c, errs.Validation("%s was already used for a different request", IdempotencyKeyHeader))
+		return
+	}
+
+	if stored.Status == 0 {
+		httpapi.Error(c, errs.Conflict("a request with this %s is still in progress", IdempotencyKeyHeader))
+		return
+	}
+
+	for name, value := range stored.Header {
+		c.Header(name, value)
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+
+	c.Status(stored.Status)
+	c.Writer.Write(stored.Body)
+	c.Abort()
+}
+
+// requestFingerprint identifies a request by route and body.
+func requestFingerprint(method, route string, body []byte) string {
+
+	h := sha256.New()
+	h.Write([]byte(method + " " + route + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response body.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"crud/api/http"
+	"crud/pkg/audit"
+	"crud/pkg/errs"
+	"crud/pkg/logging"
+	"crud/pkg/metrics"
+	"crud/pkg/tracing"
+)
+
+var requestIDRegex = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestIDMiddleware propagates the caller's X-Request-ID, or generates one,
+// and echoes it in the response. The id is added to the request context so
+// every log line of the request carries it.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		id := c.GetHeader(httpapi.RequestIDHeader)
+		if !requestIDRegex.MatchString(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(httpapi.RequestIDKey, id)
+		c.Header(httpapi.RequestIDHeader, id)
+
+		ctx := logging.With(c.Request.Context(), slog.String(httpapi.RequestIDKey, id))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// auditMiddleware puts the address and request id of the request on its
+// context for the audit log. setUser and checkAPIKey add who made it.
+func auditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		ctx := audit.WithActor(c.Request.Context(), audit.Actor{
+			IP:        c.ClientIP(),
+			RequestID: c.GetString(httpapi.RequestIDKey),
+		})
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// tracingMiddleware starts the server span of the request, continuing the
+// trace of an incoming W3C traceparent header.
+func tracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+
+		ctx, span := tracing.Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				attribute.String(httpapi.RequestIDKey, httpapi.RequestID(c)),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+		if userID := c.GetString(httpapi.UserIDKey); userID != "" {
+			span.SetAttributes(attribute.String(httpapi.UserIDKey, userID))
+		}
+
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+			if err := c.Errors.Last(); err != nil {
+				span.RecordError(err.Err)
+			}
+		}
+	}
+}
+
+// accessLogMiddleware writes one line per request. Server errors are logged
+// at error level with the underlying error, client errors at warn level.
+func accessLogMiddleware(log *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		start := time.Now()
+		ctx := c.Request.Context()
+
+		c.Next()
+
+		status := c.Writer.Status()
+
+		attrs := []any{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+
+		if userID := c.GetString(httpapi.UserIDKey); userID != "" {
+			attrs = append(attrs, slog.String(httpapi.UserIDKey, userID))
+		}
+
+		if apiKeyID := c.GetString(httpapi.APIKeyIDKey); apiKeyID != "" {
+			attrs = append(attrs, slog.String(httpapi.APIKeyIDKey, apiKeyID))
+		}
+
+		if err := c.Errors.Last(); err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		log.Log(ctx, level, "request", attrs...)
+	}
+}
+
+// recoveryMiddleware turns a panic into an INTERNAL response. The panic and
+// its stack end up in the access log line of the request.
+func recoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		httpapi.Error(c, errs.Internal(fmt.Errorf("panic: %v\n%s", recovered, debug.Stack())))
+	})
+}
+
+// metricsMiddleware records the count and latency of every request.
+func metricsMiddleware(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		start := time.Now()
+
+		c.Next()
+
+		m.ObserveRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
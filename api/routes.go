@@ -0,0 +1,161 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+
+	"crud/api/handler"
+	"crud/api/http"
+	"crud/config"
+	"crud/service"
+	"crud/storage"
+)
+
+// legacyDeprecatedAt is when the unversioned routes were superseded by /v1.
+var legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// routesV1 registers the v1 contract. It only takes additive changes;
+// anything breaking goes to v2. Failed requests are answered with the
+// message alone, as a JSON string, like before the error envelope.
+func routesV1(g *gin.RouterGroup, cfg *config.Config, h *handler.HandlerV1, services *service.Service, cache storage.CacheI) {
+	g.Use(plainErrorsMiddleware())
+	routes(g, cfg, h, services, cache)
+}
+
+// routesV2 registers the v2 contract, where breaking changes are shipped:
+// failed requests are answered with the httpapi.Response envelope.
+func routesV2(g *gin.RouterGroup, cfg *config.Config, h *handler.HandlerV1, services *service.Service, cache storage.CacheI) {
+	routes(g, cfg, h, services, cache)
+}
+
+// routes registers the routes every version shares.
+func routes(g *gin.RouterGroup, cfg *config.Config, h *handler.HandlerV1, services *service.Service, cache storage.CacheI) {
+
+	var (
+		idempotent = idempotencyMiddleware(cfg, cache)
+		limited    = rateLimitMiddleware(cfg, cache)
+		revoked    = checkRevoked(services.Auth())
+	)
+
+	g.POST("/login", limited, h.Login)
+	g.POST("/loginsuper", limited, h.LoginSuper)
+	g.POST("/login/mfa", limited, h.LoginMFA)
+	g.GET("/login/oidc", limited, h.LoginOIDC)
+	g.GET("/login/oidc/callback", limited, h.LoginOIDCCallback)
+
+	g.POST("/refreshclienttoken")
+
+	g.POST("/register", limited, h.Register)
+	g.POST("/verify-email", h.VerifyEmail)
+	g.POST("/password/forgot", limited, h.ForgotPassword)
+	g.POST("/password/reset", h.ResetPassword)
+
+	auth := g.Group("", checkToken(services.Auth(), services.APIKey()), revoked, limited)
+
+	auth.POST("/logout", h.Logout)
+	auth.GET("/me/sessions", h.GetMySessions)
+
+	auth.GET("/mfa/totp", h.GetTOTP)
+	auth.POST("/mfa/totp", h.EnrollTOTP)
+	auth.GET("/mfa/totp/qr", h.GetTOTPQR)
+	auth.POST("/mfa/totp/confirm", h.ConfirmTOTP)
+	auth.POST("/mfa/totp/disable", h.DisableTOTP)
+	auth.POST("/mfa/recovery-codes", h.RegenerateRecoveryCodes)
+
+	auth.POST("/book", idempotent, h.CreateBook)
+	auth.GET("/book/:id", h.GetBookById)
+	auth.GET("/book", h.GetBookList)
+	auth.PUT("/book/:id", h.UpdateBook)
+	auth.PATCH("/book/:id", h.PatchBook)
+	auth.DELETE("/book/:id", h.DeleteBook)
+
+	auth.GET("/user/:id", h.GetUserById)
+	auth.GET("/user", h.GetUserList)
+	auth.PUT("/user/:id", h.UpdateUser)
+	auth.PATCH("/user/:id", h.PatchUser)
+	auth.DELETE("/user/:id", h.DeleteUser)
+	auth.POST("/user/:id/sessions/revoke-all", h.RevokeAllSessions)
+	auth.GET("/user/:id/export", h.ExportUser)
+	auth.POST("/user/:id/erase", h.EraseUser)
+
+	auth.POST("/order", idempotent, h.CreateOrder)
+	auth.GET("/order/:id", h.GetOrderById)
+	auth.GET("/order", h.GetOrderList)
+	auth.PUT("/order/:id", h.UpdateOrder)
+	auth.PATCH("/order/:id", h.PatchOrder)
+	auth.DELETE("/order/:id", h.DeleteOrder)
+
+	admin := g.Group("", requireSuper(services.Auth()), revoked, limited)
+
+	admin.POST("/book/:id/restore", h.RestoreBook)
+
+	admin.POST("/user", idempotent, h.CreateUser)
+	admin.POST("/user/:id/unlock", h.UnlockUser)
+	admin.POST("/user/:id/restore", h.RestoreUser)
+
+	admin.POST("/order/:id/restore", h.RestoreOrder)
+
+	admin.POST("/api-keys", h.CreateAPIKey)
+	admin.GET("/api-keys", h.GetAPIKeyList)
+	admin.GET("/api-keys/:id", h.GetAPIKeyById)
+	admin.DELETE("/api-keys/:id", h.DeleteAPIKey)
+
+	admin.GET("/audit", h.GetAuditLogList)
+}
+
+// versionPrefixes are the route groups of the API versions.
+var versionPrefixes = []string{"/v1", "/v2"}
+
+// routeKey is the route template of c without its version prefix, which
+// route-keyed settings such as HTTP_ROUTE_TIMEOUTS are matched against, so
+// "POST /order" covers /order, /v1/order and /v2/order.
+func routeKey(c *gin.Context) string {
+
+	route := c.FullPath()
+	for _, prefix := range versionPrefixes {
+		if strings.HasPrefix(route, prefix+"/") {
+			return strings.TrimPrefix(route, prefix)
+		}
+	}
+
+	return route
+}
+
+// swaggerRoute serves the swagger document registered under instance.
+func swaggerRoute(g *gin.RouterGroup, instance string) {
+	g.GET("/swagger/*any", ginSwagger.WrapHandler(
+		swaggerFiles.Handler,
+		ginSwagger.InstanceName(instance),
+		ginSwagger.URL("doc.json"),
+	))
+}
+
+// plainErrorsMiddleware makes httpapi.Error answer with the message alone.
+func plainErrorsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(httpapi.PlainErrorsKey, true)
+		c.Next()
+	}
+}
+
+// deprecatedMiddleware marks responses of deprecated routes with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers, and links to the
+// route that replaces them under successorPrefix.
+func deprecatedMiddleware(deprecatedAt, sunset time.Time, successorPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		c.Header("Deprecation", fmt.Sprintf("@%d", deprecatedAt.Unix()))
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		c.Header("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successorPrefix, c.Request.URL.Path))
+
+		c.Next()
+	}
+}
@@ -0,0 +1,9 @@
+package api
+
+import _ "crud/api/docs/v1"
+
+// @title Book API
+// @version 1.0
+// @description Books, users and orders. v1 is frozen: it only receives additive changes. Failed requests are answered with the error message as a JSON string; unlike the unversioned API before v1, the status says what went wrong (e.g. 404 for a missing book rather than 500).
+// @BasePath /v1
+const swaggerV1 = "v1"
@@ -0,0 +1,9 @@
+package api
+
+import _ "crud/api/docs/v2"
+
+// @title Book API
+// @version 2.0
+// @description Books, users and orders. v2 is where breaking changes are shipped. Unlike v1, failed requests are answered with an envelope carrying a stable error code, the message, details such as field errors, and the request id.
+// @BasePath /v2
+const swaggerV2 = "v2"
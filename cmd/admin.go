@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"crud/config"
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/logging"
+	"crud/pkg/metrics"
+	"crud/service"
+	"crud/storage/redis"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const adminUsage = "usage: admin grant|revoke <login>"
+
+// runAdmin grants or revokes the admin flag that super admin logins need.
+// There is no HTTP route for it on purpose: only whoever can run the binary
+// against the database may hand out admin rights.
+func runAdmin(ctx context.Context, cfg config.Config, args []string) error {
+
+	if len(args) != 2 {
+		return errors.New(adminUsage)
+	}
+
+	var isAdmin bool
+
+	switch args[0] {
+	case "grant":
+		isAdmin = true
+	case "revoke":
+		isAdmin = false
+	default:
+		return errors.New(adminUsage)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return err
+	}
+
+	err = checkSchema(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	storage, err := newStorage(ctx, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("connect storage: %w", err)
+	}
+	defer storage.CloseDB()
+
+	cache, err := redis.NewRedis(ctx, cfg, logger, metrics.New())
+	if err != nil {
+		return fmt.Errorf("connect redis: %w", err)
+	}
+	defer cache.CloseDB()
+
+	user, err := service.NewUserService(logger, storage, cache).SetAdmin(ctx, &models.SetAdmin{Login: args[1], IsAdmin: isAdmin})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("user %s (%s) is_admin=%t\n", user.Login, user.Id, user.IsAdmin)
+
+	if !user.IsAdmin || !cfg.LoginSuperRequireMFA {
+		return nil
+	}
+
+	// The admin still has to enroll TOTP before a super admin login works.
+	mfa, err := storage.TOTP().GetByPKey(ctx, &models.TOTPPrimarKey{User_id: user.Id})
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return err
+	}
+
+	if mfa == nil || !mfa.Enabled {
+		fmt.Println("warning: super admin login is refused until the user enables TOTP with POST /mfa/totp")
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crud/config"
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+const configUsage = "usage: config print [--redacted]"
+
+func runConfig(cfg config.Config, args []string) error {
+
+	if len(args) == 0 || args[0] != "print" || len(args) > 2 {
+		return errors.New(configUsage)
+	}
+
+	printed := cfg
+
+	if len(args) == 2 {
+		if args[1] != "--redacted" && args[1] != "-redacted" {
+			return errors.New(configUsage)
+		}
+		printed = cfg.Redacted()
+	}
+
+	body, err := yaml.Marshal(printed)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(body))
+
+	// The effective configuration is printed even when it is invalid, so it
+	// can be used to debug the problems listed here.
+	err = cfg.Validate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	return nil
+}
@@ -2,40 +2,63 @@ package main
 
 import (
 	"context"
-	"crud/api"
 	"crud/config"
-	"crud/storage/postgres"
-	"crud/storage/redis"
+	"crud/pkg/logging"
 	"log"
-
-	"github.com/gin-gonic/gin"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 
-	cfg := config.Load()
-
-	r := gin.New()
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	r.Use(gin.Logger(), gin.Recovery())
+	if len(args) > 0 && args[0] == "config" {
+		err = runConfig(cfg, args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	storage, err := postgres.NewPostgres(context.Background(), cfg)
+	err = cfg.Validate()
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer storage.CloseDB()
 
-	cache, err := redis.NewRedis(context.Background(), cfg)
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			err = runMigrate(context.Background(), cfg, args[1:])
+		case "admin":
+			err = runAdmin(context.Background(), cfg, args[1:])
+		default:
+			log.Fatalf("unknown command %q, expected migrate, admin or config", args[0])
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer cache.CloseDB()
+	slog.SetDefault(logger)
 
-	api.SetUpApi(&cfg, r, storage, cache)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Printf("Listening port %v...\n", cfg.HTTPPort)
-	err = r.Run(cfg.HTTPPort)
+	err = runServer(ctx, cfg, logger)
 	if err != nil {
-		panic(err)
+		logger.Error("error whiling run server", slog.Any("error", err))
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crud/config"
+	"crud/migrations"
+	"crud/pkg/migrate"
+	"crud/storage/postgres"
+	"crud/storage/sqlite"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const migrateUsage = "usage: migrate up|down|status|goto <version>|baseline <version>|force <version>"
+
+func runMigrate(ctx context.Context, cfg config.Config, args []string) error {
+
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, db, err := newMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "goto", "baseline", "force":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+
+		var version uint64
+		version, err = strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+
+		switch args[0] {
+		case "goto":
+			err = migrator.Goto(ctx, uint(version))
+		case "baseline":
+			err = migrator.Baseline(ctx, uint(version))
+		case "force":
+			err = migrator.Force(ctx, uint(version))
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(migrate.FormatStatus(statuses))
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("schema version %d (latest %d)\n", version, migrator.Latest())
+
+	return nil
+}
+
+func newMigrator(cfg config.Config) (*migrate.Migrator, *sql.DB, error) {
+
+	var (
+		db      *sql.DB
+		dialect migrate.Dialect
+		err     error
+	)
+
+	switch cfg.StorageDriver {
+	case config.StorageDriverPostgres:
+		db, err = postgres.OpenDB(cfg)
+		dialect = migrate.Postgres
+	case config.StorageDriverSQLite:
+		db, err = sqlite.OpenDB(cfg)
+		dialect = migrate.SQLite
+	default:
+		return nil, nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fsys := migrations.Postgres()
+	if dialect == migrate.SQLite {
+		fsys = migrations.SQLite()
+	}
+
+	migrator, err := migrate.New(db, dialect, fsys)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return migrator, db, nil
+}
+
+// checkSchema refuses to serve against a database that is missing migrations.
+func checkSchema(ctx context.Context, cfg config.Config) error {
+
+	migrator, db, err := newMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return migrator.Check(ctx)
+}
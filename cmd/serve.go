@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"crud/api"
+	"crud/config"
+	"crud/pkg/mail"
+	"crud/pkg/metrics"
+	"crud/pkg/token"
+	"crud/pkg/tracing"
+	"crud/service"
+	"crud/storage"
+	"crud/storage/postgres"
+	"crud/storage/redis"
+	"crud/storage/sqlite"
+)
+
+// runServer serves the API until ctx is cancelled, then drains in-flight
+// requests and closes the database, Redis and the tracer in that order.
+func runServer(ctx context.Context, cfg config.Config, logger *slog.Logger) error {
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		ServiceName: cfg.TracingServiceName,
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		Insecure:    cfg.TracingInsecure,
+	})
+	if err != nil {
+		return fmt.Errorf("set up tracing: %w", err)
+	}
+	defer func() {
+		err := shutdownTracing(context.Background())
+		if err != nil {
+			logger.Error("error whiling flush traces", slog.Any("error", err))
+		}
+	}()
+
+	err = checkSchema(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv(gin.EnvGinMode) == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	r := gin.New()
+
+	metrics := metrics.New()
+
+	storage, err := newStorage(ctx, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("connect storage: %w", err)
+	}
+	defer storage.CloseDB()
+
+	if collector, ok := storage.(prometheus.Collector); ok {
+		err = metrics.Register(collector)
+		if err != nil {
+			return fmt.Errorf("register storage metrics: %w", err)
+		}
+	}
+
+	cache, err := redis.NewRedis(ctx, cfg, logger, metrics)
+	if err != nil {
+		return fmt.Errorf("connect redis: %w", err)
+	}
+	defer cache.CloseDB()
+
+	keys, err := token.NewKeySet(token.KeySetOptions{
+		Dir:       cfg.JWTKeysDir,
+		Algorithm: cfg.JWTAlgorithm,
+		Rotation:  time.Duration(cfg.JWTKeyRotation),
+		Retention: config.TimeExpiredAt,
+	}, logger)
+	if err != nil {
+		return fmt.Errorf("load signing keys: %w", err)
+	}
+	go keys.Run(ctx, time.Duration(cfg.JWTKeyReload))
+
+	go service.NewAuditService(&cfg, logger, storage).Run(ctx, time.Duration(cfg.AuditPurgeInterval))
+	go service.NewPurgeService(&cfg, logger, storage).Run(ctx, time.Duration(cfg.DeletedPurgeInterval))
+
+	tokens := token.NewIssuer(keys, cfg.JWTIssuer, cfg.JWTAudience)
+
+	err = api.SetUpApi(&cfg, logger, metrics, r, storage, cache, tokens, newMailer(cfg, logger))
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              cfg.HTTPPort,
+		Handler:           r,
+		ReadTimeout:       time.Duration(cfg.HTTPReadTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.HTTPReadHeaderTimeout),
+		WriteTimeout:      time.Duration(cfg.HTTPWriteTimeout),
+		IdleTimeout:       time.Duration(cfg.HTTPIdleTimeout),
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("listening", slog.String("addr", cfg.HTTPPort))
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down, draining in-flight requests", slog.Duration("timeout", time.Duration(cfg.HTTPShutdownTimeout)))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.HTTPShutdownTimeout))
+	defer cancel()
+
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+
+	err = <-serveErr
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	logger.Info("server stopped")
+
+	return nil
+}
+
+func newStorage(ctx context.Context, cfg config.Config, logger *slog.Logger) (storage.StorageI, error) {
+	switch cfg.StorageDriver {
+	case config.StorageDriverPostgres:
+		return postgres.NewPostgres(ctx, cfg, logger)
+	case config.StorageDriverSQLite:
+		return sqlite.NewSQLite(ctx, cfg, logger)
+	}
+
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+}
+
+func newMailer(cfg config.Config, logger *slog.Logger) mail.Mailer {
+	switch cfg.MailDriver {
+	case config.MailDriverSMTP:
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case config.MailDriverFile:
+		return mail.NewFileMailer(cfg.MailDir, cfg.MailFrom)
+	}
+
+	return mail.NewLogMailer(logger)
+}
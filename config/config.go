@@ -1,44 +1,219 @@
 package config
 
+import "time"
+
+// Config is filled in layers: the defaults below, then the config file
+// (-config flag or CONFIG_FILE), then environment variables, then flags.
+//
+// Every env variable can also be read from a file by appending _FILE to its
+// name, e.g. POSTGRES_PASSWORD_FILE=/run/secrets/pg. Fields tagged
+// secret:"true" are hidden by `config print --redacted`.
 type Config struct {
-	HTTPPort string
-
-	PostgresHost           string
-	PostgresUser           string
-	PostgresDatabase       string
-	PostgresPassword       string
-	PostgresPort           string
-	PostgresMaxConnections int32
-
-	RedisAddr     string
-	RedisPassword string
-	RedisDB       int
-
-	AuthSecretKey string
-	SuperAdmin    string
-	Client        string
+	HTTPPort           string        `yaml:"http_port" toml:"http_port" env:"HTTP_PORT" flag:"http-port"`
+	HTTPRequestTimeout Duration      `yaml:"http_request_timeout" toml:"http_request_timeout" env:"HTTP_REQUEST_TIMEOUT" flag:"http-request-timeout"`
+	HTTPRouteTimeouts  RouteTimeouts `yaml:"http_route_timeouts" toml:"http_route_timeouts" env:"HTTP_ROUTE_TIMEOUTS" flag:"http-route-timeouts"`
+
+	// Timeouts of the http.Server. HTTPShutdownTimeout bounds how long
+	// in-flight requests may take to drain on SIGTERM.
+	HTTPReadTimeout       Duration `yaml:"http_read_timeout" toml:"http_read_timeout" env:"HTTP_READ_TIMEOUT" flag:"http-read-timeout"`
+	HTTPReadHeaderTimeout Duration `yaml:"http_read_header_timeout" toml:"http_read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" flag:"http-read-header-timeout"`
+	HTTPWriteTimeout      Duration `yaml:"http_write_timeout" toml:"http_write_timeout" env:"HTTP_WRITE_TIMEOUT" flag:"http-write-timeout"`
+	HTTPIdleTimeout       Duration `yaml:"http_idle_timeout" toml:"http_idle_timeout" env:"HTTP_IDLE_TIMEOUT" flag:"http-idle-timeout"`
+	HTTPShutdownTimeout   Duration `yaml:"http_shutdown_timeout" toml:"http_shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT" flag:"http-shutdown-timeout"`
+
+	// TrustedProxies are the IPs and CIDRs, space separated, whose
+	// X-Forwarded-For and X-Real-IP headers give the client IP that rate
+	// limits, idempotency keys and the audit log use. By default no proxy
+	// is trusted and the client IP is the peer address.
+	TrustedProxies string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES" flag:"trusted-proxies"`
+
+	// LegacyRoutesSunset is announced in the Sunset header of the deprecated
+	// unversioned routes. Empty leaves the header out.
+	LegacyRoutesSunset Date `yaml:"legacy_routes_sunset" toml:"legacy_routes_sunset" env:"LEGACY_ROUTES_SUNSET" flag:"legacy-routes-sunset"`
+
+	// IdempotencyTTL is how long the response to a request with an
+	// Idempotency-Key is kept for replays.
+	IdempotencyTTL Duration `yaml:"idempotency_ttl" toml:"idempotency_ttl" env:"IDEMPOTENCY_TTL" flag:"idempotency-ttl"`
+
+	// RateLimits are enforced per route and principal; see RateLimits.
+	RateLimits RateLimits `yaml:"rate_limits" toml:"rate_limits" env:"RATE_LIMITS" flag:"rate-limits"`
+
+	// A login is locked for LoginLockoutBase once it has failed
+	// LoginLockoutThreshold times within LoginFailureWindow. Every further
+	// failure doubles the lockout, up to LoginLockoutMax.
+	LoginLockoutThreshold int      `yaml:"login_lockout_threshold" toml:"login_lockout_threshold" env:"LOGIN_LOCKOUT_THRESHOLD" flag:"login-lockout-threshold"`
+	LoginLockoutBase      Duration `yaml:"login_lockout_base" toml:"login_lockout_base" env:"LOGIN_LOCKOUT_BASE" flag:"login-lockout-base"`
+	LoginLockoutMax       Duration `yaml:"login_lockout_max" toml:"login_lockout_max" env:"LOGIN_LOCKOUT_MAX" flag:"login-lockout-max"`
+	LoginFailureWindow    Duration `yaml:"login_failure_window" toml:"login_failure_window" env:"LOGIN_FAILURE_WINDOW" flag:"login-failure-window"`
+
+	// Users with TOTP enabled log in with a password and then a code, sent
+	// within MFATokenTTL. LoginSuperRequireMFA refuses super admin logins
+	// of admins without TOTP; users that are not admins are always refused.
+	MFATokenTTL          Duration `yaml:"mfa_token_ttl" toml:"mfa_token_ttl" env:"MFA_TOKEN_TTL" flag:"mfa-token-ttl"`
+	LoginSuperRequireMFA bool     `yaml:"login_super_require_mfa" toml:"login_super_require_mfa" env:"LOGIN_SUPER_REQUIRE_MFA" flag:"login-super-require-mfa"`
+	// TOTPIssuer labels the account in authenticator apps.
+	TOTPIssuer string `yaml:"totp_issuer" toml:"totp_issuer" env:"TOTP_ISSUER" flag:"totp-issuer"`
+
+	// OIDCIssuer enables signing in through an OpenID Connect provider at
+	// GET /login/oidc. The provider sends the user back to OIDCRedirectURL,
+	// which must route to GET /login/oidc/callback, within OIDCStateTTL.
+	// OIDCScopes are requested on top of openid, space separated.
+	OIDCIssuer       string   `yaml:"oidc_issuer" toml:"oidc_issuer" env:"OIDC_ISSUER" flag:"oidc-issuer"`
+	OIDCClientID     string   `yaml:"oidc_client_id" toml:"oidc_client_id" env:"OIDC_CLIENT_ID" flag:"oidc-client-id"`
+	OIDCClientSecret string   `yaml:"oidc_client_secret" toml:"oidc_client_secret" env:"OIDC_CLIENT_SECRET" flag:"oidc-client-secret" secret:"true"`
+	OIDCRedirectURL  string   `yaml:"oidc_redirect_url" toml:"oidc_redirect_url" env:"OIDC_REDIRECT_URL" flag:"oidc-redirect-url"`
+	OIDCScopes       string   `yaml:"oidc_scopes" toml:"oidc_scopes" env:"OIDC_SCOPES" flag:"oidc-scopes"`
+	OIDCStateTTL     Duration `yaml:"oidc_state_ttl" toml:"oidc_state_ttl" env:"OIDC_STATE_TTL" flag:"oidc-state-ttl"`
+
+	LogLevel  string `yaml:"log_level" toml:"log_level" env:"LOG_LEVEL" flag:"log-level"`
+	LogFormat string `yaml:"log_format" toml:"log_format" env:"LOG_FORMAT" flag:"log-format"`
+
+	TracingExporter    string `yaml:"tracing_exporter" toml:"tracing_exporter" env:"TRACING_EXPORTER" flag:"tracing-exporter"`
+	TracingEndpoint    string `yaml:"tracing_endpoint" toml:"tracing_endpoint" env:"TRACING_ENDPOINT" flag:"tracing-endpoint"`
+	TracingInsecure    bool   `yaml:"tracing_insecure" toml:"tracing_insecure" env:"TRACING_INSECURE" flag:"tracing-insecure"`
+	TracingServiceName string `yaml:"tracing_service_name" toml:"tracing_service_name" env:"TRACING_SERVICE_NAME" flag:"tracing-service-name"`
+
+	StorageDriver string `yaml:"storage_driver" toml:"storage_driver" env:"STORAGE_DRIVER" flag:"storage-driver"`
+
+	PostgresHost           string `yaml:"postgres_host" toml:"postgres_host" env:"POSTGRES_HOST" flag:"postgres-host"`
+	PostgresUser           string `yaml:"postgres_user" toml:"postgres_user" env:"POSTGRES_USER" flag:"postgres-user"`
+	PostgresDatabase       string `yaml:"postgres_database" toml:"postgres_database" env:"POSTGRES_DATABASE" flag:"postgres-database"`
+	PostgresPassword       string `yaml:"postgres_password" toml:"postgres_password" env:"POSTGRES_PASSWORD" flag:"postgres-password" secret:"true"`
+	PostgresPort           string `yaml:"postgres_port" toml:"postgres_port" env:"POSTGRES_PORT" flag:"postgres-port"`
+	PostgresMaxConnections int32  `yaml:"postgres_max_connections" toml:"postgres_max_connections" env:"POSTGRES_MAX_CONNECTIONS" flag:"postgres-max-connections"`
+	// PostgresStatementTimeout is sent as statement_timeout on every
+	// connection, so a runaway query is cancelled by the server as well.
+	PostgresStatementTimeout Duration `yaml:"postgres_statement_timeout" toml:"postgres_statement_timeout" env:"POSTGRES_STATEMENT_TIMEOUT" flag:"postgres-statement-timeout"`
+
+	SQLitePath string `yaml:"sqlite_path" toml:"sqlite_path" env:"SQLITE_PATH" flag:"sqlite-path"`
+
+	RedisAddr     string `yaml:"redis_addr" toml:"redis_addr" env:"REDIS_ADDR" flag:"redis-addr"`
+	RedisPassword string `yaml:"redis_password" toml:"redis_password" env:"REDIS_PASSWORD" flag:"redis-password" secret:"true"`
+	RedisDB       int    `yaml:"redis_db" toml:"redis_db" env:"REDIS_DB" flag:"redis-db"`
+
+	// Tokens are signed with the newest private key in JWTKeysDir, named
+	// <kid>.pem. Every JWTKeyRotation a new JWTAlgorithm key is generated
+	// there, and replaced keys are removed once no token they signed can
+	// still be valid. With JWTKeyRotation 0 the keys are managed by hand.
+	// Instances sharing the directory pick up each other's keys every
+	// JWTKeyReload.
+	JWTKeysDir     string   `yaml:"jwt_keys_dir" toml:"jwt_keys_dir" env:"JWT_KEYS_DIR" flag:"jwt-keys-dir"`
+	JWTAlgorithm   string   `yaml:"jwt_algorithm" toml:"jwt_algorithm" env:"JWT_ALGORITHM" flag:"jwt-algorithm"`
+	JWTKeyRotation Duration `yaml:"jwt_key_rotation" toml:"jwt_key_rotation" env:"JWT_KEY_ROTATION" flag:"jwt-key-rotation"`
+	JWTKeyReload   Duration `yaml:"jwt_key_reload" toml:"jwt_key_reload" env:"JWT_KEY_RELOAD" flag:"jwt-key-reload"`
+	JWTIssuer      string   `yaml:"jwt_issuer" toml:"jwt_issuer" env:"JWT_ISSUER" flag:"jwt-issuer"`
+	JWTAudience    string   `yaml:"jwt_audience" toml:"jwt_audience" env:"JWT_AUDIENCE" flag:"jwt-audience"`
+
+	// PublicURL is the base URL of the web app the links in emails point
+	// to, e.g. PublicURL/verify-email?token=...
+	PublicURL            string   `yaml:"public_url" toml:"public_url" env:"PUBLIC_URL" flag:"public-url"`
+	EmailVerificationTTL Duration `yaml:"email_verification_ttl" toml:"email_verification_ttl" env:"EMAIL_VERIFICATION_TTL" flag:"email-verification-ttl"`
+	PasswordResetTTL     Duration `yaml:"password_reset_ttl" toml:"password_reset_ttl" env:"PASSWORD_RESET_TTL" flag:"password-reset-ttl"`
+
+	// AuditRetention is how long audit log entries are kept, 0 for ever.
+	// Older entries are deleted every AuditPurgeInterval.
+	AuditRetention     Duration `yaml:"audit_retention" toml:"audit_retention" env:"AUDIT_RETENTION" flag:"audit-retention"`
+	AuditPurgeInterval Duration `yaml:"audit_purge_interval" toml:"audit_purge_interval" env:"AUDIT_PURGE_INTERVAL" flag:"audit-purge-interval"`
+
+	// DeletedRetention is how long deleted books, users and orders can be
+	// restored, 0 for ever. They are purged every DeletedPurgeInterval.
+	DeletedRetention     Duration `yaml:"deleted_retention" toml:"deleted_retention" env:"DELETED_RETENTION" flag:"deleted-retention"`
+	DeletedPurgeInterval Duration `yaml:"deleted_purge_interval" toml:"deleted_purge_interval" env:"DELETED_PURGE_INTERVAL" flag:"deleted-purge-interval"`
+
+	// MailDriver is smtp, file (an .eml file per message in MailDir) or log.
+	MailDriver   string `yaml:"mail_driver" toml:"mail_driver" env:"MAIL_DRIVER" flag:"mail-driver"`
+	MailFrom     string `yaml:"mail_from" toml:"mail_from" env:"MAIL_FROM" flag:"mail-from"`
+	MailDir      string `yaml:"mail_dir" toml:"mail_dir" env:"MAIL_DIR" flag:"mail-dir"`
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host" env:"SMTP_HOST" flag:"smtp-host"`
+	SMTPPort     int    `yaml:"smtp_port" toml:"smtp_port" env:"SMTP_PORT" flag:"smtp-port"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username" env:"SMTP_USERNAME" flag:"smtp-username"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password" env:"SMTP_PASSWORD" flag:"smtp-password" secret:"true"`
 }
 
-func Load() Config {
+// Default returns the configuration used when nothing overrides it.
+// Credentials have no defaults and must be provided.
+func Default() Config {
 
 	var cfg Config
 
 	cfg.HTTPPort = ":4000"
+	cfg.HTTPRequestTimeout = Duration(10 * time.Second)
+	cfg.HTTPReadTimeout = Duration(15 * time.Second)
+	cfg.HTTPReadHeaderTimeout = Duration(5 * time.Second)
+	cfg.HTTPWriteTimeout = Duration(30 * time.Second)
+	cfg.HTTPIdleTimeout = Duration(60 * time.Second)
+	cfg.HTTPShutdownTimeout = Duration(20 * time.Second)
+
+	cfg.LegacyRoutesSunset = Date(time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC))
+
+	cfg.IdempotencyTTL = Duration(24 * time.Hour)
+
+	cfg.RateLimits = RateLimits{
+		RateLimitDefault:           {Limit: 600, Window: time.Minute, By: RateLimitByUser},
+		"POST /login":              {Limit: 10, Window: time.Minute, By: RateLimitByIP},
+		"POST /loginsuper":         {Limit: 5, Window: time.Minute, By: RateLimitByIP},
+		"POST /login/mfa":          {Limit: 10, Window: time.Minute, By: RateLimitByIP},
+		"GET /login/oidc":          {Limit: 10, Window: time.Minute, By: RateLimitByIP},
+		"GET /login/oidc/callback": {Limit: 10, Window: time.Minute, By: RateLimitByIP},
+		"POST /register":           {Limit: 5, Window: time.Minute, By: RateLimitByIP},
+		"POST /password/forgot":    {Limit: 5, Window: time.Minute, By: RateLimitByIP},
+	}
+
+	cfg.LoginLockoutThreshold = 5
+	cfg.LoginLockoutBase = Duration(time.Minute)
+	cfg.LoginLockoutMax = Duration(time.Hour)
+	cfg.LoginFailureWindow = Duration(24 * time.Hour)
+
+	cfg.MFATokenTTL = Duration(5 * time.Minute)
+	cfg.LoginSuperRequireMFA = true
+	cfg.TOTPIssuer = "book_api"
+
+	cfg.OIDCRedirectURL = "http://localhost:4000/v1/login/oidc/callback"
+	cfg.OIDCScopes = "email profile"
+	cfg.OIDCStateTTL = Duration(10 * time.Minute)
+
+	cfg.LogLevel = "info"
+	cfg.LogFormat = "json"
+
+	cfg.TracingExporter = "none"
+	cfg.TracingEndpoint = "localhost:4318"
+	cfg.TracingServiceName = "book_api"
+
+	cfg.StorageDriver = StorageDriverPostgres
 
 	cfg.PostgresHost = "localhost"
-	cfg.PostgresUser = "samandar"
+	cfg.PostgresUser = "postgres"
 	cfg.PostgresDatabase = "book"
-	cfg.PostgresPassword = "samandevop"
 	cfg.PostgresPort = "5432"
 	cfg.PostgresMaxConnections = 20
+	cfg.PostgresStatementTimeout = Duration(5 * time.Second)
+
+	cfg.SQLitePath = "book.db"
 
 	cfg.RedisAddr = "localhost:6379"
-	cfg.RedisPassword = ""
 	cfg.RedisDB = 0
 
-	cfg.AuthSecretKey = "9K+WgNTglA44Hg=="
-	cfg.SuperAdmin = "Super"
-	cfg.Client = "Client"
+	cfg.JWTKeysDir = "keys"
+	cfg.JWTAlgorithm = "EdDSA"
+	cfg.JWTKeyRotation = Duration(30 * 24 * time.Hour)
+	cfg.JWTKeyReload = Duration(time.Minute)
+	cfg.JWTIssuer = "book_api"
+	cfg.JWTAudience = "book_api"
+
+	cfg.PublicURL = "http://localhost:4000"
+	cfg.EmailVerificationTTL = Duration(24 * time.Hour)
+	cfg.PasswordResetTTL = Duration(time.Hour)
+
+	cfg.AuditRetention = Duration(90 * 24 * time.Hour)
+	cfg.AuditPurgeInterval = Duration(time.Hour)
+
+	cfg.DeletedRetention = Duration(30 * 24 * time.Hour)
+	cfg.DeletedPurgeInterval = Duration(time.Hour)
+
+	cfg.MailDriver = MailDriverLog
+	cfg.MailFrom = "book_api <no-reply@localhost>"
+	cfg.MailDir = "mail"
+	cfg.SMTPPort = 587
 
 	return cfg
 }
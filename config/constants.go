@@ -6,3 +6,14 @@ const (
 	TimeExpiredAt      = time.Hour * 24
 	SuperTimeExpiredAt = time.Minute * 2
 )
+
+const (
+	StorageDriverPostgres = "postgres"
+	StorageDriverSQLite   = "sqlite"
+)
+
+const (
+	MailDriverSMTP = "smtp"
+	MailDriverFile = "file"
+	MailDriverLog  = "log"
+)
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Date is a calendar day written as "2027-04-19", in UTC. An empty value
+// means no date.
+type Date time.Time
+
+func (d Date) Time() time.Time {
+	return time.Time(d)
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+
+	if d.Time().IsZero() {
+		return []byte{}, nil
+	}
+
+	return []byte(d.Time().Format(dateLayout)), nil
+}
+
+func (d *Date) UnmarshalText(text []byte) error {
+
+	if len(text) == 0 {
+		*d = Date{}
+		return nil
+	}
+
+	t, err := time.Parse(dateLayout, string(text))
+	if err != nil {
+		return fmt.Errorf("%q is not a valid date, use YYYY-MM-DD", text)
+	}
+
+	*d = Date(t)
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration written as "5s" or "1m30s" in config files,
+// env variables and flags.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("%q is not a valid duration", text)
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+// RouteTimeouts overrides the request timeout of single routes. Keys are the
+// method and the route template without the version prefix, e.g.
+// "GET /book/:id", which covers /v1 and /v2 alike. In env variables and
+// flags it is written as "GET /book/:id=2s,POST /order=5s".
+type RouteTimeouts map[string]Duration
+
+func (r RouteTimeouts) MarshalText() ([]byte, error) {
+
+	var pairs []string
+	for route, timeout := range r {
+		pairs = append(pairs, route+"="+time.Duration(timeout).String())
+	}
+	sort.Strings(pairs)
+
+	return []byte(strings.Join(pairs, ",")), nil
+}
+
+func (r *RouteTimeouts) UnmarshalText(text []byte) error {
+
+	routes := RouteTimeouts{}
+
+	for _, pair := range strings.Split(string(text), ",") {
+
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		i := strings.LastIndex(pair, "=")
+		if i < 0 {
+			return fmt.Errorf("%q must look like \"GET /book/:id=2s\"", pair)
+		}
+
+		var timeout Duration
+		err := timeout.UnmarshalText([]byte(pair[i+1:]))
+		if err != nil {
+			return err
+		}
+
+		routes[strings.TrimSpace(pair[:i])] = timeout
+	}
+
+	*r = routes
+
+	return nil
+}
+
+// Timeout returns the timeout of the route template path, falling back to
+// HTTPRequestTimeout. Zero means no timeout.
+func (cfg *Config) Timeout(method, path string) time.Duration {
+
+	if timeout, ok := cfg.HTTPRouteTimeouts[method+" "+path]; ok {
+		return time.Duration(timeout)
+	}
+
+	return time.Duration(cfg.HTTPRequestTimeout)
+}
+
+// LongestTimeout returns the longest timeout of any route, or zero when some
+// route has no timeout.
+func (cfg *Config) LongestTimeout() time.Duration {
+
+	longest := time.Duration(cfg.HTTPRequestTimeout)
+	if longest <= 0 {
+		return 0
+	}
+
+	for _, timeout := range cfg.HTTPRouteTimeouts {
+		if timeout <= 0 {
+			return 0
+		}
+		longest = max(longest, time.Duration(timeout))
+	}
+
+	return longest
+}
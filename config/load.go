@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Load builds the configuration from args (usually os.Args[1:]) and the
+// environment. It returns the arguments left after the flags, i.e. the
+// subcommand. Load does not validate the result, call Validate for that.
+func Load(args []string) (Config, []string, error) {
+
+	var (
+		cfg      = Default()
+		file     string
+		flagVals = map[string]string{}
+		fs       = flag.NewFlagSet("book_api", flag.ContinueOnError)
+	)
+
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&file, "config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+
+	for _, f := range fields(&cfg) {
+		name := f.flag
+		fs.Func(name, "overrides "+f.env, func(value string) error {
+			flagVals[name] = value
+			return nil
+		})
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return cfg, nil, err
+	}
+
+	if file != "" {
+		err = loadFile(&cfg, file)
+		if err != nil {
+			return cfg, nil, err
+		}
+	}
+
+	var problems []string
+
+	for _, f := range fields(&cfg) {
+
+		value, ok, err := lookupEnv(f.env)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		if flagValue, set := flagVals[f.flag]; set {
+			value, ok = flagValue, true
+		}
+
+		if !ok {
+			continue
+		}
+
+		err = f.set(value)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return cfg, fs.Args(), &ValidationError{Problems: problems}
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+func loadFile(cfg *Config, file string) error {
+
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(body, cfg)
+	case ".toml":
+		decoder := toml.NewDecoder(strings.NewReader(string(body)))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml or .toml", file)
+	}
+
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// lookupEnv reads name, or the file named by name_FILE.
+func lookupEnv(name string) (string, bool, error) {
+
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true, nil
+	}
+
+	path, ok := os.LookupEnv(name + "_FILE")
+	if !ok {
+		return "", false, nil
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+	}
+
+	return strings.TrimRight(string(body), "\r\n"), true, nil
+}
+
+type field struct {
+	env    string
+	flag   string
+	secret bool
+	value  reflect.Value
+}
+
+func fields(cfg *Config) []field {
+
+	var (
+		resp []field
+		v    = reflect.ValueOf(cfg).Elem()
+		t    = v.Type()
+	)
+
+	for i := 0; i < t.NumField(); i++ {
+
+		tag := t.Field(i).Tag
+
+		resp = append(resp, field{
+			env:    tag.Get("env"),
+			flag:   tag.Get("flag"),
+			secret: tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+
+	return resp
+}
+
+func (f field) set(value string) error {
+
+	if u, ok := f.value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		err := u.UnmarshalText([]byte(value))
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.env, err)
+		}
+		return nil
+	}
+
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(value)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, f.value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a valid integer", f.env, value)
+		}
+		f.value.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a valid boolean", f.env, value)
+		}
+		f.value.SetBool(b)
+	default:
+		return errors.New(f.env + ": unsupported config type " + f.value.Type().String())
+	}
+
+	return nil
+}
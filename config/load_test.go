@@ -0,0 +1,207 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFile stores body as name in a new temporary directory.
+func writeFile(t *testing.T, name, body string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+
+	err := os.WriteFile(file, []byte(body), 0o600)
+	if err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+
+	return file
+}
+
+func TestLoadLayers(t *testing.T) {
+
+	yamlFile := writeFile(t, "config.yaml", "http_port: \":5000\"\nlog_level: debug\nredis_db: 2\n")
+	tomlFile := writeFile(t, "config.toml", "http_port = \":5000\"\nlog_level = \"debug\"\nredis_db = 2\n")
+	secret := writeFile(t, "pg", "from-file\n")
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		// check inspects the loaded configuration.
+		check func(t *testing.T, cfg Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg Config) {
+				if cfg.HTTPPort != ":4000" || cfg.LogLevel != "info" || cfg.RedisDB != 0 {
+					t.Errorf("HTTPPort, LogLevel, RedisDB = %q, %q, %d, want the defaults", cfg.HTTPPort, cfg.LogLevel, cfg.RedisDB)
+				}
+			},
+		},
+		{
+			name: "yaml file over defaults",
+			args: []string{"-config", yamlFile},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.HTTPPort != ":5000" || cfg.LogLevel != "debug" || cfg.RedisDB != 2 {
+					t.Errorf("HTTPPort, LogLevel, RedisDB = %q, %q, %d, want the file", cfg.HTTPPort, cfg.LogLevel, cfg.RedisDB)
+				}
+				if cfg.LogFormat != "json" {
+					t.Errorf("LogFormat = %q, want the default kept", cfg.LogFormat)
+				}
+			},
+		},
+		{
+			name: "toml file from CONFIG_FILE",
+			env:  map[string]string{"CONFIG_FILE": tomlFile},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.HTTPPort != ":5000" || cfg.LogLevel != "debug" || cfg.RedisDB != 2 {
+					t.Errorf("HTTPPort, LogLevel, RedisDB = %q, %q, %d, want the file", cfg.HTTPPort, cfg.LogLevel, cfg.RedisDB)
+				}
+			},
+		},
+		{
+			name: "env over file",
+			args: []string{"-config", yamlFile},
+			env:  map[string]string{"HTTP_PORT": ":6000"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.HTTPPort != ":6000" || cfg.LogLevel != "debug" {
+					t.Errorf("HTTPPort, LogLevel = %q, %q, want the env and the file", cfg.HTTPPort, cfg.LogLevel)
+				}
+			},
+		},
+		{
+			name: "flag over env",
+			args: []string{"-config", yamlFile, "-http-port", ":7000"},
+			env:  map[string]string{"HTTP_PORT": ":6000"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.HTTPPort != ":7000" {
+					t.Errorf("HTTPPort = %q, want the flag", cfg.HTTPPort)
+				}
+			},
+		},
+		{
+			name: "secret from a _FILE variable",
+			env:  map[string]string{"POSTGRES_PASSWORD_FILE": secret},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.PostgresPassword != "from-file" {
+					t.Errorf("PostgresPassword = %q, want the file without the newline", cfg.PostgresPassword)
+				}
+			},
+		},
+		{
+			name: "variable over its _FILE",
+			env:  map[string]string{"POSTGRES_PASSWORD": "from-env", "POSTGRES_PASSWORD_FILE": secret},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.PostgresPassword != "from-env" {
+					t.Errorf("PostgresPassword = %q, want the variable", cfg.PostgresPassword)
+				}
+			},
+		},
+		{
+			name: "typed values",
+			env: map[string]string{
+				"HTTP_REQUEST_TIMEOUT":    "3s",
+				"HTTP_ROUTE_TIMEOUTS":     "GET /book/:id=2s, POST /order=1m",
+				"LOGIN_SUPER_REQUIRE_MFA": "false",
+				"LEGACY_ROUTES_SUNSET":    "",
+				"RATE_LIMITS":             "POST /login=3/1m,*=100/1m by user",
+			},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.HTTPRequestTimeout != Duration(3*time.Second) {
+					t.Errorf("HTTPRequestTimeout = %v, want 3s", time.Duration(cfg.HTTPRequestTimeout))
+				}
+				if len(cfg.HTTPRouteTimeouts) != 2 || cfg.HTTPRouteTimeouts["POST /order"] != Duration(time.Minute) {
+					t.Errorf("HTTPRouteTimeouts = %v", cfg.HTTPRouteTimeouts)
+				}
+				if cfg.LoginSuperRequireMFA {
+					t.Errorf("LoginSuperRequireMFA = true, want false")
+				}
+				if !cfg.LegacyRoutesSunset.Time().IsZero() {
+					t.Errorf("LegacyRoutesSunset = %v, want no date", cfg.LegacyRoutesSunset.Time())
+				}
+				want := RateLimit{Limit: 3, Window: time.Minute, By: RateLimitByIP}
+				if len(cfg.RateLimits) != 2 || cfg.RateLimits["POST /login"] != want {
+					t.Errorf("RateLimits = %v", cfg.RateLimits)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			t.Setenv("CONFIG_FILE", "")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, rest, err := Load(append(tt.args, "serve"))
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if len(rest) != 1 || rest[0] != "serve" {
+				t.Errorf("Load args = %v, want [serve]", rest)
+			}
+
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		// problems is the number of problems reported, 0 for an error that
+		// is not a ValidationError.
+		problems int
+	}{
+		{name: "unknown flag", args: []string{"-no-such-flag", "1"}},
+		{name: "missing file", args: []string{"-config", filepath.Join(t.TempDir(), "none.yaml")}},
+		{name: "unsupported format", args: []string{"-config", writeFile(t, "config.json", "{}")}},
+		{name: "unknown yaml key", args: []string{"-config", writeFile(t, "config.yaml", "no_such_key: 1\n")}},
+		{name: "unknown toml key", args: []string{"-config", writeFile(t, "config.toml", "no_such_key = 1\n")}},
+		{name: "bad integer", env: map[string]string{"REDIS_DB": "two"}, problems: 1},
+		{name: "bad boolean", env: map[string]string{"TRACING_INSECURE": "maybe"}, problems: 1},
+		{name: "bad duration", env: map[string]string{"MFA_TOKEN_TTL": "5"}, problems: 1},
+		{name: "bad route timeout", env: map[string]string{"HTTP_ROUTE_TIMEOUTS": "GET /book/:id"}, problems: 1},
+		{name: "bad rate limit", env: map[string]string{"RATE_LIMITS": "POST /login=ten/1m"}, problems: 1},
+		{name: "bad date", env: map[string]string{"LEGACY_ROUTES_SUNSET": "19.04.2027"}, problems: 1},
+		{name: "missing _FILE", env: map[string]string{"SMTP_PASSWORD_FILE": filepath.Join(t.TempDir(), "none")}, problems: 1},
+		{name: "every bad value", env: map[string]string{"REDIS_DB": "two", "MFA_TOKEN_TTL": "5"}, args: []string{"-smtp-port", "x"}, problems: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			t.Setenv("CONFIG_FILE", "")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, _, err := Load(tt.args)
+			if err == nil {
+				t.Fatalf("Load succeeded, want an error")
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				if tt.problems != 0 {
+					t.Errorf("Load = %v, want %d problems", err, tt.problems)
+				}
+				return
+			}
+
+			if len(verr.Problems) != tt.problems {
+				t.Errorf("Load problems = %q, want %d", verr.Problems, tt.problems)
+			}
+		})
+	}
+}
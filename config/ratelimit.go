@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Principals a rate limit can count requests by. ByUser falls back to the
+// client IP for anonymous requests, ByAPIKey to the user and then the IP.
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByUser   = "user"
+	RateLimitByAPIKey = "api_key"
+)
+
+// RateLimitDefault is the RateLimits key of the limit applied to routes
+// without their own.
+const RateLimitDefault = "*"
+
+// RateLimit allows Limit requests per sliding Window for every principal.
+// It is written as "10/1m" or "10/1m by user"; the principal defaults to ip.
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+	By     string
+}
+
+func (r RateLimit) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d/%s by %s", r.Limit, r.Window, r.By)), nil
+}
+
+func (r *RateLimit) UnmarshalText(text []byte) error {
+
+	var (
+		rule   = strings.TrimSpace(string(text))
+		parsed = RateLimit{By: RateLimitByIP}
+	)
+
+	if i := strings.Index(rule, " by "); i >= 0 {
+		parsed.By = strings.TrimSpace(rule[i+len(" by "):])
+		rule = strings.TrimSpace(rule[:i])
+	}
+
+	limit, window, ok := strings.Cut(rule, "/")
+	if !ok {
+		return fmt.Errorf("%q must look like \"10/1m by ip\"", text)
+	}
+
+	var err error
+
+	parsed.Limit, err = strconv.Atoi(limit)
+	if err != nil {
+		return fmt.Errorf("%q: limit %q is not a number", text, limit)
+	}
+
+	parsed.Window, err = time.ParseDuration(window)
+	if err != nil {
+		return fmt.Errorf("%q: %v", text, err)
+	}
+
+	*r = parsed
+
+	return nil
+}
+
+// RateLimits maps the method and route template without the version prefix,
+// e.g. "POST /login", to its limit. RateLimitDefault ("*") applies to the
+// other routes. In env variables and flags it is written as
+// "POST /login=10/1m by ip,*=600/1m by user".
+type RateLimits map[string]RateLimit
+
+func (r RateLimits) MarshalText() ([]byte, error) {
+
+	var pairs []string
+	for route, limit := range r {
+		text, _ := limit.MarshalText()
+		pairs = append(pairs, route+"="+string(text))
+	}
+	sort.Strings(pairs)
+
+	return []byte(strings.Join(pairs, ",")), nil
+}
+
+func (r *RateLimits) UnmarshalText(text []byte) error {
+
+	limits := RateLimits{}
+
+	for _, pair := range strings.Split(string(text), ",") {
+
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		route, rule, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("%q must look like \"POST /login=10/1m by ip\"", pair)
+		}
+
+		var limit RateLimit
+		err := limit.UnmarshalText([]byte(rule))
+		if err != nil {
+			return err
+		}
+
+		limits[strings.TrimSpace(route)] = limit
+	}
+
+	*r = limits
+
+	return nil
+}
+
+// UnmarshalYAML replaces the default limits instead of merging into them,
+// as the env and flag forms do.
+func (r *RateLimits) UnmarshalYAML(unmarshal func(interface{}) error) error {
+
+	var limits map[string]RateLimit
+
+	err := unmarshal(&limits)
+	if err != nil {
+		return err
+	}
+
+	*r = limits
+
+	return nil
+}
+
+// RateLimit returns the limit of the route template path, falling back to
+// the default one. ok is false when the route is not limited.
+func (cfg *Config) RateLimit(method, path string) (limit RateLimit, ok bool) {
+
+	if limit, ok = cfg.RateLimits[method+" "+path]; ok {
+		return limit, true
+	}
+
+	limit, ok = cfg.RateLimits[RateLimitDefault]
+
+	return limit, ok
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError lists every problem found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the whole configuration and reports all problems at once.
+func (cfg Config) Validate() error {
+
+	var problems []string
+
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	_, _, err := net.SplitHostPort(cfg.HTTPPort)
+	check(err == nil, "HTTP_PORT: %q must look like \":4000\" or \"host:4000\"", cfg.HTTPPort)
+
+	check(cfg.HTTPRequestTimeout >= 0, "HTTP_REQUEST_TIMEOUT must not be negative")
+	check(cfg.HTTPReadTimeout >= 0, "HTTP_READ_TIMEOUT must not be negative")
+	check(cfg.HTTPReadHeaderTimeout >= 0, "HTTP_READ_HEADER_TIMEOUT must not be negative")
+	check(cfg.HTTPWriteTimeout >= 0, "HTTP_WRITE_TIMEOUT must not be negative")
+	check(cfg.HTTPWriteTimeout == 0 || cfg.HTTPWriteTimeout > cfg.HTTPRequestTimeout,
+		"HTTP_WRITE_TIMEOUT must be longer than HTTP_REQUEST_TIMEOUT, or responses of slow requests are cut off")
+	check(cfg.HTTPIdleTimeout >= 0, "HTTP_IDLE_TIMEOUT must not be negative")
+	check(cfg.HTTPShutdownTimeout > 0, "HTTP_SHUTDOWN_TIMEOUT must be positive")
+	for _, proxy := range strings.Fields(cfg.TrustedProxies) {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "TRUSTED_PROXIES: %q is neither an IP nor a CIDR", proxy)
+	}
+	for route, timeout := range cfg.HTTPRouteTimeouts {
+		check(len(strings.Fields(route)) == 2, "HTTP_ROUTE_TIMEOUTS: %q must look like \"GET /book/:id\"", route)
+		check(timeout >= 0, "HTTP_ROUTE_TIMEOUTS: %q must not be negative", route)
+	}
+
+	check(cfg.IdempotencyTTL > 0, "IDEMPOTENCY_TTL must be positive")
+
+	for route, limit := range cfg.RateLimits {
+		check(route == RateLimitDefault || len(strings.Fields(route)) == 2, "RATE_LIMITS: %q must look like \"POST /login\" or be %q", route, RateLimitDefault)
+		check(limit.Limit > 0 && limit.Window > 0, "RATE_LIMITS: %q needs a positive limit and window", route)
+		switch limit.By {
+		case RateLimitByIP, RateLimitByUser, RateLimitByAPIKey:
+		default:
+			check(false, "RATE_LIMITS: %q must count by %s, %s or %s", route, RateLimitByIP, RateLimitByUser, RateLimitByAPIKey)
+		}
+	}
+
+	check(cfg.LoginLockoutThreshold > 0, "LOGIN_LOCKOUT_THRESHOLD must be positive")
+	check(cfg.LoginLockoutBase > 0, "LOGIN_LOCKOUT_BASE must be positive")
+	check(cfg.LoginLockoutMax >= cfg.LoginLockoutBase, "LOGIN_LOCKOUT_MAX must not be shorter than LOGIN_LOCKOUT_BASE")
+	check(cfg.LoginFailureWindow > 0, "LOGIN_FAILURE_WINDOW must be positive")
+
+	check(cfg.MFATokenTTL > 0, "MFA_TOKEN_TTL must be positive")
+	check(cfg.TOTPIssuer != "" && !strings.Contains(cfg.TOTPIssuer, ":"), "TOTP_ISSUER: %q must be non-empty and must not contain a colon", cfg.TOTPIssuer)
+
+	if cfg.OIDCIssuer != "" {
+		issuer, err := url.Parse(cfg.OIDCIssuer)
+		check(err == nil && (issuer.Scheme == "http" || issuer.Scheme == "https") && issuer.Host != "", "OIDC_ISSUER: %q must be an absolute http(s) URL", cfg.OIDCIssuer)
+		check(cfg.OIDCClientID != "", "OIDC_CLIENT_ID is required with OIDC_ISSUER")
+		redirectURL, err := url.Parse(cfg.OIDCRedirectURL)
+		check(err == nil && (redirectURL.Scheme == "http" || redirectURL.Scheme == "https") && redirectURL.Host != "", "OIDC_REDIRECT_URL: %q must be an absolute http(s) URL", cfg.OIDCRedirectURL)
+		check(cfg.OIDCStateTTL > 0, "OIDC_STATE_TTL must be positive")
+	}
+
+	switch strings.ToLower(cfg.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		check(false, "LOG_LEVEL: %q must be debug, info, warn or error", cfg.LogLevel)
+	}
+	check(cfg.LogFormat == "json" || cfg.LogFormat == "text", "LOG_FORMAT: %q must be json or text", cfg.LogFormat)
+
+	switch cfg.TracingExporter {
+	case "none", "stdout":
+	case "otlp":
+		check(cfg.TracingEndpoint != "", "TRACING_ENDPOINT is required with the otlp exporter")
+	default:
+		check(false, "TRACING_EXPORTER: %q must be none, stdout or otlp", cfg.TracingExporter)
+	}
+	check(cfg.TracingServiceName != "", "TRACING_SERVICE_NAME is required")
+
+	switch cfg.StorageDriver {
+	case StorageDriverPostgres:
+		check(cfg.PostgresHost != "", "POSTGRES_HOST is required")
+		check(cfg.PostgresUser != "", "POSTGRES_USER is required")
+		check(cfg.PostgresPassword != "", "POSTGRES_PASSWORD is required")
+		check(cfg.PostgresDatabase != "", "POSTGRES_DATABASE is required")
+		_, err = strconv.ParseUint(cfg.PostgresPort, 10, 16)
+		check(err == nil, "POSTGRES_PORT: %q is not a valid port", cfg.PostgresPort)
+		check(cfg.PostgresMaxConnections > 0, "POSTGRES_MAX_CONNECTIONS must be positive")
+		check(cfg.PostgresStatementTimeout >= 0, "POSTGRES_STATEMENT_TIMEOUT must not be negative")
+	case StorageDriverSQLite:
+		check(cfg.SQLitePath != "", "SQLITE_PATH is required")
+	default:
+		check(false, "STORAGE_DRIVER: %q must be %q or %q", cfg.StorageDriver, StorageDriverPostgres, StorageDriverSQLite)
+	}
+
+	check(cfg.RedisAddr != "", "REDIS_ADDR is required")
+	check(cfg.RedisDB >= 0, "REDIS_DB must not be negative")
+
+	check(cfg.JWTKeysDir != "", "JWT_KEYS_DIR is required")
+	check(cfg.JWTAlgorithm == "RS256" || cfg.JWTAlgorithm == "EdDSA", "JWT_ALGORITHM: %q must be RS256 or EdDSA", cfg.JWTAlgorithm)
+	check(cfg.JWTKeyRotation == 0 || time.Duration(cfg.JWTKeyRotation) >= time.Hour, "JWT_KEY_ROTATION must be 0 or at least 1h")
+	check(cfg.JWTKeyReload > 0, "JWT_KEY_RELOAD must be positive")
+	check(cfg.JWTIssuer != "", "JWT_ISSUER is required")
+	check(cfg.JWTAudience != "", "JWT_AUDIENCE is required")
+
+	publicURL, err := url.Parse(cfg.PublicURL)
+	check(err == nil && (publicURL.Scheme == "http" || publicURL.Scheme == "https") && publicURL.Host != "", "PUBLIC_URL: %q must be an absolute http(s) URL", cfg.PublicURL)
+	check(cfg.EmailVerificationTTL > 0, "EMAIL_VERIFICATION_TTL must be positive")
+	check(cfg.PasswordResetTTL > 0, "PASSWORD_RESET_TTL must be positive")
+
+	check(cfg.AuditRetention >= 0, "AUDIT_RETENTION must not be negative")
+	check(cfg.AuditPurgeInterval > 0, "AUDIT_PURGE_INTERVAL must be positive")
+
+	check(cfg.DeletedRetention >= 0, "DELETED_RETENTION must not be negative")
+	check(cfg.DeletedPurgeInterval > 0, "DELETED_PURGE_INTERVAL must be positive")
+
+	_, err = mail.ParseAddress(cfg.MailFrom)
+	check(err == nil, "MAIL_FROM: %q is not an email address", cfg.MailFrom)
+
+	switch cfg.MailDriver {
+	case MailDriverSMTP:
+		check(cfg.SMTPHost != "", "SMTP_HOST is required")
+		check(cfg.SMTPPort > 0 && cfg.SMTPPort <= 65535, "SMTP_PORT: %d is not a valid port", cfg.SMTPPort)
+	case MailDriverFile:
+		check(cfg.MailDir != "", "MAIL_DIR is required")
+	case MailDriverLog:
+	default:
+		check(false, "MAIL_DRIVER: %q must be %q, %q or %q", cfg.MailDriver, MailDriverSMTP, MailDriverFile, MailDriverLog)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// Redacted returns a copy of cfg with every secret replaced by a placeholder.
+func (cfg Config) Redacted() Config {
+
+	for _, f := range fields(&cfg) {
+		if f.secret && f.value.String() != "" {
+			f.value.SetString("******")
+		}
+	}
+
+	return cfg
+}
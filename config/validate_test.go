@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns the defaults with the credentials they lack.
+func validConfig() Config {
+
+	cfg := Default()
+	cfg.PostgresPassword = "secret"
+
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		// want is part of the single problem reported, empty for none.
+		want string
+	}{
+		{name: "defaults", modify: func(cfg *Config) {}},
+		{name: "sqlite needs no credentials", modify: func(cfg *Config) { cfg.StorageDriver = StorageDriverSQLite; cfg.PostgresPassword = "" }},
+		{name: "trusted proxies", modify: func(cfg *Config) { cfg.TrustedProxies = "10.0.0.0/8 192.168.1.1 ::1" }},
+		{name: "bad port", modify: func(cfg *Config) { cfg.HTTPPort = "4000" }, want: "HTTP_PORT"},
+		{name: "write timeout shorter than the request timeout", modify: func(cfg *Config) { cfg.HTTPWriteTimeout = Duration(5 * time.Second) }, want: "HTTP_WRITE_TIMEOUT"},
+		{name: "bad trusted proxy", modify: func(cfg *Config) { cfg.TrustedProxies = "10.0.0.0/8 proxy.local" }, want: "TRUSTED_PROXIES"},
+		{name: "bad route", modify: func(cfg *Config) { cfg.HTTPRouteTimeouts = RouteTimeouts{"/book": Duration(time.Second)} }, want: "HTTP_ROUTE_TIMEOUTS"},
+		{name: "negative route timeout", modify: func(cfg *Config) { cfg.HTTPRouteTimeouts = RouteTimeouts{"GET /book": Duration(-time.Second)} }, want: "HTTP_ROUTE_TIMEOUTS"},
+		{name: "unknown rate limit principal", modify: func(cfg *Config) { cfg.RateLimits = RateLimits{"*": {Limit: 1, Window: time.Second, By: "host"}} }, want: "RATE_LIMITS"},
+		{name: "zero rate limit", modify: func(cfg *Config) { cfg.RateLimits = RateLimits{"*": {Window: time.Second, By: RateLimitByIP}} }, want: "RATE_LIMITS"},
+		{name: "lockout max shorter than base", modify: func(cfg *Config) { cfg.LoginLockoutMax = Duration(time.Second) }, want: "LOGIN_LOCKOUT_MAX"},
+		{name: "colon in TOTP issuer", modify: func(cfg *Config) { cfg.TOTPIssuer = "book:api" }, want: "TOTP_ISSUER"},
+		{name: "OIDC without a client", modify: func(cfg *Config) { cfg.OIDCIssuer = "https://accounts.example.com" }, want: "OIDC_CLIENT_ID"},
+		{name: "relative OIDC issuer", modify: func(cfg *Config) { cfg.OIDCIssuer = "accounts.example.com"; cfg.OIDCClientID = "book" }, want: "OIDC_ISSUER"},
+		{name: "unknown log level", modify: func(cfg *Config) { cfg.LogLevel = "trace" }, want: "LOG_LEVEL"},
+		{name: "otlp without an endpoint", modify: func(cfg *Config) { cfg.TracingExporter = "otlp"; cfg.TracingEndpoint = "" }, want: "TRACING_ENDPOINT"},
+		{name: "postgres without a password", modify: func(cfg *Config) { cfg.PostgresPassword = "" }, want: "POSTGRES_PASSWORD"},
+		{name: "unknown storage driver", modify: func(cfg *Config) { cfg.StorageDriver = "mysql" }, want: "STORAGE_DRIVER"},
+		{name: "short key rotation", modify: func(cfg *Config) { cfg.JWTKeyRotation = Duration(time.Minute) }, want: "JWT_KEY_ROTATION"},
+		{name: "unknown JWT algorithm", modify: func(cfg *Config) { cfg.JWTAlgorithm = "HS256" }, want: "JWT_ALGORITHM"},
+		{name: "relative public URL", modify: func(cfg *Config) { cfg.PublicURL = "/app" }, want: "PUBLIC_URL"},
+		{name: "bad sender", modify: func(cfg *Config) { cfg.MailFrom = "book_api" }, want: "MAIL_FROM"},
+		{name: "smtp without a host", modify: func(cfg *Config) { cfg.MailDriver = MailDriverSMTP }, want: "SMTP_HOST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			cfg := validConfig()
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate = %v, want a ValidationError", err)
+			}
+			if len(verr.Problems) != 1 || !strings.Contains(verr.Problems[0], tt.want) {
+				t.Errorf("Validate problems = %q, want one about %s", verr.Problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+
+	cfg := validConfig()
+	cfg.HTTPPort = ""
+	cfg.LogFormat = "xml"
+	cfg.RedisAddr = ""
+
+	var verr *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &verr) || len(verr.Problems) != 3 {
+		t.Errorf("Validate = %v, want 3 problems", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+
+	cfg := validConfig()
+	cfg.SMTPPassword = "smtp"
+
+	redacted := cfg.Redacted()
+
+	if redacted.PostgresPassword != "******" || redacted.SMTPPassword != "******" {
+		t.Errorf("Redacted passwords = %q, %q", redacted.PostgresPassword, redacted.SMTPPassword)
+	}
+	if redacted.RedisPassword != "" {
+		t.Errorf("Redacted RedisPassword = %q, want empty secrets left empty", redacted.RedisPassword)
+	}
+	if redacted.PostgresUser != cfg.PostgresUser || cfg.PostgresPassword != "secret" {
+		t.Errorf("Redacted changed a setting that is not a secret, or the original")
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		request time.Duration
+		routes  RouteTimeouts
+		// want is the timeout of GET /book/:id.
+		want        time.Duration
+		wantLongest time.Duration
+	}{
+		{name: "request timeout", request: 10 * time.Second, want: 10 * time.Second, wantLongest: 10 * time.Second},
+		{name: "shorter route", request: 10 * time.Second, routes: RouteTimeouts{"GET /book/:id": Duration(time.Second)}, want: time.Second, wantLongest: 10 * time.Second},
+		{name: "longer route", request: 10 * time.Second, routes: RouteTimeouts{"POST /order": Duration(time.Minute)}, want: 10 * time.Second, wantLongest: time.Minute},
+		{name: "route without a timeout", request: 10 * time.Second, routes: RouteTimeouts{"GET /book/:id": 0}, want: 0, wantLongest: 0},
+		{name: "no request timeout", routes: RouteTimeouts{"POST /order": Duration(time.Minute)}, want: 0, wantLongest: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			cfg := Config{HTTPRequestTimeout: Duration(tt.request), HTTPRouteTimeouts: tt.routes}
+
+			if got := cfg.Timeout("GET", "/book/:id"); got != tt.want {
+				t.Errorf("Timeout = %v, want %v", got, tt.want)
+			}
+			if got := cfg.LongestTimeout(); got != tt.wantLongest {
+				t.Errorf("LongestTimeout = %v, want %v", got, tt.wantLongest)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+
+	tests := []struct {
+		text    string
+		want    RateLimit
+		wantErr bool
+	}{
+		{text: "10/1m", want: RateLimit{Limit: 10, Window: time.Minute, By: RateLimitByIP}},
+		{text: " 5/30s by user ", want: RateLimit{Limit: 5, Window: 30 * time.Second, By: RateLimitByUser}},
+		{text: "100/1h by api_key", want: RateLimit{Limit: 100, Window: time.Hour, By: RateLimitByAPIKey}},
+		{text: "10", wantErr: true},
+		{text: "ten/1m", wantErr: true},
+		{text: "10/minute", wantErr: true},
+	}
+
+	for _, tt := range tests {
+
+		var got RateLimit
+		err := got.UnmarshalText([]byte(tt.text))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("UnmarshalText(%q) = %+v, want an error", tt.text, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("UnmarshalText(%q) = %+v, %v, want %+v", tt.text, got, err, tt.want)
+		}
+	}
+
+	cfg := Config{RateLimits: RateLimits{
+		RateLimitDefault: {Limit: 600, Window: time.Minute, By: RateLimitByUser},
+		"POST /login":    {Limit: 10, Window: time.Minute, By: RateLimitByIP},
+	}}
+
+	if limit, ok := cfg.RateLimit("POST", "/login"); !ok || limit.Limit != 10 {
+		t.Errorf("RateLimit(POST /login) = %+v, %v, want its own limit", limit, ok)
+	}
+	if limit, ok := cfg.RateLimit("GET", "/book"); !ok || limit.Limit != 600 {
+		t.Errorf("RateLimit(GET /book) = %+v, %v, want the default", limit, ok)
+	}
+
+	delete(cfg.RateLimits, RateLimitDefault)
+	if _, ok := cfg.RateLimit("GET", "/book"); ok {
+		t.Errorf("RateLimit(GET /book) without a default is limited")
+	}
+}
@@ -0,0 +1,23 @@
+// Package migrations embeds the SQL migrations of every storage backend so
+// the binary can apply them without the files being present on disk.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Postgres returns the migrations for storage/postgres.
+func Postgres() fs.FS {
+	sub, _ := fs.Sub(files, "postgres")
+	return sub
+}
+
+// SQLite returns the migrations for storage/sqlite.
+func SQLite() fs.FS {
+	sub, _ := fs.Sub(files, "sqlite")
+	return sub
+}
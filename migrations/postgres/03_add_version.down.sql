@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN version;
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE books DROP COLUMN version;
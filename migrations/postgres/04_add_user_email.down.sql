@@ -0,0 +1,3 @@
+DROP INDEX users_email_key;
+ALTER TABLE users DROP COLUMN email_verified_at;
+ALTER TABLE users DROP COLUMN email;
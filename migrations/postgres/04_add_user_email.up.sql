@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN email VARCHAR(254);
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+CREATE UNIQUE INDEX users_email_key ON users (email);
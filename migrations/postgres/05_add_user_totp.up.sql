@@ -0,0 +1,14 @@
+CREATE TABLE user_totp (
+    user_id UUID NOT NULL PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    secret VARCHAR NOT NULL,
+    enabled_at TIMESTAMP,
+    last_step BIGINT DEFAULT 0 NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE recovery_codes (
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    PRIMARY KEY (user_id, code_hash)
+);
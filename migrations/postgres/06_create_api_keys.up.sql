@@ -0,0 +1,11 @@
+CREATE TABLE api_keys (
+    api_key_id UUID NOT NULL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes VARCHAR NOT NULL,
+    created_by UUID REFERENCES users(user_id) ON DELETE SET NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
@@ -0,0 +1,10 @@
+CREATE TABLE user_identities (
+    issuer VARCHAR NOT NULL,
+    subject VARCHAR NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    email VARCHAR,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
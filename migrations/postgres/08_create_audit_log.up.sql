@@ -0,0 +1,17 @@
+CREATE TABLE audit_log (
+    audit_id UUID NOT NULL PRIMARY KEY,
+    actor_id UUID,
+    api_key_id UUID,
+    action VARCHAR(16) NOT NULL,
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id UUID NOT NULL,
+    before JSONB,
+    after JSONB,
+    ip VARCHAR,
+    request_id VARCHAR,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+CREATE INDEX audit_log_entity_idx ON audit_log (entity_type, entity_id);
+CREATE INDEX audit_log_actor_id_idx ON audit_log (actor_id);
@@ -0,0 +1,7 @@
+ALTER TABLE books ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX books_deleted_at_idx ON books (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX orders_deleted_at_idx ON orders (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,29 @@
+CREATE TABLE users (
+    user_id TEXT NOT NULL PRIMARY KEY,
+    first_name VARCHAR(45) NOT NULL,
+    last_name VARCHAR(45) NOT NULL,
+    login TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    phone_number VARCHAR(9) NOT NULL,
+    balance NUMERIC DEFAULT 0 NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE books (
+    book_id TEXT NOT NULL PRIMARY KEY,
+    title TEXT NOT NULL,
+    author VARCHAR(150),
+    price NUMERIC NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE orders (
+    order_id TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(user_id),
+    book_id TEXT NOT NULL REFERENCES books(book_id),
+    payed NUMERIC NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
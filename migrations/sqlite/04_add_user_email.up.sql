@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN email TEXT;
+ALTER TABLE users ADD COLUMN email_verified_at TEXT;
+CREATE UNIQUE INDEX users_email_key ON users (email);
@@ -0,0 +1,11 @@
+CREATE TABLE api_keys (
+    api_key_id TEXT NOT NULL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix TEXT NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_by TEXT REFERENCES users(user_id) ON DELETE SET NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
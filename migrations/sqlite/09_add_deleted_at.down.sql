@@ -0,0 +1,7 @@
+DROP INDEX orders_deleted_at_idx;
+DROP INDEX users_deleted_at_idx;
+DROP INDEX books_deleted_at_idx;
+
+ALTER TABLE orders DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE books DROP COLUMN deleted_at;
@@ -0,0 +1,24 @@
+package models
+
+type Register struct {
+	First_name string `json:"first_name" binding:"required,max=45" example:"Samandar"`
+	Last_name  string `json:"last_name" binding:"required,max=45" example:"Foziljonov"`
+	Login      string `json:"login" binding:"required,min=3,max=64" example:"samandar"`
+	Password   string `json:"password" binding:"required,min=6,max=72"`
+	// 9 digits without country code
+	Phone_number string `json:"phone_number" binding:"required,phone" example:"997191323"`
+	Email        string `json:"email" binding:"required,email,max=254" example:"samandar@example.com"`
+}
+
+type VerifyEmail struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPassword struct {
+	Email string `json:"email" binding:"required,email,max=254" example:"samandar@example.com"`
+}
+
+type ResetPassword struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6,max=72"`
+}
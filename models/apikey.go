@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+type APIKeyPrimarKey struct {
+	Id string `json:"api_key_id"`
+	// Prefix identifies the key when authenticating with it.
+	Prefix string `json:"prefix"`
+}
+
+type CreateAPIKeySwagger struct {
+	Name   string   `json:"name" binding:"required,max=100" example:"billing export"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=books:read books:write users:read users:write orders:read orders:write" example:"books:read"`
+	// ExpiresInDays is how long the key is valid; 0 keeps it valid until
+	// it is deleted.
+	ExpiresInDays int32 `json:"expires_in_days" binding:"gte=0,lte=3650" example:"90"`
+}
+
+type CreateAPIKey struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=books:read books:write users:read users:write orders:read orders:write"`
+	ExpiresInDays int32    `json:"expires_in_days" binding:"gte=0,lte=3650"`
+	// Set by the service.
+	Prefix     string        `json:"-"`
+	KeyHash    string        `json:"-"`
+	Created_by string        `json:"-"`
+	ExpiresIn  time.Duration `json:"-"`
+}
+
+type APIKey struct {
+	Id         string   `json:"api_key_id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	KeyHash    string   `json:"-"`
+	Scopes     []string `json:"scopes"`
+	Created_by string   `json:"created_by,omitempty"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	// Expired is true once ExpiresAt has passed.
+	Expired    bool   `json:"expired"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// CreateAPIKeyResponse carries the key itself, which is only shown once.
+type CreateAPIKeyResponse struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// TouchAPIKey records a use of the key, unless one was recorded within
+// Interval, so keys used on every request do not write on every request.
+type TouchAPIKey struct {
+	Id       string
+	Interval time.Duration
+}
+
+type GetListAPIKeyRequest struct {
+	Limit  int32
+	Offset int32
+}
+
+type GetListAPIKeyResponse struct {
+	Count   int32     `json:"count"`
+	APIKeys []*APIKey `json:"api_keys"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CreateAuditLog records one write. Before and After hold the fields it
+// changed; Before is null for a create and After for a delete.
+type CreateAuditLog struct {
+	Actor_id   string
+	APIKey_id  string
+	Action     string
+	EntityType string
+	Entity_id  string
+	Before     json.RawMessage
+	After      json.RawMessage
+	IP         string
+	RequestID  string
+}
+
+type AuditLog struct {
+	Id string `json:"audit_id"`
+	// Actor_id is the user the write was made by, empty when it was made
+	// anonymously or with an API key.
+	Actor_id   string          `json:"actor_id,omitempty"`
+	APIKey_id  string          `json:"api_key_id,omitempty"`
+	Action     string          `json:"action" example:"update"`
+	EntityType string          `json:"entity_type" example:"book"`
+	Entity_id  string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	IP         string          `json:"ip,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// AuditLogParams are the query parameters of GET /audit.
+type AuditLogParams struct {
+	ListParams
+	Actor_id   string    `form:"actor_id" binding:"omitempty,uuid"`
+	Action     string    `form:"action" binding:"omitempty,oneof=create update delete restore erase"`
+	EntityType string    `form:"entity_type" binding:"omitempty,oneof=book user order api_key"`
+	Entity_id  string    `form:"entity_id" binding:"omitempty,uuid"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// GetListAuditLogRequest filters the audit log by the non-empty fields.
+// From is inclusive and To exclusive.
+type GetListAuditLogRequest struct {
+	Limit      int32
+	Offset     int32
+	Actor_id   string
+	Action     string
+	EntityType string
+	Entity_id  string
+	From       time.Time
+	To         time.Time
+}
+
+// RedactAuditLog drops the values recorded for an entity and the IP of the
+// writes made by an actor, keeping the entries themselves.
+type RedactAuditLog struct {
+	EntityType string
+	Entity_id  string
+	Actor_id   string
+}
+
+type GetListAuditLogResponse struct {
+	Count     int32       `json:"count"`
+	AuditLogs []*AuditLog `json:"audit_logs"`
+}
+
+// BalanceChange is one entry of the balance history of a user, taken from
+// the audit log: its creation, an edit, or an order charging or refunding
+// it. Amount is After - Before.
+type BalanceChange struct {
+	Action    string  `json:"action"`
+	Before    float64 `json:"balance_before"`
+	After     float64 `json:"balance_after"`
+	Amount    float64 `json:"amount"`
+	Actor_id  string  `json:"actor_id,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+	CreatedAt string  `json:"created_at"`
+}
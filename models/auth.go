@@ -1,10 +1,17 @@
 package models
 
 type Login struct {
-	Login    string `json:"login"`
-	Password string `json:"password"`
+	Login    string `json:"login" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	// UserAgent and IP describe the device the session is opened from.
+	UserAgent string `json:"-"`
+	IP        string `json:"-"`
 }
 
 type LoginResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token,omitempty"`
+	// MFARequired is set instead of AccessToken when the user has to
+	// continue with POST /login/mfa, passing MFAToken and a code.
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
 }
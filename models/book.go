@@ -2,38 +2,74 @@ package models
 
 type BookPrimarKey struct {
 	Id string `json:"book_id"`
+	// Version, when set, restricts Delete to that version.
+	Version int64 `json:"-"`
+	// IncludeDeleted finds the book even if it is deleted.
+	IncludeDeleted bool `json:"-"`
 }
 
 type CreateBook struct {
-	Title  string  `json:"title"`
-	Author string  `json:"author"`
-	Price  float64 `json:"price"`
+	Title  string  `json:"title" binding:"required,max=255" example:"Norwegian Wood"`
+	Author string  `json:"author" binding:"max=150" example:"Haruki Murakami"`
+	Price  float64 `json:"price" binding:"gte=0" example:"1500"`
+	// ISBN-10 or ISBN-13, hyphens allowed
+	ISBN string `json:"isbn" binding:"omitempty,isbn" format:"isbn" example:"978-0-375-70402-4"`
 }
 type Book struct {
 	Id        string  `json:"book_id"`
 	Title     string  `json:"title"`
 	Author    string  `json:"author"`
 	Price     float64 `json:"price"`
+	ISBN      string  `json:"isbn"`
 	CreatedAt string  `json:"created_at"`
 	UpdatedAt string  `json:"updated_at"`
+	Version   int64   `json:"version"`
+	// DeletedAt is only set on deleted books, see include_deleted.
+	DeletedAt string `json:"deleted_at,omitempty"`
 }
 
 type UpdateBookSwagger struct {
-	Title  string  `json:"title"`
-	Author string  `json:"author"`
-	Price  float64 `json:"price"`
+	Title  string  `json:"title" binding:"required,max=255" example:"Norwegian Wood"`
+	Author string  `json:"author" binding:"max=150" example:"Haruki Murakami"`
+	Price  float64 `json:"price" binding:"gte=0" example:"1500"`
+	// ISBN-10 or ISBN-13, hyphens allowed
+	ISBN string `json:"isbn" binding:"omitempty,isbn" format:"isbn" example:"978-0-375-70402-4"`
 }
 
 type UpdateBook struct {
 	Id     string  `json:"book_id"`
-	Title  string  `json:"title"`
-	Author string  `json:"author"`
-	Price  float64 `json:"price"`
+	Title  string  `json:"title" binding:"required,max=255"`
+	Author string  `json:"author" binding:"max=150"`
+	Price  float64 `json:"price" binding:"gte=0"`
+	ISBN   string  `json:"isbn" binding:"omitempty,isbn"`
+	// Version, when set, is the version the write expects to replace; see
+	// If-Match.
+	Version int64 `json:"-"`
+}
+
+type PatchBookSwagger struct {
+	Title  string  `json:"title" binding:"omitempty,max=255" example:"Norwegian Wood"`
+	Author string  `json:"author" binding:"max=150" example:"Haruki Murakami"`
+	Price  float64 `json:"price" binding:"gte=0" example:"1500"`
+	// ISBN-10 or ISBN-13, hyphens allowed; null clears it
+	ISBN string `json:"isbn" binding:"omitempty,isbn" format:"isbn" example:"978-0-375-70402-4"`
+}
+
+// PatchBook holds the columns a partial update writes. Nil fields are left
+// unchanged.
+type PatchBook struct {
+	Id      string
+	Title   *string
+	Author  *string
+	Price   *float64
+	ISBN    *string
+	Version int64
 }
 
 type GetListBookRequest struct {
-	Limit  int32
-	Offset int32
+	Limit          int32
+	Offset         int32
+	IncludeDeleted bool
 }
 
 type GetListBookResponse struct {
@@ -0,0 +1,21 @@
+package models
+
+const (
+	HealthOK   = "ok"
+	HealthFail = "fail"
+)
+
+type Health struct {
+	Status string `json:"status" example:"ok"`
+}
+
+type HealthCheck struct {
+	Status  string `json:"status" example:"ok"`
+	Message string `json:"message,omitempty" example:"schema version 2 (latest 2)"`
+	Error   string `json:"error,omitempty"`
+}
+
+type Readiness struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
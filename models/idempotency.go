@@ -0,0 +1,11 @@
+package models
+
+// IdempotentResponse is the stored outcome of a request made with an
+// Idempotency-Key. Status is 0 while the first request is still running.
+type IdempotentResponse struct {
+	// Fingerprint identifies the request the key was first used with.
+	Fingerprint string            `json:"fingerprint"`
+	Status      int               `json:"status"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
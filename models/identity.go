@@ -0,0 +1,46 @@
+package models
+
+// IdentityPrimarKey is an account at an external identity provider.
+type IdentityPrimarKey struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+}
+
+// CreateIdentity links an account at an identity provider to a user.
+type CreateIdentity struct {
+	Issuer  string
+	Subject string
+	User_id string
+	// Email is the email the provider had for the account when it was
+	// linked.
+	Email string
+}
+
+type Identity struct {
+	Issuer    string `json:"issuer"`
+	Subject   string `json:"subject"`
+	User_id   string `json:"user_id"`
+	Email     string `json:"email,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type GetListIdentityRequest struct {
+	User_id string
+}
+
+type GetListIdentityResponse struct {
+	Count      int32       `json:"count"`
+	Identities []*Identity `json:"identities"`
+}
+
+// LoginOIDC is the callback of the identity provider, with either a code or
+// an error.
+type LoginOIDC struct {
+	Code             string `form:"code" binding:"required_without=Error"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+	// UserAgent and IP describe the device the session is opened from.
+	UserAgent string `form:"-"`
+	IP        string `form:"-"`
+}
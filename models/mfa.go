@@ -0,0 +1,63 @@
+package models
+
+type TOTPPrimarKey struct {
+	User_id string `json:"user_id"`
+}
+
+type CreateTOTP struct {
+	User_id string
+	Secret  string
+}
+
+type TOTP struct {
+	User_id string `json:"user_id"`
+	Secret  string `json:"-"`
+	// Enabled is false until enrollment is confirmed with a code.
+	Enabled bool `json:"enabled"`
+	// LastStep is the time step of the last accepted code.
+	LastStep          int64  `json:"-"`
+	RecoveryCodesLeft int32  `json:"recovery_codes_left"`
+	CreatedAt         string `json:"created_at,omitempty"`
+}
+
+// UseTOTPStep accepts a code of Step unless a code of the same or a later
+// step was accepted before.
+type UseTOTPStep struct {
+	User_id string
+	Step    int64
+}
+
+type UseRecoveryCode struct {
+	User_id  string
+	CodeHash string
+}
+
+type SetRecoveryCodes struct {
+	User_id    string
+	CodeHashes []string
+}
+
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	// URL is the otpauth:// URI authenticator apps enroll with; GET
+	// /mfa/totp/qr renders it as a QR code.
+	URL string `json:"otpauth_url"`
+}
+
+type TOTPCode struct {
+	Code string `json:"code" binding:"required,len=6,numeric" example:"123456"`
+}
+
+type RecoveryCodesResponse struct {
+	// RecoveryCodes are shown once; each can replace a code a single time.
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type LoginMFA struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric" example:"123456"`
+	RecoveryCode string `json:"recovery_code" binding:"omitempty,max=32"`
+	// UserAgent and IP describe the device the session is opened from.
+	UserAgent string `json:"-"`
+	IP        string `json:"-"`
+}
@@ -2,16 +2,20 @@ package models
 
 type OrderPrimarKey struct {
 	Id string `json:"order_id"`
+	// Version, when set, restricts Delete to that version.
+	Version int64 `json:"-"`
+	// IncludeDeleted finds the order even if it is deleted.
+	IncludeDeleted bool `json:"-"`
 }
 
 type CreateOrderSwagger struct {
-	User_id string `json:"user_id"`
-	Book_id string `json:"book_id"`
+	User_id string `json:"user_id" binding:"required,uuid" format:"uuid"`
+	Book_id string `json:"book_id" binding:"required,uuid" format:"uuid"`
 }
 
 type CreateOrder struct {
-	User_id string  `json:"user_id"`
-	Book_id string  `json:"book_id"`
+	User_id string  `json:"user_id" binding:"required,uuid"`
+	Book_id string  `json:"book_id" binding:"required,uuid"`
 	Payed   float64 `json:"payed"`
 }
 
@@ -22,32 +26,49 @@ type Order struct {
 	Payed     float64 `json:"payed"`
 	CreatedAt string  `json:"created_at"`
 	UpdatedAt string  `json:"updated_at"`
+	Version   int64   `json:"version"`
+	// DeletedAt is only set on deleted orders, see include_deleted.
+	DeletedAt string `json:"deleted_at,omitempty"`
 }
 
 type OrderGroup struct {
+	Id        string  `json:"order_id"`
+	Book_id   string  `json:"book_id"`
 	FullName  string  `json:"fullname"`
 	Title     string  `json:"title"`
 	Payed     float64 `json:"payed"`
 	CreatedAt string  `json:"created_at"`
 	UpdatedAt string  `json:"updated_at"`
+	DeletedAt string  `json:"deleted_at,omitempty"`
 }
 
 type UpdateOrderSwagger struct {
-	User_id string `json:"user_id"`
-	Book_id string `json:"book_id"`
+	User_id string `json:"user_id" binding:"required,uuid" format:"uuid"`
+	Book_id string `json:"book_id" binding:"required,uuid" format:"uuid"`
 }
 
 type UpdateOrder struct {
 	Id        string  `json:"order_id"`
-	User_id   string  `json:"user_id"`
-	Book_id   string  `json:"book_id"`
+	User_id   string  `json:"user_id" binding:"required,uuid"`
+	Book_id   string  `json:"book_id" binding:"required,uuid"`
 	Payed     float64 `json:"payed"`
 	UpdatedAt string  `json:"updated_at"`
+	// Version, when set, is the version the write expects to replace; see
+	// If-Match.
+	Version int64 `json:"-"`
+}
+
+type PatchOrderSwagger struct {
+	User_id string `json:"user_id" binding:"omitempty,uuid" format:"uuid"`
+	Book_id string `json:"book_id" binding:"omitempty,uuid" format:"uuid"`
 }
 
 type GetListOrderRequest struct {
-	Limit  int32
-	Offset int32
+	Limit          int32
+	Offset         int32
+	IncludeDeleted bool
+	// User_id, when set, lists only the orders of the user.
+	User_id string
 }
 
 type GetListOrderResponse struct {
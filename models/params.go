@@ -0,0 +1,18 @@
+package models
+
+// IdParam is the :id path parameter of the by-id routes.
+type IdParam struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+// ListParams are the paging query parameters of the list routes.
+type ListParams struct {
+	Limit  int32 `form:"limit" binding:"gte=0,lte=1000"`
+	Offset int32 `form:"offset" binding:"gte=0"`
+}
+
+// DeletedParams is the include_deleted query parameter of the routes of
+// soft deleted entities, which only super admins may set.
+type DeletedParams struct {
+	IncludeDeleted bool `form:"include_deleted"`
+}
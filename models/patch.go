@@ -0,0 +1,22 @@
+package models
+
+// Media types accepted by the PATCH routes.
+const (
+	// MergePatchType is an RFC 7396 JSON Merge Patch.
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType is an RFC 6902 JSON Patch.
+	JSONPatchType = "application/json-patch+json"
+)
+
+// PatchRequest is a raw patch document for the entity Id. ContentType picks
+// the patch format; plain application/json is read as a merge patch.
+type PatchRequest struct {
+	Id          string
+	ContentType string
+	Document    []byte
+	// Version, when set, is the version the patch expects to replace.
+	Version int64
+	// Super is set for super admins, who may change fields other callers
+	// cannot, such as the balance and the password of a user.
+	Super bool
+}
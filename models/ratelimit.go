@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RateLimitResult is the outcome of counting a request against a rate limit.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long a rejected principal has to wait.
+	RetryAfter time.Duration
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Token describes the access token of an authenticated request.
+type Token struct {
+	// Id is the jti claim. Tokens issued before sessions were tracked have
+	// none.
+	Id        string
+	User_id   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Session is a live access token with the device it was issued to.
+type Session struct {
+	Id        string `json:"session_id"`
+	User_id   string `json:"user_id"`
+	Kind      string `json:"kind" example:"client"`
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	// Current marks the session of the token the list was requested with.
+	Current bool `json:"current"`
+}
+
+type GetListSessionResponse struct {
+	Count    int32      `json:"count"`
+	Sessions []*Session `json:"sessions"`
+}
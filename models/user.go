@@ -3,50 +3,126 @@ package models
 type UserPrimarKey struct {
 	Id    string `json:"user_id"`
 	Login string `json:"login"`
+	Email string `json:"email"`
+	// Version, when set, restricts Delete to that version.
+	Version int64 `json:"-"`
+	// IncludeDeleted finds the user even if it is deleted.
+	IncludeDeleted bool `json:"-"`
 }
 
 type CreateUser struct {
-	First_name   string  `json:"first_name"`
-	Last_name    string  `json:"last_name"`
-	Login        string  `json:"login"`
-	Password     string  `json:"password"`
-	Phone_number string  `json:"phone_number"`
-	Balance      float64 `json:"balance"`
+	First_name string `json:"first_name" binding:"required,max=45" example:"Samandar"`
+	Last_name  string `json:"last_name" binding:"required,max=45" example:"Foziljonov"`
+	Login      string `json:"login" binding:"required,min=3,max=64" example:"samandar"`
+	Password   string `json:"password" binding:"required,min=6,max=72"`
+	// 9 digits without country code
+	Phone_number string  `json:"phone_number" binding:"required,phone" example:"997191323"`
+	Balance      float64 `json:"balance" binding:"gte=0"`
+	// Email is only set by Register, which verifies it.
+	Email string `json:"-"`
 }
 type User struct {
-	Id           string  `json:"user_id"`
-	First_name   string  `json:"first_name"`
-	Last_name    string  `json:"last_name"`
-	Login        string  `json:"login"`
-	Password     string  `json:"password"`
+	Id         string `json:"user_id"`
+	First_name string `json:"first_name"`
+	Last_name  string `json:"last_name"`
+	Login      string `json:"login"`
+	// Password is the bcrypt hash of the password and never leaves the
+	// server. The audit log records that it changed.
+	Password     string  `json:"-" audit:"password"`
 	Phone_number string  `json:"phone_number"`
 	Balance      float64 `json:"balance"`
-	CreatedAt    string  `json:"created_at"`
-	UpdatedAt    string  `json:"updated_at"`
+	Email        string  `json:"email,omitempty"`
+	// EmailVerified is false until the link sent on registration is used.
+	EmailVerified bool `json:"email_verified"`
+	// IsAdmin allows super admin logins. It is only set out of band, with
+	// the admin command.
+	IsAdmin   bool   `json:"is_admin"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Version   int64  `json:"version"`
+	// DeletedAt is only set on deleted users, see include_deleted.
+	DeletedAt string `json:"deleted_at,omitempty"`
+	// ErasedAt is only set on erased users, whose personal data was
+	// replaced with placeholders.
+	ErasedAt string `json:"erased_at,omitempty"`
 }
 
 type UpdateUserSwagger struct {
-	First_name   string  `json:"first_name"`
-	Last_name    string  `json:"last_name"`
-	Login        string  `json:"login"`
-	Password     string  `json:"password"`
-	Phone_number string  `json:"phone_number"`
-	Balance      float64 `json:"balance"`
+	First_name string `json:"first_name" binding:"required,max=45" example:"Samandar"`
+	Last_name  string `json:"last_name" binding:"required,max=45" example:"Foziljonov"`
+	Login      string `json:"login" binding:"required,min=3,max=64" example:"samandar"`
+	Password   string `json:"password" binding:"required,min=6,max=72"`
+	// 9 digits without country code
+	Phone_number string  `json:"phone_number" binding:"required,phone" example:"997191323"`
+	Balance      float64 `json:"balance" binding:"gte=0"`
 }
 
 type UpdateUser struct {
 	Id           string  `json:"user_id"`
-	First_name   string  `json:"first_name"`
-	Last_name    string  `json:"last_name"`
-	Login        string  `json:"login"`
-	Password     string  `json:"password"`
-	Phone_number string  `json:"phone_number"`
-	Balance      float64 `json:"balance"`
+	First_name   string  `json:"first_name" binding:"required,max=45"`
+	Last_name    string  `json:"last_name" binding:"required,max=45"`
+	Login        string  `json:"login" binding:"required,min=3,max=64"`
+	Password     string  `json:"password" binding:"required,min=6,max=72"`
+	Phone_number string  `json:"phone_number" binding:"required,phone"`
+	Balance      float64 `json:"balance" binding:"gte=0"`
+	// Version, when set, is the version the write expects to replace; see
+	// If-Match.
+	Version int64 `json:"-"`
+	// Super is set for super admins, the only callers that may change the
+	// balance and the password.
+	Super bool `json:"-"`
+}
+
+type PatchUserSwagger struct {
+	First_name string `json:"first_name" binding:"omitempty,max=45" example:"Samandar"`
+	Last_name  string `json:"last_name" binding:"omitempty,max=45" example:"Foziljonov"`
+	Login      string `json:"login" binding:"omitempty,min=3,max=64" example:"samandar"`
+	Password   string `json:"password" binding:"omitempty,min=6,max=72"`
+	// 9 digits without country code
+	Phone_number string  `json:"phone_number" binding:"omitempty,phone" example:"997191323"`
+	Balance      float64 `json:"balance" binding:"gte=0"`
+}
+
+// PatchUser holds the columns a partial update writes. Nil fields are left
+// unchanged.
+type PatchUser struct {
+	Id           string
+	First_name   *string
+	Last_name    *string
+	Login        *string
+	Password     *string
+	Phone_number *string
+	Balance      *float64
+	// EmailVerified, when true, marks the email verified.
+	EmailVerified bool
+	IsAdmin       *bool
+	Version       int64
+}
+
+// EraseUser replaces the names and login of a user with placeholders. The
+// password, phone number and email are cleared.
+type EraseUser struct {
+	Id         string
+	First_name string
+	Last_name  string
+	Login      string
+}
+
+// SetAdmin grants or revokes the admin flag of the user with Login.
+type SetAdmin struct {
+	Login   string `json:"login" binding:"required"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+type UpdateBalance struct {
+	Id     string  `json:"user_id"`
+	Amount float64 `json:"amount"`
 }
 
 type GetListUserRequest struct {
-	Limit  int32
-	Offset int32
+	Limit          int32
+	Offset         int32
+	IncludeDeleted bool
 }
 
 type GetListUserResponse struct {
@@ -0,0 +1,158 @@
+// Package audit carries who makes a request in the context, so the service
+// layer can record it next to the writes it makes, and computes what a write
+// changed.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// Actions of the audit log.
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionErase   = "erase"
+)
+
+// Entity types of the audit log.
+const (
+	EntityBook   = "book"
+	EntityUser   = "user"
+	EntityOrder  = "order"
+	EntityAPIKey = "api_key"
+)
+
+// Actor is who a request is made by. UserID and APIKeyID are empty for
+// anonymous requests.
+type Actor struct {
+	UserID    string
+	APIKeyID  string
+	IP        string
+	RequestID string
+}
+
+type ctxKey struct{}
+
+// WithActor returns a copy of ctx made by actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, ctxKey{}, actor)
+}
+
+// ActorFrom returns the actor of ctx, which is empty outside a request.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(ctxKey{}).(Actor)
+	return actor
+}
+
+// ignored fields change with every write and say nothing about it.
+var ignored = map[string]bool{
+	"updated_at": true,
+	"version":    true,
+}
+
+// redacted fields are recorded as changed without their values.
+var redacted = map[string]bool{
+	"password": true,
+}
+
+const redactedValue = "[redacted]"
+
+// Diff returns the JSON fields of before and after that differ, each as a
+// JSON object. A nil before (a create) or after (a delete) gives null and
+// all fields of the other. Both are null when nothing changed.
+func Diff(before, after interface{}) (json.RawMessage, json.RawMessage, error) {
+
+	old, err := fields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	new, err := fields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if old != nil && new != nil {
+		for name, value := range old {
+			if reflect.DeepEqual(value, new[name]) {
+				delete(old, name)
+				delete(new, name)
+			}
+		}
+
+		if len(old) == 0 && len(new) == 0 {
+			return nil, nil, nil
+		}
+	}
+
+	oldJSON, err := marshal(old)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newJSON, err := marshal(new)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return oldJSON, newJSON, nil
+}
+
+func fields(v interface{}) (map[string]interface{}, error) {
+
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+
+	err = json.Unmarshal(raw, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields kept out of JSON, such as password hashes, are compared under
+	// the name of their audit tag.
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() == reflect.Struct {
+		for i := 0; i < rv.NumField(); i++ {
+			if name := rv.Type().Field(i).Tag.Get("audit"); name != "" {
+				m[name] = rv.Field(i).Interface()
+			}
+		}
+	}
+
+	for name := range m {
+		if ignored[name] {
+			delete(m, name)
+		}
+	}
+
+	return m, nil
+}
+
+// marshal encodes m, hiding redacted values. Changed redacted fields are
+// compared before they are hidden, so they still show up as changed.
+func marshal(m map[string]interface{}) (json.RawMessage, error) {
+
+	if m == nil {
+		return nil, nil
+	}
+
+	for name := range m {
+		if redacted[name] {
+			m[name] = redactedValue
+		}
+	}
+
+	return json.Marshal(m)
+}
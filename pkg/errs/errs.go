@@ -0,0 +1,202 @@
+// Package errs defines the domain errors shared by storage, handlers and the
+// HTTP responder. Every error carries a stable Code that clients can rely on;
+// the HTTP status is derived from the code.
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type Code string
+
+const (
+	CodeInvalidArgument   Code = "INVALID_ARGUMENT"
+	CodeValidation        Code = "VALIDATION_FAILED"
+	CodeUnauthorized      Code = "UNAUTHORIZED"
+	CodeForbidden         Code = "FORBIDDEN"
+	CodeNotFound          Code = "NOT_FOUND"
+	CodeConflict          Code = "CONFLICT"
+	CodeUnsupportedMedia  Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodePrecondition      Code = "PRECONDITION_FAILED"
+	CodeTooManyRequests   Code = "TOO_MANY_REQUESTS"
+	CodeInsufficientFunds Code = "INSUFFICIENT_FUNDS"
+	CodeTimeout           Code = "TIMEOUT"
+	CodeCanceled          Code = "CANCELED"
+	CodeInternal          Code = "INTERNAL"
+)
+
+// StatusClientClosedRequest is the non-standard status (from nginx) used when
+// the client went away before the response was ready.
+const StatusClientClosedRequest = 499
+
+var statuses = map[Code]int{
+	CodeInvalidArgument:   http.StatusBadRequest,
+	CodeValidation:        http.StatusUnprocessableEntity,
+	CodeUnauthorized:      http.StatusUnauthorized,
+	CodeForbidden:         http.StatusForbidden,
+	CodeNotFound:          http.StatusNotFound,
+	CodeConflict:          http.StatusConflict,
+	CodeUnsupportedMedia:  http.StatusUnsupportedMediaType,
+	CodePrecondition:      http.StatusPreconditionFailed,
+	CodeTooManyRequests:   http.StatusTooManyRequests,
+	CodeInsufficientFunds: http.StatusPaymentRequired,
+	CodeTimeout:           http.StatusGatewayTimeout,
+	CodeCanceled:          StatusClientClosedRequest,
+	CodeInternal:          http.StatusInternalServerError,
+}
+
+// Sentinels for errors.Is, e.g. errors.Is(err, errs.ErrNotFound).
+var (
+	ErrInvalidArgument   = &Error{Code: CodeInvalidArgument}
+	ErrValidation        = &Error{Code: CodeValidation}
+	ErrUnauthorized      = &Error{Code: CodeUnauthorized}
+	ErrForbidden         = &Error{Code: CodeForbidden}
+	ErrNotFound          = &Error{Code: CodeNotFound}
+	ErrConflict          = &Error{Code: CodeConflict}
+	ErrUnsupportedMedia  = &Error{Code: CodeUnsupportedMedia}
+	ErrPrecondition      = &Error{Code: CodePrecondition}
+	ErrTooManyRequests   = &Error{Code: CodeTooManyRequests}
+	ErrInsufficientFunds = &Error{Code: CodeInsufficientFunds}
+	ErrTimeout           = &Error{Code: CodeTimeout}
+	ErrCanceled          = &Error{Code: CodeCanceled}
+)
+
+type Error struct {
+	Code    Code
+	Message string
+	// Details is serialised as is into the response, e.g. field errors.
+	Details interface{}
+	// Err is the underlying cause. It is logged but never sent to clients.
+	Err error
+	// RetryAfter, when set, is sent as the Retry-After header.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches sentinels by code.
+func (e *Error) Is(target error) bool {
+
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return t.Code == e.Code && t.Message == ""
+}
+
+func (e *Error) WithDetails(details interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func InvalidArgument(format string, args ...interface{}) *Error {
+	return New(CodeInvalidArgument, format, args...)
+}
+
+func Validation(format string, args ...interface{}) *Error {
+	return New(CodeValidation, format, args...)
+}
+
+func Unauthorized(format string, args ...interface{}) *Error {
+	return New(CodeUnauthorized, format, args...)
+}
+
+func Forbidden(format string, args ...interface{}) *Error {
+	return New(CodeForbidden, format, args...)
+}
+
+func NotFound(format string, args ...interface{}) *Error {
+	return New(CodeNotFound, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) *Error {
+	return New(CodeConflict, format, args...)
+}
+
+func UnsupportedMedia(format string, args ...interface{}) *Error {
+	return New(CodeUnsupportedMedia, format, args...)
+}
+
+func PreconditionFailed(format string, args ...interface{}) *Error {
+	return New(CodePrecondition, format, args...)
+}
+
+// TooManyRequests tells the client to retry after retryAfter.
+func TooManyRequests(retryAfter time.Duration, format string, args ...interface{}) *Error {
+
+	e := New(CodeTooManyRequests, format, args...)
+	e.RetryAfter = retryAfter
+
+	return e
+}
+
+func InsufficientFunds(format string, args ...interface{}) *Error {
+	return New(CodeInsufficientFunds, format, args...)
+}
+
+func Timeout(format string, args ...interface{}) *Error {
+	return New(CodeTimeout, format, args...)
+}
+
+// Internal hides err behind a generic message.
+func Internal(err error) *Error {
+	return New(CodeInternal, "internal server error").Wrap(err)
+}
+
+// From returns err as an *Error. Expired and cancelled contexts become
+// TIMEOUT and CANCELED, other unknown errors are treated as internal.
+func From(err error) *Error {
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Timeout("request timed out").Wrap(err)
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return New(CodeCanceled, "request was cancelled").Wrap(err)
+	}
+
+	return Internal(err)
+}
+
+// HTTPStatus returns the status code a response carrying code should use.
+func HTTPStatus(code Code) int {
+
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,192 @@
+package helper
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Placeholder renders the n-th (1-based) bind parameter of a query.
+type Placeholder func(n int) string
+
+// Dollar renders Postgres style placeholders: $1, $2, ...
+func Dollar(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// Question renders SQLite/MySQL style placeholders: ?
+func Question(int) string {
+	return "?"
+}
+
+var identRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// UpdateQuery builds an UPDATE statement whose SET list is only known at
+// runtime. Table and column names must be identifiers written in code and
+// panic otherwise; values are always sent as bind parameters, never spliced
+// into the SQL.
+type UpdateQuery struct {
+	table       string
+	placeholder Placeholder
+	sets        []assignment
+	where       []assignment
+}
+
+// assignment is "column = value", or "column = expr" when expr is set. As
+// a condition it is "column IS NULL" or "column IS NOT NULL" when null is.
+type assignment struct {
+	column string
+	value  interface{}
+	expr   string
+	null   string
+}
+
+func NewUpdateQuery(table string, placeholder Placeholder) *UpdateQuery {
+	return &UpdateQuery{
+		table:       ident(table),
+		placeholder: placeholder,
+	}
+}
+
+// Set assigns value to column.
+func (q *UpdateQuery) Set(column string, value interface{}) *UpdateQuery {
+
+	q.sets = append(q.sets, assignment{column: ident(column), value: value})
+
+	return q
+}
+
+// SetExpr assigns a constant SQL expression such as now() to column.
+func (q *UpdateQuery) SetExpr(column, expr string) *UpdateQuery {
+
+	q.sets = append(q.sets, assignment{column: ident(column), expr: expr})
+
+	return q
+}
+
+// Where restricts the statement to rows where column equals value. Several
+// conditions are joined with AND.
+func (q *UpdateQuery) Where(column string, value interface{}) *UpdateQuery {
+
+	q.where = append(q.where, assignment{column: ident(column), value: value})
+
+	return q
+}
+
+// WhereNull restricts the statement to rows where column is NULL, or is not
+// NULL when null is false.
+func (q *UpdateQuery) WhereNull(column string, null bool) *UpdateQuery {
+
+	cond := "IS NULL"
+	if !null {
+		cond = "IS NOT NULL"
+	}
+
+	q.where = append(q.where, assignment{column: ident(column), null: cond})
+
+	return q
+}
+
+// Build returns the statement and its arguments. It panics without a Where
+// condition, so a bug can never update a whole table.
+func (q *UpdateQuery) Build() (string, []interface{}) {
+
+	if len(q.where) == 0 {
+		panic("helper: UPDATE " + q.table + " without WHERE")
+	}
+
+	var args []interface{}
+
+	render := func(list []assignment) []string {
+		out := make([]string, 0, len(list))
+		for _, a := range list {
+			if a.null != "" {
+				out = append(out, a.column+" "+a.null)
+				continue
+			}
+			if a.expr != "" {
+				out = append(out, a.column+" = "+a.expr)
+				continue
+			}
+			args = append(args, a.value)
+			out = append(out, a.column+" = "+q.placeholder(len(args)))
+		}
+		return out
+	}
+
+	sets := render(q.sets)
+	where := render(q.where)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		q.table,
+		strings.Join(sets, ", "),
+		strings.Join(where, " AND "),
+	)
+
+	return query, args
+}
+
+func ident(name string) string {
+
+	if !identRegex.MatchString(name) {
+		panic(fmt.Sprintf("helper: invalid SQL identifier %q", name))
+	}
+
+	return name
+}
+
+var operators = map[string]bool{"=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// Filter builds a WHERE clause from optional conditions, such as the query
+// parameters of a list route. Like UpdateQuery it only takes identifiers
+// and operators written in code, and sends values as bind parameters.
+type Filter struct {
+	placeholder Placeholder
+	conds       []string
+	args        []interface{}
+}
+
+func NewFilter(placeholder Placeholder) *Filter {
+	return &Filter{
+		placeholder: placeholder,
+	}
+}
+
+// Where adds "column op value". Several conditions are joined with AND.
+func (f *Filter) Where(column, op string, value interface{}) *Filter {
+
+	if !operators[op] {
+		panic(fmt.Sprintf("helper: invalid SQL operator %q", op))
+	}
+
+	f.args = append(f.args, value)
+	f.conds = append(f.conds, ident(column)+" "+op+" "+f.placeholder(len(f.args)))
+
+	return f
+}
+
+// WhereNull adds "column IS NULL", or "column IS NOT NULL" when null is
+// false.
+func (f *Filter) WhereNull(column string, null bool) *Filter {
+
+	cond := "IS NULL"
+	if !null {
+		cond = "IS NOT NULL"
+	}
+
+	f.conds = append(f.conds, ident(column)+" "+cond)
+
+	return f
+}
+
+// Build returns " WHERE ..." and its arguments, or an empty clause without
+// conditions.
+func (f *Filter) Build() (string, []interface{}) {
+
+	if len(f.conds) == 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(f.conds, " AND "), f.args
+}
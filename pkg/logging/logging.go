@@ -0,0 +1,94 @@
+// Package logging builds the structured logger of the service and carries
+// request-scoped attributes, such as the request id, in the context. Every
+// line logged with such a context (InfoContext, ErrorContext, ...) gets the
+// attributes, whichever layer logs it.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New returns a logger writing to w. level is one of debug, info, warn or
+// error; format is json or text.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+
+	var lvl slog.Level
+	err := lvl.UnmarshalText([]byte(level))
+	if err != nil {
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	return slog.New(contextHandler{handler}), nil
+}
+
+// Discard returns a logger that drops everything, for tools and tests.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+type ctxKey struct{}
+
+// With returns a copy of ctx whose log lines also carry attrs.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+
+	prev, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+
+	next := make([]slog.Attr, 0, len(prev)+len(attrs))
+	next = append(next, prev...)
+	next = append(next, attrs...)
+
+	return context.WithValue(ctx, ctxKey{}, next)
+}
+
+// contextHandler adds the attributes stored by With, and the trace and span
+// ids of the current span, to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes every message as an .eml file into a directory.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+
+	err := os.MkdirAll(m.dir, 0o700)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	_, err = rand.Read(suffix)
+	if err != nil {
+		return err
+	}
+
+	name := time.Now().UTC().Format("20060102T150405.000000000Z") + "-" + hex.EncodeToString(suffix) + ".eml"
+
+	return os.WriteFile(filepath.Join(m.dir, name), render(m.from, msg), 0o600)
+}
+
+// LogMailer logs every message instead of sending it. The body holds
+// single-use tokens, so it is only meant for local development.
+type LogMailer struct {
+	log *slog.Logger
+}
+
+func NewLogMailer(log *slog.Logger) *LogMailer {
+	return &LogMailer{
+		log: log,
+	}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+
+	m.log.InfoContext(ctx, "mail",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+
+	return nil
+}
@@ -0,0 +1,41 @@
+// Package mail sends the emails of the account flows. The SMTP mailer is
+// used in production; the file and log mailers keep messages local for
+// development and tests.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	// Body is plain text.
+	Body string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// render formats msg as an RFC 5322 message.
+func render(from string, msg *Message) []byte {
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	return b.Bytes()
+}
@@ -0,0 +1,136 @@
+// Package metrics holds the Prometheus collectors of the service. A Metrics
+// value owns its own registry, which is served on /metrics.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"crud/storage"
+)
+
+const Namespace = "book_api"
+
+// Cache results recorded by CacheResult.
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	cache        *prometheus.CounterVec
+	orders       prometheus.Counter
+	revenue      prometheus.Counter
+	failedLogins *prometheus.CounterVec
+}
+
+func New() *Metrics {
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "cache_requests_total",
+			Help:      "Cache lookups by cache and result (hit, miss, error).",
+		}, []string{"cache", "result"}),
+		orders: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "orders_created_total",
+			Help:      "Orders created.",
+		}),
+		revenue: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "revenue_total",
+			Help:      "Sum of the prices charged for created orders.",
+		}),
+		failedLogins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "failed_logins_total",
+			Help:      "Rejected logins by kind (client, super).",
+		}, []string{"kind"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.cache,
+		m.orders,
+		m.revenue,
+		m.failedLogins,
+	)
+
+	return m
+}
+
+// Register adds extra collectors, e.g. the database pool of the storage.
+func (m *Metrics) Register(c prometheus.Collector) error {
+	return m.registry.Register(c)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records one HTTP request. route is the route template, so
+// the number of series does not grow with ids in the path.
+func (m *Metrics) ObserveRequest(method, route string, status int, latency time.Duration) {
+
+	if route == "" {
+		route = "unmatched"
+	}
+
+	code := strconv.Itoa(status)
+
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(latency.Seconds())
+}
+
+// CacheResult records the outcome of a cache lookup from its error.
+func (m *Metrics) CacheResult(cache string, err error) {
+
+	result := CacheHit
+	switch {
+	case errors.Is(err, storage.ErrCacheMiss):
+		result = CacheMiss
+	case err != nil:
+		result = CacheError
+	}
+
+	m.cache.WithLabelValues(cache, result).Inc()
+}
+
+// OrderCreated records a created order and the amount charged for it.
+func (m *Metrics) OrderCreated(payed float64) {
+	m.orders.Inc()
+	m.revenue.Add(payed)
+}
+
+// FailedLogin records a rejected login of the given kind.
+func (m *Metrics) FailedLogin(kind string) {
+	m.failedLogins.WithLabelValues(kind).Inc()
+}
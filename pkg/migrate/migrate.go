@@ -0,0 +1,724 @@
+// Package migrate applies versioned SQL migrations embedded in the binary and
+// records them in a schema_migrations table.
+//
+// Migration files are named NN_name.up.sql and NN_name.down.sql, where NN is
+// the version. Every version needs both files; up files are checksummed so a
+// migration edited after it was applied is reported instead of silently
+// ignored.
+//
+// Databases migrated by golang-migrate, or created before migrations were
+// recorded, are refused until Baseline records what they already have.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	SQLite
+)
+
+// advisoryLockID serialises concurrent migrators on Postgres.
+const advisoryLockID = 7305620111
+
+var ErrSchemaBehind = errors.New("database schema is behind, run `migrate up`")
+
+// ErrUnversioned is returned for a database whose schema does not record its
+// migrations here: one migrated by golang-migrate, or with tables but without
+// our table of migrations. Baseline adopts it.
+var ErrUnversioned = errors.New("database schema is not versioned, run `migrate baseline <version>`")
+
+const createTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR NOT NULL,
+		checksum VARCHAR NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+	)
+`
+
+type Migration struct {
+	Version  uint
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt string
+	// Modified is set when the applied checksum differs from the embedded file.
+	Modified bool
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []*Migration
+}
+
+func New(db *sql.DB, dialect Dialect, fsys fs.FS) (*Migrator, error) {
+
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// LatestVersion returns the highest migration version found in fsys.
+func LatestVersion(fsys fs.FS) (uint, error) {
+
+	migrations, err := load(fsys)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Latest returns the highest known migration version.
+func (m *Migrator) Latest() uint {
+
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the highest applied migration version.
+func (m *Migrator) Version(ctx context.Context) (uint, error) {
+
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var version uint
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+
+	return version, nil
+}
+
+// Check returns ErrSchemaBehind when migrations are pending and an error when
+// an applied migration was modified afterwards.
+func (m *Migrator) Check(ctx context.Context) error {
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range statuses {
+		if s.Modified {
+			return fmt.Errorf("migration %d_%s was modified after it was applied", s.Version, s.Name)
+		}
+		if !s.Applied {
+			return fmt.Errorf("%w: version %d_%s is not applied", ErrSchemaBehind, s.Version, s.Name)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, mig := range m.migrations {
+
+		status := Status{
+			Version: mig.Version,
+			Name:    mig.Name,
+		}
+
+		if row, ok := applied[mig.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.appliedAt
+			status.Modified = row.checksum != mig.Checksum
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down reverts the last applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version == 0 {
+		return nil
+	}
+
+	var target uint
+	for _, mig := range m.migrations {
+		if mig.Version < version {
+			target = mig.Version
+		}
+	}
+
+	return m.Goto(ctx, target)
+}
+
+// Goto migrates up or down until version is the last applied migration.
+// Version 0 reverts everything.
+func (m *Migrator) Goto(ctx context.Context, version uint) error {
+
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	conn, unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, createTable)
+	if err != nil {
+		return err
+	}
+
+	for _, mig := range m.migrations {
+		if row, ok := applied[mig.Version]; ok && row.checksum != mig.Checksum {
+			return fmt.Errorf("migration %d_%s was modified after it was applied", mig.Version, mig.Name)
+		}
+	}
+
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok || mig.Version > version {
+			continue
+		}
+
+		err = m.apply(ctx, conn, mig.Up,
+			m.bind("INSERT INTO schema_migrations(version, name, checksum) VALUES ($1, $2, $3)"),
+			mig.Version, mig.Name, mig.Checksum,
+		)
+		if err != nil {
+			return fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok || mig.Version <= version {
+			continue
+		}
+
+		err = m.apply(ctx, conn, mig.Down,
+			m.bind("DELETE FROM schema_migrations WHERE version = $1"),
+			mig.Version,
+		)
+		if err != nil {
+			return fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Baseline adopts a database whose schema is not versioned (see
+// ErrUnversioned) by recording the migrations up to version as applied,
+// without running them. The table of golang-migrate is replaced; its version
+// must match and must not be dirty. Version 0 records nothing, for databases
+// whose tables are not ours.
+func (m *Migrator) Baseline(ctx context.Context, version uint) error {
+
+	conn, unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := m.inspect(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case state.legacy != nil && state.legacy.dirty:
+		return fmt.Errorf("golang-migrate left version %d dirty, repair the schema and run `migrate force <version>`", state.legacy.version)
+	case state.legacy != nil && state.legacy.version != version:
+		return fmt.Errorf("golang-migrate recorded version %d, not %d", state.legacy.version, version)
+	case state.legacy == nil && state.applied > 0:
+		return errors.New("database schema is already versioned, use `migrate force <version>` to change its records")
+	}
+
+	return m.mark(ctx, conn, version, state.legacy != nil)
+}
+
+// Force records exactly the migrations up to version as applied, with the
+// checksums of the embedded files, without running any of them. It repairs
+// the records after a migration failed halfway or was applied by hand, and
+// replaces the table of golang-migrate even when it is dirty.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+
+	conn, unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := m.inspect(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return m.mark(ctx, conn, version, state.legacy != nil)
+}
+
+// mark replaces the records with the migrations up to version, dropping the
+// table of golang-migrate first when legacy is set.
+func (m *Migrator) mark(ctx context.Context, conn *sql.Conn, version uint, legacy bool) error {
+
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if legacy {
+		_, err = tx.ExecContext(ctx, "DROP TABLE schema_migrations")
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, createTable)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+
+		_, err = tx.ExecContext(ctx, m.bind("INSERT INTO schema_migrations(version, name, checksum) VALUES ($1, $2, $3)"),
+			mig.Version, mig.Name, mig.Checksum,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// lock takes a connection and, on Postgres, the advisory lock that
+// serialises migrators. unlock releases both.
+func (m *Migrator) lock(ctx context.Context) (*sql.Conn, func(), error) {
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.dialect != Postgres {
+		return conn, func() { conn.Close() }, nil
+	}
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockID)
+		conn.Close()
+	}, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, record, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type appliedRow struct {
+	checksum  string
+	appliedAt string
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// schemaState is how a database records its migrations.
+type schemaState struct {
+	// legacy is set when schema_migrations is the table of golang-migrate.
+	legacy *legacyVersion
+	// versioned is set when schema_migrations is our table, even empty
+	// after a baseline at version 0.
+	versioned bool
+	// applied counts the migrations recorded in our format.
+	applied int
+	// tables counts the other tables.
+	tables int
+}
+
+// legacyVersion is the single row golang-migrate keeps.
+type legacyVersion struct {
+	version uint
+	dirty   bool
+}
+
+// applied returns the recorded migrations. A schema that is not versioned
+// here fails with ErrUnversioned, so its migrations are not applied twice.
+func (m *Migrator) applied(ctx context.Context, q querier) (map[uint]appliedRow, error) {
+
+	resp := map[uint]appliedRow{}
+
+	state, err := m.inspect(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case state.legacy != nil:
+		dirty := ""
+		if state.legacy.dirty {
+			dirty = ", dirty"
+		}
+		return nil, fmt.Errorf("%w: golang-migrate recorded version %d%s", ErrUnversioned, state.legacy.version, dirty)
+	case !state.versioned && state.tables > 0:
+		return nil, fmt.Errorf("%w: the database has %d tables but no applied migrations, baseline it with the version they match (0 if they are not ours)", ErrUnversioned, state.tables)
+	case state.applied == 0:
+		return resp, nil
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var (
+			version   int64
+			checksum  sql.NullString
+			appliedAt sql.NullString
+		)
+
+		err = rows.Scan(&version, &checksum, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		resp[uint(version)] = appliedRow{
+			checksum:  checksum.String,
+			appliedAt: appliedAt.String,
+		}
+	}
+
+	return resp, rows.Err()
+}
+
+// inspect finds out how the database records its migrations. The table of
+// golang-migrate has the same name as ours but no checksum column.
+func (m *Migrator) inspect(ctx context.Context, q querier) (*schemaState, error) {
+
+	var (
+		state   = &schemaState{}
+		columns = "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'schema_migrations'"
+		tables  = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' AND table_name <> 'schema_migrations'"
+	)
+
+	if m.dialect == SQLite {
+		columns = "SELECT name FROM pragma_table_info('schema_migrations')"
+		tables = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name <> 'schema_migrations'"
+	}
+
+	names, err := queryStrings(ctx, q, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryRow(ctx, q, tables, &state.tables)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) == 0 {
+		return state, nil
+	}
+
+	var checksum, dirty bool
+	for _, name := range names {
+		checksum = checksum || name == "checksum"
+		dirty = dirty || name == "dirty"
+	}
+
+	if checksum {
+		state.versioned = true
+		return state, queryRow(ctx, q, "SELECT COUNT(*) FROM schema_migrations", &state.applied)
+	}
+
+	if !dirty {
+		return nil, errors.New("schema_migrations has an unknown format")
+	}
+
+	// golang-migrate keeps one row, or none before its first migration.
+	rows, err := q.QueryContext(ctx, "SELECT version, dirty FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	state.legacy = &legacyVersion{}
+
+	for rows.Next() {
+
+		var version int64
+
+		err = rows.Scan(&version, &state.legacy.dirty)
+		if err != nil {
+			return nil, err
+		}
+
+		state.legacy.version = uint(version)
+	}
+
+	return state, rows.Err()
+}
+
+func queryStrings(ctx context.Context, q querier, query string) ([]string, error) {
+
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var resp []string
+	for rows.Next() {
+
+		var value string
+
+		err = rows.Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+
+		resp = append(resp, value)
+	}
+
+	return resp, rows.Err()
+}
+
+func queryRow(ctx context.Context, q querier, query string, dest interface{}) error {
+
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.Scan(dest)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (m *Migrator) find(version uint) *Migration {
+
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+
+	return nil
+}
+
+// bind rewrites $N placeholders for dialects that only understand "?".
+func (m *Migrator) bind(query string) string {
+
+	if m.dialect != SQLite {
+		return query
+	}
+
+	for i := 9; i > 0; i-- {
+		query = strings.ReplaceAll(query, "$"+strconv.Itoa(i), "?")
+	}
+
+	return query
+}
+
+func load(fsys fs.FS) ([]*Migration, error) {
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+
+	for _, entry := range entries {
+
+		name := entry.Name()
+		if entry.IsDir() || path.Ext(name) != ".sql" {
+			continue
+		}
+
+		base := strings.TrimSuffix(name, ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 || (direction != ".up" && direction != ".down") {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+
+		version, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", name)
+		}
+
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[uint(version)]
+		if !ok {
+			mig = &Migration{Version: uint(version), Name: parts[1]}
+			byVersion[uint(version)] = mig
+		}
+
+		if mig.Name != parts[1] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, parts[1])
+		}
+
+		if direction == ".up" {
+			sum := sha256.Sum256(body)
+			mig.Up = string(body)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	var migrations []*Migration
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// FormatStatus renders statuses as a table for the `migrate status` command.
+func FormatStatus(statuses []Status) string {
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%-8s %-30s %-10s %s\n", "VERSION", "NAME", "STATE", "APPLIED AT")
+
+	for _, s := range statuses {
+
+		state := "pending"
+		if s.Applied {
+			state = "applied"
+		}
+		if s.Modified {
+			state = "modified"
+		}
+
+		appliedAt := s.AppliedAt
+		if t, err := time.Parse(time.RFC3339Nano, appliedAt); err == nil {
+			appliedAt = t.Format("2006-01-02 15:04:05")
+		}
+
+		fmt.Fprintf(&b, "%-8d %-30s %-10s %s\n", s.Version, s.Name, state, appliedAt)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,406 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+// testFS has two migrations, each creating a table.
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"01_books.up.sql":    {Data: []byte("CREATE TABLE books (id TEXT PRIMARY KEY);")},
+		"01_books.down.sql":  {Data: []byte("DROP TABLE books;")},
+		"02_orders.up.sql":   {Data: []byte("CREATE TABLE orders (id TEXT PRIMARY KEY);")},
+		"02_orders.down.sql": {Data: []byte("DROP TABLE orders;")},
+	}
+}
+
+// newTestDB opens a SQLite database in a new temporary file.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB, fsys fstest.MapFS) *Migrator {
+	t.Helper()
+
+	m, err := New(db, SQLite, fsys)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return m
+}
+
+func TestLoad(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    []uint
+		wantErr bool
+	}{
+		{
+			name:  "sorted by version",
+			files: map[string]string{"10_b.up.sql": "b", "10_b.down.sql": "b", "2_a.up.sql": "a", "2_a.down.sql": "a"},
+			want:  []uint{2, 10},
+		},
+		{
+			name:  "other files are ignored",
+			files: map[string]string{"1_a.up.sql": "a", "1_a.down.sql": "a", "README.md": "docs"},
+			want:  []uint{1},
+		},
+		{
+			name:  "empty",
+			files: map[string]string{},
+		},
+		{
+			name:    "no name",
+			files:   map[string]string{"1.up.sql": "a", "1.down.sql": "a"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown direction",
+			files:   map[string]string{"1_a.sideways.sql": "a"},
+			wantErr: true,
+		},
+		{
+			name:    "version is not a number",
+			files:   map[string]string{"one_a.up.sql": "a", "one_a.down.sql": "a"},
+			wantErr: true,
+		},
+		{
+			name:    "version 0",
+			files:   map[string]string{"0_a.up.sql": "a", "0_a.down.sql": "a"},
+			wantErr: true,
+		},
+		{
+			name:    "missing down file",
+			files:   map[string]string{"1_a.up.sql": "a"},
+			wantErr: true,
+		},
+		{
+			name:    "missing up file",
+			files:   map[string]string{"1_a.down.sql": "a"},
+			wantErr: true,
+		},
+		{
+			name:    "conflicting names",
+			files:   map[string]string{"1_a.up.sql": "a", "1_a.down.sql": "a", "1_b.up.sql": "b", "1_b.down.sql": "b"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			fsys := fstest.MapFS{}
+			for name, body := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(body)}
+			}
+
+			migrations, err := load(fsys)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("load succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+
+			var got []uint
+			for _, mig := range migrations {
+				got = append(got, mig.Version)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("versions = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("versions = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGoto(t *testing.T) {
+
+	ctx := context.Background()
+	db := newTestDB(t)
+	m := newTestMigrator(t, db, testFS())
+
+	steps := []struct {
+		name string
+		run  func() error
+		want uint
+	}{
+		{name: "up", run: func() error { return m.Up(ctx) }, want: 2},
+		{name: "up again", run: func() error { return m.Up(ctx) }, want: 2},
+		{name: "down", run: func() error { return m.Down(ctx) }, want: 1},
+		{name: "goto 2", run: func() error { return m.Goto(ctx, 2) }, want: 2},
+		{name: "goto 0", run: func() error { return m.Goto(ctx, 0) }, want: 0},
+		{name: "down at 0", run: func() error { return m.Down(ctx) }, want: 0},
+	}
+
+	for _, step := range steps {
+
+		err := step.run()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+
+		version, err := m.Version(ctx)
+		if err != nil {
+			t.Fatalf("%s: Version: %v", step.name, err)
+		}
+		if version != step.want {
+			t.Fatalf("%s: Version = %d, want %d", step.name, version, step.want)
+		}
+
+		var tables int
+		err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('books', 'orders')").Scan(&tables)
+		if err != nil {
+			t.Fatalf("%s: count tables: %v", step.name, err)
+		}
+		if tables != int(step.want) {
+			t.Fatalf("%s: %d tables, want %d", step.name, tables, step.want)
+		}
+	}
+
+	err := m.Goto(ctx, 3)
+	if err == nil {
+		t.Errorf("Goto of an unknown version succeeded")
+	}
+}
+
+func TestCheck(t *testing.T) {
+
+	ctx := context.Background()
+	db := newTestDB(t)
+	m := newTestMigrator(t, db, testFS())
+
+	err := m.Check(ctx)
+	if !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("Check of an empty database = %v, want ErrSchemaBehind", err)
+	}
+
+	err = m.Goto(ctx, 1)
+	if err != nil {
+		t.Fatalf("Goto: %v", err)
+	}
+
+	err = m.Check(ctx)
+	if !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("Check with a pending migration = %v, want ErrSchemaBehind", err)
+	}
+
+	err = m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	err = m.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check of an up to date database: %v", err)
+	}
+
+	// The same migrations with the first one edited after it was applied.
+	edited := testFS()
+	edited["01_books.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE books (id TEXT PRIMARY KEY, title TEXT);")}
+	m = newTestMigrator(t, db, edited)
+
+	err = m.Check(ctx)
+	if err == nil || errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("Check of a modified migration = %v, want a modification error", err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if !statuses[0].Modified || statuses[1].Modified {
+		t.Errorf("Status = %+v, want only the first migration modified", statuses)
+	}
+
+	err = m.Down(ctx)
+	if err == nil {
+		t.Errorf("Down with a modified migration succeeded")
+	}
+}
+
+func TestBaseline(t *testing.T) {
+
+	tests := []struct {
+		name string
+		// setup prepares the database before Baseline.
+		setup   string
+		version uint
+		want    uint
+		wantErr bool
+	}{
+		{
+			name:    "tables created before migrations were recorded",
+			setup:   "CREATE TABLE books (id TEXT PRIMARY KEY);",
+			version: 1,
+			want:    1,
+		},
+		{
+			name:    "tables that are not ours",
+			setup:   "CREATE TABLE other (id TEXT);",
+			version: 0,
+			want:    0,
+		},
+		{
+			name:    "golang-migrate at the same version",
+			setup:   "CREATE TABLE books (id TEXT PRIMARY KEY); CREATE TABLE schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL); INSERT INTO schema_migrations VALUES (1, false);",
+			version: 1,
+			want:    1,
+		},
+		{
+			name:    "golang-migrate at another version",
+			setup:   "CREATE TABLE books (id TEXT PRIMARY KEY); CREATE TABLE schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL); INSERT INTO schema_migrations VALUES (1, false);",
+			version: 2,
+			wantErr: true,
+		},
+		{
+			name:    "golang-migrate dirty",
+			setup:   "CREATE TABLE books (id TEXT PRIMARY KEY); CREATE TABLE schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL); INSERT INTO schema_migrations VALUES (1, true);",
+			version: 1,
+			wantErr: true,
+		},
+		{
+			name:    "unknown version",
+			setup:   "CREATE TABLE books (id TEXT PRIMARY KEY);",
+			version: 3,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ctx := context.Background()
+			db := newTestDB(t)
+			m := newTestMigrator(t, db, testFS())
+
+			_, err := db.ExecContext(ctx, tt.setup)
+			if err != nil {
+				t.Fatalf("setup: %v", err)
+			}
+
+			_, err = m.Version(ctx)
+			if !errors.Is(err, ErrUnversioned) {
+				t.Fatalf("Version before Baseline = %v, want ErrUnversioned", err)
+			}
+
+			err = m.Baseline(ctx, tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Baseline succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Baseline: %v", err)
+			}
+
+			version, err := m.Version(ctx)
+			if err != nil {
+				t.Fatalf("Version: %v", err)
+			}
+			if version != tt.want {
+				t.Errorf("Version = %d, want %d", version, tt.want)
+			}
+
+			// The other tables must not be created twice.
+			err = m.Up(ctx)
+			if err != nil {
+				t.Fatalf("Up after Baseline: %v", err)
+			}
+		})
+	}
+}
+
+func TestBaselineVersioned(t *testing.T) {
+
+	ctx := context.Background()
+	m := newTestMigrator(t, newTestDB(t), testFS())
+
+	err := m.Goto(ctx, 1)
+	if err != nil {
+		t.Fatalf("Goto: %v", err)
+	}
+
+	err = m.Baseline(ctx, 2)
+	if err == nil {
+		t.Errorf("Baseline of a versioned database succeeded")
+	}
+}
+
+func TestForce(t *testing.T) {
+
+	ctx := context.Background()
+	db := newTestDB(t)
+	m := newTestMigrator(t, db, testFS())
+
+	// A dirty golang-migrate table, repaired by hand.
+	_, err := db.ExecContext(ctx, "CREATE TABLE books (id TEXT PRIMARY KEY); CREATE TABLE schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL); INSERT INTO schema_migrations VALUES (2, true);")
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	err = m.Force(ctx, 1)
+	if err != nil {
+		t.Fatalf("Force: %v", err)
+	}
+
+	version, err := m.Version(ctx)
+	if err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("Version = %d, want 1", version)
+	}
+
+	err = m.Force(ctx, 2)
+	if err != nil {
+		t.Fatalf("Force: %v", err)
+	}
+
+	err = m.Check(ctx)
+	if err != nil {
+		t.Errorf("Check after Force: %v", err)
+	}
+
+	err = m.Force(ctx, 0)
+	if err != nil {
+		t.Fatalf("Force: %v", err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if s.Applied {
+			t.Errorf("migration %d is applied after Force(0)", s.Version)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Package oidctest runs a local OpenID Connect provider for tests, in the
+// spirit of net/http/httptest. It serves discovery, JWKS, authorization and
+// token endpoints, signs in whoever User is without asking, and enforces
+// PKCE, the redirect URI and the client credentials like a real provider.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"crud/pkg/token"
+)
+
+const keyID = "oidctest"
+
+// User is the account the provider signs in.
+type User struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	GivenName         string
+	FamilyName        string
+	PreferredUsername string
+}
+
+// Server is a provider listening on a local port. Its issuer is URL.
+type Server struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	user  User
+	deny  bool
+	codes map[string]*grant
+}
+
+type grant struct {
+	user        User
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// NewServer starts a provider that accepts the client clientID with
+// clientSecret. Close it when done.
+func NewServer(clientID, clientSecret string) (*Server, error) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		codes:        map[string]*grant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+
+	return s, nil
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetUser sets the account the following sign-ins are for.
+func (s *Server) SetUser(user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = user
+}
+
+// SetDeny makes the following sign-ins fail with access_denied, as when
+// the user cancels at the provider.
+func (s *Server) SetDeny(deny bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deny = deny
+}
+
+// Authorize follows the authorization URL the way a browser would and
+// returns the callback URL the provider redirects back to.
+func (s *Server) Authorize(authURL string) (string, error) {
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return "", fmt.Errorf("oidctest: authorize: status %d", resp.StatusCode)
+	}
+
+	return resp.Header.Get("Location"), nil
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, token.JWKS{Keys: []token.JWK{{
+		Kty: "RSA",
+		Kid: keyID,
+		Use: "sig",
+		Alg: "RS256",
+		N:   encode(s.key.N.Bytes()),
+		E:   encode(big.NewInt(int64(s.key.E)).Bytes()),
+	}}})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+
+	q := r.URL.Query()
+
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	callback := redirectURI.Query()
+	callback.Set("state", q.Get("state"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case q.Get("client_id") != s.ClientID:
+		callback.Set("error", "unauthorized_client")
+	case q.Get("response_type") != "code":
+		callback.Set("error", "unsupported_response_type")
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		callback.Set("error", "invalid_request")
+		callback.Set("error_description", "PKCE with S256 is required")
+	case s.deny:
+		callback.Set("error", "access_denied")
+	default:
+		code := randomHex()
+		s.codes[code] = &grant{
+			user:        s.user,
+			nonce:       q.Get("nonce"),
+			challenge:   q.Get("code_challenge"),
+			redirectURI: q.Get("redirect_uri"),
+		}
+		callback.Set("code", code)
+	}
+
+	redirectURI.RawQuery = callback.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+
+	err := r.ParseForm()
+	if err != nil {
+		tokenError(w, "invalid_request", err.Error())
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	if clientID != s.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.ClientSecret)) != 1 {
+		tokenError(w, "invalid_client", "unknown client or wrong secret")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "")
+		return
+	}
+
+	// Codes are good for one exchange, right or wrong.
+	s.mu.Lock()
+	grant, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	if !ok {
+		tokenError(w, "invalid_grant", "unknown or used code")
+		return
+	}
+
+	if r.PostForm.Get("redirect_uri") != grant.redirectURI {
+		tokenError(w, "invalid_grant", "redirect_uri does not match")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if encode(sum[:]) != grant.challenge {
+		tokenError(w, "invalid_grant", "code_verifier does not match")
+		return
+	}
+
+	now := time.Now()
+
+	claims := jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            grant.user.Subject,
+		"aud":            s.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"email":          grant.user.Email,
+		"email_verified": grant.user.EmailVerified,
+	}
+	if grant.nonce != "" {
+		claims["nonce"] = grant.nonce
+	}
+	for name, value := range map[string]string{
+		"name":               grant.user.Name,
+		"given_name":         grant.user.GivenName,
+		"family_name":        grant.user.FamilyName,
+		"preferred_username": grant.user.PreferredUsername,
+	} {
+		if value != "" {
+			claims[name] = value
+		}
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = keyID
+
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		tokenError(w, "server_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomHex(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+
+	status := http.StatusBadRequest
+	if code == "invalid_client" {
+		status = http.StatusUnauthorized
+	}
+
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomHex() string {
+
+	raw := make([]byte, 16)
+
+	_, err := rand.Read(raw)
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(raw)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,46 @@
+// Package password hashes user passwords with bcrypt.
+package password
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cost is the bcrypt cost of new hashes.
+const Cost = bcrypt.DefaultCost
+
+// Hash returns the bcrypt hash of password, which must not be longer than
+// 72 bytes.
+func Hash(password string) (string, error) {
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Check reports whether password matches hash. Passwords stored before they
+// were hashed are compared as they are; NeedsRehash reports them. An empty
+// hash, as left by erasing a user, matches nothing.
+func Check(hash, password string) bool {
+
+	if hash == "" {
+		return false
+	}
+
+	if NeedsRehash(hash) {
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(password)) == 1
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NeedsRehash reports whether hash is not a bcrypt hash, i.e. a password
+// stored in plain text.
+func NeedsRehash(hash string) bool {
+	return !strings.HasPrefix(hash, "$2a$") && !strings.HasPrefix(hash, "$2b$") && !strings.HasPrefix(hash, "$2y$")
+}
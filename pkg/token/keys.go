@@ -0,0 +1,344 @@
+// Package token issues and verifies the API's access tokens. Tokens are
+// signed with asymmetric keys kept as PEM files in a directory, so other
+// services can verify them with the public keys published as a JWKS.
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Algorithms of the generated keys.
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const rsaKeyBits = 2048
+
+// Key is a signing key loaded from <dir>/<kid>.pem. The file's modification
+// time is when it was created.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	CreatedAt time.Time
+
+	private crypto.Signer
+}
+
+func (k *Key) Public() crypto.PublicKey {
+	return k.private.Public()
+}
+
+type KeySetOptions struct {
+	// Dir holds the private keys as PKCS#8 (or PKCS#1 RSA) PEM files.
+	Dir string
+	// Algorithm is used for the keys the set generates.
+	Algorithm string
+	// Rotation is how often a new key is generated. 0 disables generation
+	// and retirement; the keys in Dir are then managed by hand.
+	Rotation time.Duration
+	// Retention is how long a replaced key is still published, which must
+	// cover the lifetime of the tokens it signed.
+	Retention time.Duration
+}
+
+// KeySet holds the keys tokens are signed and verified with. The newest key
+// signs; every key verifies until it is retired.
+type KeySet struct {
+	opts KeySetOptions
+	log  *slog.Logger
+
+	mu   sync.RWMutex
+	keys []*Key // newest first
+}
+
+// NewKeySet loads the keys in opts.Dir and, with rotation enabled, generates
+// the first one when there is none.
+func NewKeySet(opts KeySetOptions, log *slog.Logger) (*KeySet, error) {
+
+	s := &KeySet{
+		opts: opts,
+		log:  log,
+	}
+
+	err := s.Rotate()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Run reloads the keys every interval until ctx is done, rotating them when
+// due. Keys added by other instances sharing the directory are picked up
+// the same way.
+func (s *KeySet) Run(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.Rotate()
+		if err != nil {
+			s.log.ErrorContext(ctx, "error whiling rotate signing keys", slog.Any("error", err))
+		}
+	}
+}
+
+// Rotate reloads the keys, generates a new one when the newest is older
+// than the rotation period and removes the keys replaced longer than the
+// retention ago. When the directory has no keys left the set keeps the
+// ones it has and Rotate fails.
+func (s *KeySet) Rotate() error {
+
+	keys, err := loadKeys(s.opts.Dir)
+	if err != nil {
+		return err
+	}
+
+	if s.opts.Rotation > 0 {
+
+		if len(keys) == 0 || time.Since(keys[0].CreatedAt) >= s.opts.Rotation {
+
+			key, err := generateKey(s.opts.Dir, s.opts.Algorithm)
+			if err != nil {
+				return err
+			}
+
+			s.log.Info("generated signing key", slog.String("kid", key.ID), slog.String("alg", key.Method.Alg()))
+
+			keys = append([]*Key{key}, keys...)
+		}
+
+		keys = s.retire(keys)
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("no signing keys in %s", s.opts.Dir)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+
+	return nil
+}
+
+// retire removes the keys whose successor has signed for longer than the
+// retention, so no valid token can carry them anymore.
+func (s *KeySet) retire(keys []*Key) []*Key {
+
+	for i := 1; i < len(keys); i++ {
+
+		if time.Since(keys[i-1].CreatedAt) < s.opts.Retention {
+			continue
+		}
+
+		for _, key := range keys[i:] {
+			err := os.Remove(filepath.Join(s.opts.Dir, key.ID+".pem"))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				s.log.Warn("error whiling remove retired signing key", slog.String("kid", key.ID), slog.Any("error", err))
+				continue
+			}
+			s.log.Info("retired signing key", slog.String("kid", key.ID))
+		}
+
+		return keys[:i]
+	}
+
+	return keys
+}
+
+// Signing returns the key new tokens are signed with.
+func (s *KeySet) Signing() (*Key, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.keys) == 0 {
+		return nil, errors.New("no signing key")
+	}
+
+	return s.keys[0], nil
+}
+
+// Lookup returns the key with the kid, if it is still in the set.
+func (s *KeySet) Lookup(kid string) (*Key, bool) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+// Keys returns the keys in the set, newest first.
+func (s *KeySet) Keys() []*Key {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*Key(nil), s.keys...)
+}
+
+func loadKeys(dir string) ([]*Key, error) {
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*Key
+
+	for _, file := range files {
+
+		key, err := loadKey(file)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", file, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID > keys[j].ID
+		}
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+func loadKey(file string) (*Key, error) {
+
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	var private interface{}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := Key{
+		ID:        strings.TrimSuffix(filepath.Base(file), ".pem"),
+		CreatedAt: info.ModTime(),
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.private = jwt.SigningMethodRS256, private
+	case ed25519.PrivateKey:
+		key.Method, key.private = jwt.SigningMethodEdDSA, private
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", private)
+	}
+
+	return &key, nil
+}
+
+func generateKey(dir, algorithm string) (*Key, error) {
+
+	var (
+		private crypto.Signer
+		err     error
+	)
+
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	// Written aside and linked into place, so other instances never load
+	// half a key, nor overwrite one generated in the same second.
+	kid := time.Now().UTC().Format("20060102T150405Z")
+	file := filepath.Join(dir, kid+".pem")
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = pem.Encode(tmp, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	err = os.Link(tmp.Name(), file)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, err
+	}
+
+	return loadKey(file)
+}
@@ -0,0 +1,196 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var discardLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// writeKey stores an Ed25519 key as <dir>/<kid>.pem, created age ago.
+func writeKey(t *testing.T, dir, kid string, age time.Duration) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+
+	file := filepath.Join(dir, kid+".pem")
+
+	err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	created := time.Now().Add(-age)
+
+	err = os.Chtimes(file, created, created)
+	if err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+
+	type key struct {
+		kid string
+		age time.Duration
+	}
+
+	tests := []struct {
+		name      string
+		keys      []key
+		rotation  time.Duration
+		retention time.Duration
+		// want are the kids kept, newest first; "new" is a generated key.
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:     "generates the first key",
+			rotation: 24 * time.Hour,
+			want:     []string{"new"},
+		},
+		{
+			name:      "keeps a key younger than the rotation",
+			keys:      []key{{"a", time.Hour}},
+			rotation:  24 * time.Hour,
+			retention: 48 * time.Hour,
+			want:      []string{"a"},
+		},
+		{
+			name:      "rotates a key older than the rotation",
+			keys:      []key{{"a", 25 * time.Hour}},
+			rotation:  24 * time.Hour,
+			retention: 48 * time.Hour,
+			want:      []string{"new", "a"},
+		},
+		{
+			name:      "keeps a replaced key within the retention",
+			keys:      []key{{"b", time.Hour}, {"a", 30 * time.Hour}},
+			rotation:  24 * time.Hour,
+			retention: 48 * time.Hour,
+			want:      []string{"b", "a"},
+		},
+		{
+			name:      "retires keys replaced longer than the retention ago",
+			keys:      []key{{"c", time.Hour}, {"b", 50 * time.Hour}, {"a", 100 * time.Hour}},
+			rotation:  24 * time.Hour,
+			retention: 48 * time.Hour,
+			want:      []string{"c", "b"},
+		},
+		{
+			name: "leaves keys managed by hand alone",
+			keys: []key{{"b", 100 * time.Hour}, {"a", 200 * time.Hour}},
+			want: []string{"b", "a"},
+		},
+		{
+			name:    "fails without keys to manage by hand",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := t.TempDir()
+			for _, k := range tt.keys {
+				writeKey(t, dir, k.kid, k.age)
+			}
+
+			set, err := NewKeySet(KeySetOptions{
+				Dir:       dir,
+				Algorithm: AlgorithmEdDSA,
+				Rotation:  tt.rotation,
+				Retention: tt.retention,
+			}, discardLog)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewKeySet succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewKeySet: %v", err)
+			}
+
+			var got []string
+			for _, k := range set.Keys() {
+				kid := k.ID
+				if _, err := time.Parse("20060102T150405Z", kid); err == nil {
+					kid = "new"
+				}
+				got = append(got, kid)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("keys = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("keys = %v, want %v", got, tt.want)
+				}
+			}
+
+			files, _ := filepath.Glob(filepath.Join(dir, "*.pem"))
+			if len(files) != len(tt.want) {
+				t.Errorf("key files = %v, want %d", files, len(tt.want))
+			}
+
+			signing, err := set.Signing()
+			if err != nil || signing != set.Keys()[0] {
+				t.Errorf("Signing = %v, %v, want the newest key", signing, err)
+			}
+		})
+	}
+}
+
+func TestRotateKeepsKeysWhenDirEmpties(t *testing.T) {
+
+	dir := t.TempDir()
+	writeKey(t, dir, "a", time.Hour)
+
+	set, err := NewKeySet(KeySetOptions{Dir: dir, Algorithm: AlgorithmEdDSA}, discardLog)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	err = os.Remove(filepath.Join(dir, "a.pem"))
+	if err != nil {
+		t.Fatalf("remove key: %v", err)
+	}
+
+	err = set.Rotate()
+	if err == nil {
+		t.Errorf("Rotate of an empty directory succeeded, want an error")
+	}
+
+	key, err := set.Signing()
+	if err != nil || key.ID != "a" {
+		t.Errorf("Signing after a failed reload = %v, %v, want key a", key, err)
+	}
+}
+
+func TestSigningEmptySet(t *testing.T) {
+
+	var set KeySet
+
+	_, err := set.Signing()
+	if err == nil {
+		t.Errorf("Signing of an empty set succeeded, want an error")
+	}
+}
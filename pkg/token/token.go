@@ -0,0 +1,126 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Token types, carried in the typ claim so a token cannot be used for
+// another purpose than it was issued for.
+const (
+	TypeAccess = "access"
+	// TypeMFA tokens only continue a login with a second factor.
+	TypeMFA = "mfa"
+)
+
+// Roles, carried in the role claim.
+const (
+	RoleClient = "client"
+	RoleSuper  = "super"
+)
+
+// Claims of the tokens the API issues. Subject and UserID both hold the
+// user id; user_id is kept for existing clients.
+type Claims struct {
+	jwt.RegisteredClaims
+	UserID string `json:"user_id"`
+	Type   string `json:"typ"`
+	Role   string `json:"role"`
+}
+
+// Issuer signs tokens with the newest key of a KeySet and verifies them
+// against all of its keys.
+type Issuer struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+}
+
+func NewIssuer(keys *KeySet, issuer, audience string) *Issuer {
+	return &Issuer{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+	}
+}
+
+func (i *Issuer) Keys() *KeySet {
+	return i.keys
+}
+
+// Issue signs claims valid for ttl from now, setting the registered claims
+// other than the ID.
+func (i *Issuer) Issue(claims Claims, ttl time.Duration) (string, error) {
+
+	now := time.Now()
+
+	claims.Issuer = i.issuer
+	claims.Audience = jwt.ClaimStrings{i.audience}
+	claims.Subject = claims.UserID
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+
+	key, err := i.keys.Signing()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.private)
+}
+
+// Parse verifies a token, optionally prefixed with "Bearer ", and checks it
+// was issued by us, for us, as a token of type typ.
+func (i *Issuer) Parse(raw, typ string) (*Claims, error) {
+
+	raw = strings.TrimSpace(raw)
+	if len(raw) > len("Bearer ") && strings.EqualFold(raw[:len("Bearer ")], "Bearer ") {
+		raw = raw[len("Bearer "):]
+	}
+
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+
+		kid, _ := t.Header["kid"].(string)
+
+		key, ok := i.keys.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+
+		// The algorithm comes from the key, never from the token.
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("key %q does not sign with %s", kid, t.Method.Alg())
+		}
+
+		return key.Public(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !claims.VerifyIssuer(i.issuer, true):
+		return nil, errors.New("unexpected issuer")
+	case !claims.VerifyAudience(i.audience, true):
+		return nil, errors.New("unexpected audience")
+	case claims.Type != typ:
+		return nil, fmt.Errorf("unexpected token type %q", claims.Type)
+	case claims.UserID == "" || claims.UserID != claims.Subject:
+		return nil, errors.New("missing subject")
+	case claims.Role != RoleClient && claims.Role != RoleSuper:
+		return nil, fmt.Errorf("unexpected role %q", claims.Role)
+	case claims.ExpiresAt == nil:
+		return nil, errors.New("missing expiry")
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,89 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// newTestIssuer returns an Issuer of a key set with one new key.
+func newTestIssuer(t *testing.T, issuer, audience string) *Issuer {
+	t.Helper()
+
+	keys, err := NewKeySet(KeySetOptions{
+		Dir:       t.TempDir(),
+		Algorithm: AlgorithmEdDSA,
+		Rotation:  time.Hour,
+		Retention: time.Hour,
+	}, discardLog)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	return NewIssuer(keys, issuer, audience)
+}
+
+func TestParse(t *testing.T) {
+
+	issuer := newTestIssuer(t, "book_api", "book_api")
+
+	issue := func(i *Issuer, claims Claims, ttl time.Duration) string {
+		raw, err := i.Issue(claims, ttl)
+		if err != nil {
+			t.Fatalf("Issue: %v", err)
+		}
+		return raw
+	}
+
+	access := Claims{UserID: "user-1", Type: TypeAccess, Role: RoleClient}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Issuer: "book_api", Subject: "user-1", Audience: jwt.ClaimStrings{"book_api"}, ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+		UserID:           "user-1",
+		Type:             TypeAccess,
+		Role:             RoleClient,
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("sign unsigned token: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		typ     string
+		wantErr bool
+	}{
+		{name: "access token", raw: issue(issuer, access, time.Hour), typ: TypeAccess},
+		{name: "bearer prefix", raw: "Bearer " + issue(issuer, access, time.Hour), typ: TypeAccess},
+		{name: "super admin token", raw: issue(issuer, Claims{UserID: "user-1", Type: TypeAccess, Role: RoleSuper}, time.Hour), typ: TypeAccess},
+		{name: "other type", raw: issue(issuer, Claims{UserID: "user-1", Type: TypeMFA, Role: RoleClient}, time.Hour), typ: TypeAccess, wantErr: true},
+		{name: "unknown role", raw: issue(issuer, Claims{UserID: "user-1", Type: TypeAccess, Role: "root"}, time.Hour), typ: TypeAccess, wantErr: true},
+		{name: "no user", raw: issue(issuer, Claims{Type: TypeAccess, Role: RoleClient}, time.Hour), typ: TypeAccess, wantErr: true},
+		{name: "expired", raw: issue(issuer, access, -time.Minute), typ: TypeAccess, wantErr: true},
+		{name: "other issuer", raw: issue(NewIssuer(issuer.Keys(), "other", "book_api"), access, time.Hour), typ: TypeAccess, wantErr: true},
+		{name: "other audience", raw: issue(NewIssuer(issuer.Keys(), "book_api", "other"), access, time.Hour), typ: TypeAccess, wantErr: true},
+		{name: "key of another set", raw: issue(newTestIssuer(t, "book_api", "book_api"), access, time.Hour), typ: TypeAccess, wantErr: true},
+		{name: "unsigned", raw: unsigned, typ: TypeAccess, wantErr: true},
+		{name: "garbage", raw: "not a token", typ: TypeAccess, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			claims, err := issuer.Parse(tt.raw, tt.typ)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse = %+v, want an error", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if claims.UserID != "user-1" || claims.Subject != "user-1" || claims.Type != tt.typ {
+				t.Errorf("Parse = %+v", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Package totp implements RFC 6238 time-based one-time passwords, as shown by
+// authenticator apps, and the recovery codes that replace them when the
+// device is lost.
+package totp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// Period is how long a code is valid. Skew codes before and after the
+// current one are accepted as well, to allow for clock drift.
+const (
+	Period = 30 * time.Second
+	Skew   = 1
+)
+
+var opts = totp.ValidateOpts{
+	Period:    uint(Period / time.Second),
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// Key is a new TOTP secret and the otpauth:// URI authenticator apps enroll
+// it with.
+type Key struct {
+	Secret string
+	URL    string
+}
+
+// Generate creates a secret for the account, labelled issuer in the
+// authenticator app.
+func Generate(issuer, account string) (*Key, error) {
+
+	raw := make([]byte, 20)
+
+	_, err := rand.Read(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+
+	return &Key{
+		Secret: secret,
+		URL:    URL(issuer, account, secret),
+	}, nil
+}
+
+// URL is the otpauth:// URI of a secret, in the format of
+// https://github.com/google/google-authenticator/wiki/Key-Uri-Format.
+func URL(issuer, account, secret string) string {
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", opts.Algorithm.String())
+	query.Set("digits", opts.Digits.String())
+	query.Set("period", strconv.FormatUint(uint64(opts.Period), 10))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
+
+// QR renders the otpauth:// URI as a size x size PNG.
+func QR(url string, size int) ([]byte, error) {
+
+	key, err := otp.NewKeyFromURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(size, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+
+	err = png.Encode(&b, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// Match checks code against the secret at now and returns the time step
+// the code belongs to. Callers reject steps at or before the last accepted
+// one, so a code cannot be replayed.
+func Match(secret, code string, now time.Time) (int64, bool) {
+
+	for skew := -Skew; skew <= Skew; skew++ {
+
+		at := now.Add(time.Duration(skew) * Period)
+
+		expected, err := totp.GenerateCodeCustom(secret, at, opts)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return at.Unix() / int64(opts.Period), true
+		}
+	}
+
+	return 0, false
+}
+
+// RecoveryCodes generates n single-use codes formatted as xxxxx-xxxxx.
+func RecoveryCodes(n int) ([]string, error) {
+
+	codes := make([]string, 0, n)
+
+	for i := 0; i < n; i++ {
+
+		raw := make([]byte, 7)
+
+		_, err := rand.Read(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))[:10]
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode returns the hash recovery codes are stored as. Case,
+// spaces and dashes are ignored.
+func HashRecoveryCode(code string) string {
+
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,131 @@
+package totp
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 test vectors, base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestMatch(t *testing.T) {
+
+	// RFC 6238 appendix B, cut to six digits.
+	at := time.Unix(1111111109, 0)
+	step := at.Unix() / 30
+
+	tests := []struct {
+		name     string
+		code     string
+		now      time.Time
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "RFC 6238 at 59", code: "287082", now: time.Unix(59, 0), wantStep: 1, wantOK: true},
+		{name: "RFC 6238 at 1234567890", code: "005924", now: time.Unix(1234567890, 0), wantStep: 1234567890 / 30, wantOK: true},
+		{name: "current step", code: "081804", now: at, wantStep: step, wantOK: true},
+		{name: "previous step within the skew", code: "081804", now: at.Add(Period), wantStep: step, wantOK: true},
+		{name: "next step within the skew", code: "081804", now: at.Add(-Period), wantStep: step, wantOK: true},
+		{name: "two steps late", code: "081804", now: at.Add(2 * Period), wantOK: false},
+		{name: "two steps early", code: "081804", now: at.Add(-2 * Period), wantOK: false},
+		{name: "wrong code", code: "000000", now: at, wantOK: false},
+		{name: "too short", code: "08180", now: at, wantOK: false},
+		{name: "empty", code: "", now: at, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			step, ok := Match(rfcSecret, tt.code, tt.now)
+			if ok != tt.wantOK {
+				t.Fatalf("Match = %d, %v, want ok %v", step, ok, tt.wantOK)
+			}
+			// The step, not the time of the check, identifies the code, so
+			// the caller can refuse it once it was used.
+			if ok && step != tt.wantStep {
+				t.Errorf("Match step = %d, want %d", step, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestMatchInvalidSecret(t *testing.T) {
+
+	_, ok := Match("not base32!", "123456", time.Now())
+	if ok {
+		t.Errorf("Match with an invalid secret succeeded")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+
+	key, err := Generate("Book API", "samandar")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	parsed, err := otp.NewKeyFromURL(key.URL)
+	if err != nil {
+		t.Fatalf("NewKeyFromURL(%q): %v", key.URL, err)
+	}
+
+	if parsed.Secret() != key.Secret || parsed.Issuer() != "Book API" || parsed.AccountName() != "samandar" || parsed.Period() != 30 {
+		t.Errorf("URL %q does not describe the key", key.URL)
+	}
+
+	_, err = QR(key.URL, 200)
+	if err != nil {
+		t.Errorf("QR: %v", err)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+
+	codes, err := RecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("RecoveryCodes: %v", err)
+	}
+
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := map[string]bool{}
+
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("recovery code %q is not formatted as xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("recovery code %q generated twice", code)
+		}
+		seen[code] = true
+	}
+
+	if len(codes) != 10 {
+		t.Errorf("RecoveryCodes(10) returned %d codes", len(codes))
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+
+	want := HashRecoveryCode("abcde-fghij")
+
+	tests := []struct {
+		code string
+		same bool
+	}{
+		{"abcde-fghij", true},
+		{"ABCDE-FGHIJ", true},
+		{"abcdefghij", true},
+		{" abcde fghij ", true},
+		{"abcde-fghik", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := HashRecoveryCode(tt.code); (got == want) != tt.same {
+			t.Errorf("HashRecoveryCode(%q) matches = %v, want %v", tt.code, got == want, tt.same)
+		}
+	}
+}
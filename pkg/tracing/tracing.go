@@ -0,0 +1,177 @@
+// Package tracing configures OpenTelemetry: the exporter, the global tracer
+// provider and W3C trace context propagation. It also has the helpers the
+// storage backends use to trace their statements.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation name of every span of the service.
+const TracerName = "crud"
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type Options struct {
+	ServiceName string
+	// Exporter is one of none, stdout or otlp.
+	Exporter string
+	// Endpoint is the host:port of the OTLP/HTTP collector.
+	Endpoint string
+	// Insecure sends OTLP over plain HTTP.
+	Insecure bool
+}
+
+// Setup installs the global tracer provider and the W3C traceparent
+// propagator. The returned function flushes pending spans and must be called
+// on shutdown. With ExporterNone only propagation is installed, so incoming
+// trace ids still show up in logs and error responses.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterOTLP:
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint)}
+		if opts.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the service from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// TraceID returns the trace id of the span in ctx, or "" if there is none.
+func TraceID(ctx context.Context) string {
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+
+	return sc.TraceID().String()
+}
+
+// StartQuery starts a client span for a SQL statement. The span is named
+// after the statement, e.g. "SELECT books".
+func StartQuery(ctx context.Context, system attribute.KeyValue, query string) (context.Context, trace.Span) {
+
+	query = strings.Join(strings.Fields(query), " ")
+	operation, table := Statement(query)
+
+	name := operation
+	if table != "" {
+		name += " " + table
+	}
+
+	attrs := []attribute.KeyValue{
+		system,
+		semconv.DBStatement(query),
+		semconv.DBOperation(operation),
+	}
+	if table != "" {
+		attrs = append(attrs, semconv.DBSQLTable(table))
+	}
+
+	return Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Statement returns the operation and the main table of a SQL statement,
+// e.g. ("INSERT", "orders"). table is empty when it cannot be told.
+func Statement(query string) (operation, table string) {
+
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return "", ""
+	}
+
+	operation = strings.ToUpper(words[0])
+
+	after := map[string]string{
+		"SELECT": "FROM",
+		"DELETE": "FROM",
+		"INSERT": "INTO",
+		"UPDATE": "UPDATE",
+	}[operation]
+
+	if after == "" {
+		return operation, ""
+	}
+
+	for i, word := range words {
+		if strings.EqualFold(word, after) && i+1 < len(words) {
+			return operation, strings.Trim(strings.SplitN(words[i+1], "(", 2)[0], `"`)
+		}
+	}
+
+	return operation, ""
+}
@@ -0,0 +1,185 @@
+// Package validation registers the custom binding rules used by the models
+// and turns validator errors into field-level errs.Error values.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"crud/pkg/errs"
+)
+
+// FieldError describes one rejected field. Field is the JSON (or uri) name.
+type FieldError struct {
+	Field   string `json:"field" example:"price"`
+	Rule    string `json:"rule" example:"gte"`
+	Param   string `json:"param,omitempty" example:"0"`
+	Message string `json:"message" example:"must be greater than or equal to 0"`
+}
+
+var phoneRegex = regexp.MustCompile(`^[0-9]{9}$`)
+
+// Register installs the custom rules on gin's validator. It must run before
+// the first request is bound.
+func Register() error {
+
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected binding validator engine")
+	}
+
+	v.RegisterTagNameFunc(fieldName)
+
+	rules := map[string]validator.Func{
+		"phone": func(fl validator.FieldLevel) bool { return phoneRegex.MatchString(fl.Field().String()) },
+		"isbn":  func(fl validator.FieldLevel) bool { return IsISBN(fl.Field().String()) },
+	}
+
+	for tag, fn := range rules {
+		err := v.RegisterValidation(tag, fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsISBN reports whether s is a valid ISBN-10 or ISBN-13. Hyphens and spaces
+// are ignored and the check digit is verified.
+func IsISBN(s string) bool {
+
+	s = strings.NewReplacer("-", "", " ", "").Replace(s)
+
+	switch len(s) {
+	case 10:
+		sum := 0
+		for i, r := range s {
+			var digit int
+			switch {
+			case r >= '0' && r <= '9':
+				digit = int(r - '0')
+			case (r == 'X' || r == 'x') && i == 9:
+				digit = 10
+			default:
+				return false
+			}
+			sum += digit * (10 - i)
+		}
+		return sum%11 == 0
+	case 13:
+		sum := 0
+		for i, r := range s {
+			if r < '0' || r > '9' {
+				return false
+			}
+			weight := 1
+			if i%2 == 1 {
+				weight = 3
+			}
+			sum += int(r-'0') * weight
+		}
+		return sum%10 == 0
+	}
+
+	return false
+}
+
+// Struct validates v against its binding tags, for callers that did not go
+// through gin's binding.
+func Struct(v interface{}) error {
+
+	err := binding.Validator.ValidateStruct(v)
+	if err != nil {
+		return Error(err)
+	}
+
+	return nil
+}
+
+// Error converts an error returned by gin's ShouldBind* into an *errs.Error:
+// VALIDATION_FAILED with a list of FieldError details for rule violations,
+// INVALID_ARGUMENT for malformed input.
+func Error(err error) error {
+
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+
+		fields := make([]FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fields = append(fields, FieldError{
+				Field:   fe.Field(),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: message(fe),
+			})
+		}
+
+		return errs.Validation("request validation failed").WithDetails(fields).Wrap(err)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return errs.Validation("request validation failed").WithDetails([]FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: "must be of type " + typeErr.Type.String(),
+		}}).Wrap(err)
+	}
+
+	return errs.InvalidArgument("malformed request: %v", err).Wrap(err)
+}
+
+func message(fe validator.FieldError) string {
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return "must be at least " + fe.Param() + " characters long"
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.String {
+			return "must be at most " + fe.Param() + " characters long"
+		}
+		return "must be at most " + fe.Param()
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lte":
+		return "must be less than or equal to " + fe.Param()
+	case "uuid":
+		return "must be a UUID"
+	case "phone":
+		return "must be 9 digits, e.g. 997191323"
+	case "isbn":
+		return "must be a valid ISBN-10 or ISBN-13"
+	}
+
+	return "failed the " + fe.Tag() + " rule"
+}
+
+func fieldName(f reflect.StructField) string {
+
+	for _, tag := range []string{"json", "uri", "form"} {
+		name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+
+	return f.Name
+}
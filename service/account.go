@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"crud/config"
+	"crud/models"
+	"crud/pkg/audit"
+	"crud/pkg/errs"
+	"crud/pkg/mail"
+	"crud/pkg/password"
+	"crud/pkg/validation"
+	"crud/storage"
+)
+
+// Purposes of the one-time tokens sent by email.
+const (
+	tokenVerifyEmail   = "verify_email"
+	tokenResetPassword = "reset_password"
+)
+
+// AccountService holds the self-service account flows: registration, email
+// verification and password reset.
+type AccountService struct {
+	cfg     *config.Config
+	log     *slog.Logger
+	storage storage.StorageI
+	cache   storage.CacheI
+	mailer  mail.Mailer
+}
+
+func NewAccountService(cfg *config.Config, log *slog.Logger, storage storage.StorageI, cache storage.CacheI, mailer mail.Mailer) *AccountService {
+	return &AccountService{
+		cfg:     cfg,
+		log:     log,
+		storage: storage,
+		cache:   cache,
+		mailer:  mailer,
+	}
+}
+
+// Register creates a user and sends a link to verify its email. The user
+// can log in once the email is verified.
+func (s *AccountService) Register(ctx context.Context, req *models.Register) (*models.User, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(req.Email)
+
+	hash, err := password.Hash(req.Password)
+	if err != nil {
+		return nil, errs.Internal(err)
+	}
+
+	var user *models.User
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		id, err := tx.User().Create(ctx, &models.CreateUser{
+			First_name:   req.First_name,
+			Last_name:    req.Last_name,
+			Login:        req.Login,
+			Password:     hash,
+			Phone_number: req.Phone_number,
+			Email:        email,
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err = tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionCreate, audit.EntityUser, id, nil, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.cache.User().Delete(ctx)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling cache delete", slog.Any("error", err))
+	}
+
+	// The account exists either way, so a failure is only logged. Resetting
+	// the password with POST /password/forgot verifies the email as well.
+	err = s.send(ctx, tokenVerifyEmail, user.Id, email, time.Duration(s.cfg.EmailVerificationTTL), "/verify-email",
+		"Verify your email",
+		"Welcome to book_api!\n\nOpen the link below to verify your email. It expires in %s.\n\n%s\n",
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "error whiling send verification email", slog.Any("error", err))
+	}
+
+	return user, nil
+}
+
+// VerifyEmail marks the email of the token's user verified.
+func (s *AccountService) VerifyEmail(ctx context.Context, req *models.VerifyEmail) error {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.consume(ctx, tokenVerifyEmail, req.Token)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.patch(ctx, &models.PatchUser{Id: id, EmailVerified: true})
+	if err != nil {
+		return err
+	}
+
+	err = s.cache.User().Delete(ctx)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling cache delete", slog.Any("error", err))
+	}
+
+	return nil
+}
+
+// ForgotPassword sends a password reset link to the user with the email.
+// It succeeds for unknown emails too, so it cannot be used to find out which
+// emails are registered.
+func (s *AccountService) ForgotPassword(ctx context.Context, req *models.ForgotPassword) error {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.storage.User().GetByPKey(ctx, &models.UserPrimarKey{Email: strings.ToLower(req.Email)})
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, tokenResetPassword, user.Id, user.Email, time.Duration(s.cfg.PasswordResetTTL), "/reset-password",
+		"Reset your password",
+		"Open the link below to choose a new password. It expires in %s.\n\n%s\n\nIf you did not ask for it, ignore this email.\n",
+	)
+}
+
+// ResetPassword sets the password of the token's user and logs it out
+// everywhere. Resetting also proves access to the email, so it verifies it
+// and lifts a login lockout.
+func (s *AccountService) ResetPassword(ctx context.Context, req *models.ResetPassword) error {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.consume(ctx, tokenResetPassword, req.Token)
+	if err != nil {
+		return err
+	}
+
+	hash, err := password.Hash(req.Password)
+	if err != nil {
+		return errs.Internal(err)
+	}
+
+	user, err := s.patch(ctx, &models.PatchUser{
+		Id:            id,
+		Password:      &hash,
+		EmailVerified: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.cache.User().Delete(ctx)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling cache delete", slog.Any("error", err))
+	}
+
+	err = revokeSessions(ctx, s.cache, id)
+	if err != nil {
+		s.log.ErrorContext(ctx, "error whiling revoke sessions", slog.Any("error", err))
+	}
+
+	err = s.cache.LoginAttempts().Reset(ctx, user.Login)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling reset login attempts", slog.Any("error", err))
+	}
+
+	return nil
+}
+
+// patch writes req to the token's user and returns it. A user deleted since
+// the token was sent makes the token invalid.
+func (s *AccountService) patch(ctx context.Context, req *models.PatchUser) (*models.User, error) {
+
+	var user *models.User
+
+	err := s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id})
+		if errors.Is(err, errs.ErrNotFound) {
+			return invalidToken()
+		} else if err != nil {
+			return err
+		}
+
+		rowsAffected, err := tx.User().Patch(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return invalidToken()
+		}
+
+		user, err = tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionUpdate, audit.EntityUser, req.Id, current, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// send creates a one-time token for purpose and mails a link with it,
+// PUBLIC_URL + path + ?token=..., to the user.
+func (s *AccountService) send(ctx context.Context, purpose, userID, to string, ttl time.Duration, path, subject, body string) error {
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.cache.Tokens().Create(ctx, purpose, token, userID, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimSuffix(s.cfg.PublicURL, "/") + path + "?token=" + url.QueryEscape(token)
+
+	return s.mailer.Send(ctx, &mail.Message{
+		To:      to,
+		Subject: subject,
+		Body:    fmt.Sprintf(body, ttl, link),
+	})
+}
+
+func (s *AccountService) consume(ctx context.Context, purpose, token string) (string, error) {
+
+	id, err := s.cache.Tokens().Consume(ctx, purpose, token)
+	if errors.Is(err, storage.ErrCacheMiss) {
+		return "", invalidToken()
+	}
+
+	return id, err
+}
+
+func invalidToken() error {
+	return errs.InvalidArgument("invalid or expired token")
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"crud/models"
+	"crud/pkg/audit"
+	"crud/pkg/errs"
+	"crud/pkg/validation"
+	"crud/storage"
+)
+
+const (
+	// apiKeyPrefix starts every key so leaked keys are easy to spot, e.g.
+	// bk_3kq9x2mf_<secret>.
+	apiKeyPrefix = "bk_"
+	// apiKeyTouchInterval is how often the last use of a key is recorded.
+	apiKeyTouchInterval = time.Minute
+)
+
+var apiKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// APIKeyService manages the API keys back-office scripts authenticate with
+// instead of a user's token. Only the hash of a key is stored; the key
+// itself is shown once, when it is created.
+type APIKeyService struct {
+	log     *slog.Logger
+	storage storage.StorageI
+}
+
+func NewAPIKeyService(log *slog.Logger, storage storage.StorageI) *APIKeyService {
+	return &APIKeyService{
+		log:     log,
+		storage: storage,
+	}
+}
+
+func (s *APIKeyService) Create(ctx context.Context, req *models.CreateAPIKey) (*models.CreateAPIKeyResponse, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := randomString(5)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomString(20)
+	if err != nil {
+		return nil, err
+	}
+
+	key := apiKeyPrefix + prefix + "_" + secret
+
+	req.Prefix = prefix
+	req.KeyHash = hashAPIKey(key)
+	req.ExpiresIn = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+
+	var apiKey *models.APIKey
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		id, err := tx.APIKey().Create(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		apiKey, err = tx.APIKey().GetByPKey(ctx, &models.APIKeyPrimarKey{Id: id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionCreate, audit.EntityAPIKey, id, nil, apiKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResponse{
+		APIKey: apiKey,
+		Key:    key,
+	}, nil
+}
+
+func (s *APIKeyService) GetByPKey(ctx context.Context, req *models.APIKeyPrimarKey) (*models.APIKey, error) {
+	return s.storage.APIKey().GetByPKey(ctx, req)
+}
+
+func (s *APIKeyService) GetList(ctx context.Context, req *models.GetListAPIKeyRequest) (*models.GetListAPIKeyResponse, error) {
+	return s.storage.APIKey().GetList(ctx, req)
+}
+
+func (s *APIKeyService) Delete(ctx context.Context, req *models.APIKeyPrimarKey) error {
+
+	return s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.APIKey().GetByPKey(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := tx.APIKey().Delete(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return errs.NotFound("api key not found")
+		}
+
+		return record(ctx, tx, audit.ActionDelete, audit.EntityAPIKey, req.Id, current, nil)
+	})
+}
+
+// Authenticate looks up the API key a request was made with, rejects it if
+// it is unknown or expired, and records its use.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	prefix, _, found := strings.Cut(rest, "_")
+	if !ok || !found {
+		return nil, errs.Unauthorized("invalid api key")
+	}
+
+	apiKey, err := s.storage.APIKey().GetByPKey(ctx, &models.APIKeyPrimarKey{Prefix: prefix})
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.Unauthorized("invalid api key")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(apiKey.KeyHash)) != 1 {
+		return nil, errs.Unauthorized("invalid api key")
+	}
+
+	if apiKey.Expired {
+		return nil, errs.Unauthorized("api key expired")
+	}
+
+	// Tracking the last use is best effort; it must not fail the request.
+	_, err = s.storage.APIKey().Touch(ctx, &models.TouchAPIKey{Id: apiKey.Id, Interval: apiKeyTouchInterval})
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling touching api key", slog.String("api_key_id", apiKey.Id), slog.Any("error", err))
+	}
+
+	return apiKey, nil
+}
+
+// HasScope reports whether the key grants scope.
+func HasScope(apiKey *models.APIKey, scope string) bool {
+
+	for _, s := range apiKey.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// randomString returns n random bytes as lowercase base32.
+func randomString(n int) (string, error) {
+
+	raw := make([]byte, n)
+
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", errs.Internal(err)
+	}
+
+	return strings.ToLower(apiKeyEncoding.EncodeToString(raw)), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
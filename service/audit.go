@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"crud/config"
+	"crud/models"
+	"crud/pkg/audit"
+	"crud/pkg/errs"
+	"crud/storage"
+)
+
+// AuditService reads the audit log and enforces its retention. Entries are
+// written by the other services, with record, in the transaction of the
+// write they describe.
+type AuditService struct {
+	cfg     *config.Config
+	log     *slog.Logger
+	storage storage.StorageI
+}
+
+func NewAuditService(cfg *config.Config, log *slog.Logger, storage storage.StorageI) *AuditService {
+	return &AuditService{
+		cfg:     cfg,
+		log:     log,
+		storage: storage,
+	}
+}
+
+func (s *AuditService) GetList(ctx context.Context, req *models.GetListAuditLogRequest) (*models.GetListAuditLogResponse, error) {
+	return s.storage.Audit().GetList(ctx, req)
+}
+
+// Purge deletes the entries older than AuditRetention. A zero retention
+// keeps them forever.
+func (s *AuditService) Purge(ctx context.Context) (int64, error) {
+
+	if s.cfg.AuditRetention == 0 {
+		return 0, nil
+	}
+
+	return s.storage.Audit().DeleteBefore(ctx, time.Now().Add(-time.Duration(s.cfg.AuditRetention)))
+}
+
+// Run purges the audit log every interval until ctx is done.
+func (s *AuditService) Run(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := s.Purge(ctx)
+		if err != nil {
+			s.log.ErrorContext(ctx, "error whiling purge audit log", slog.Any("error", err))
+			continue
+		}
+
+		if deleted > 0 {
+			s.log.InfoContext(ctx, "purged audit log", slog.Int64("deleted", deleted))
+		}
+	}
+}
+
+// record writes an audit entry for a write made in tx by the actor of ctx.
+// before is nil for a create and after for a delete. Updates that changed
+// nothing are not recorded.
+func record(ctx context.Context, tx storage.StorageI, action, entityType, entityID string, before, after interface{}) error {
+
+	diffBefore, diffAfter, err := audit.Diff(before, after)
+	if err != nil {
+		return errs.Internal(err)
+	}
+
+	if diffBefore == nil && diffAfter == nil {
+		return nil
+	}
+
+	actor := audit.ActorFrom(ctx)
+
+	return tx.Audit().Create(ctx, &models.CreateAuditLog{
+		Actor_id:   actor.UserID,
+		APIKey_id:  actor.APIKeyID,
+		Action:     action,
+		EntityType: entityType,
+		Entity_id:  entityID,
+		Before:     diffBefore,
+		After:      diffAfter,
+		IP:         actor.IP,
+		RequestID:  actor.RequestID,
+	})
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"crud/config"
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/metrics"
+	"crud/pkg/oidc"
+	"crud/pkg/password"
+	"crud/pkg/token"
+	"crud/pkg/validation"
+	"crud/storage"
+
+	"github.com/google/uuid"
+)
+
+// Login kinds, used as the label of the failed logins counter and as the
+// role of the issued token.
+const (
+	LoginClient = token.RoleClient
+	LoginSuper  = token.RoleSuper
+)
+
+type AuthService struct {
+	cfg     *config.Config
+	log     *slog.Logger
+	metrics *metrics.Metrics
+	storage storage.StorageI
+	cache   storage.CacheI
+	tokens  *token.Issuer
+	// oidc is nil unless OIDCIssuer is set.
+	oidc *oidc.Client
+}
+
+func NewAuthService(cfg *config.Config, log *slog.Logger, metrics *metrics.Metrics, storage storage.StorageI, cache storage.CacheI, tokens *token.Issuer) *AuthService {
+
+	s := &AuthService{
+		cfg:     cfg,
+		log:     log,
+		metrics: metrics,
+		storage: storage,
+		cache:   cache,
+		tokens:  tokens,
+	}
+
+	if cfg.OIDCIssuer != "" {
+		s.oidc = oidc.New(oidc.Options{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       strings.Fields(cfg.OIDCScopes),
+		})
+	}
+
+	return s
+}
+
+// Login issues a client token, or an MFA token when the user has TOTP
+// enabled.
+func (s *AuthService) Login(ctx context.Context, req *models.Login) (*models.LoginResponse, error) {
+	return s.login(ctx, req, LoginClient)
+}
+
+// LoginSuper issues a short-lived super admin token, or an MFA token when
+// the user has TOTP enabled. Only admins may log in, and unless
+// LoginSuperRequireMFA is off, only admins with TOTP.
+func (s *AuthService) LoginSuper(ctx context.Context, req *models.Login) (*models.LoginResponse, error) {
+	return s.login(ctx, req, LoginSuper)
+}
+
+func (s *AuthService) login(ctx context.Context, req *models.Login, kind string) (*models.LoginResponse, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkLocked(ctx, req.Login)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.storage.User().GetByPKey(ctx, &models.UserPrimarKey{Login: req.Login})
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, s.failed(ctx, req.Login, kind)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !password.Check(user.Password, req.Password) {
+		return nil, s.failed(ctx, req.Login, kind)
+	}
+
+	if password.NeedsRehash(user.Password) {
+		s.rehash(ctx, user.Id, req.Password)
+	}
+
+	// Users created by an admin have no email to verify.
+	if user.Email != "" && !user.EmailVerified {
+		return nil, errs.Forbidden("email is not verified")
+	}
+
+	mfa, err := s.totpEnabled(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == LoginSuper {
+		err = s.checkSuperLogin(user, mfa)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// With a second factor the failures are only forgotten once the code
+	// is right as well.
+	if mfa {
+		return s.mfaChallenge(user.Id, kind)
+	}
+
+	s.resetAttempts(ctx, req.Login)
+
+	return s.issue(ctx, user.Id, kind, req.UserAgent, req.IP)
+}
+
+// rehash replaces a password stored in plain text, from before passwords
+// were hashed, with its hash. A failure is only logged; the next login tries
+// again.
+func (s *AuthService) rehash(ctx context.Context, userID, plain string) {
+
+	hash, err := password.Hash(plain)
+	if err == nil {
+		_, err = s.storage.User().Patch(ctx, &models.PatchUser{Id: userID, Password: &hash})
+	}
+
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling rehash password", slog.Any("error", err))
+		return
+	}
+
+	// The patch bumped the version the cached list holds.
+	err = s.cache.User().Delete(ctx)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling cache delete", slog.Any("error", err))
+	}
+}
+
+// checkSuperLogin lets only admins log in as super admin and, unless
+// LoginSuperRequireMFA is off, only with TOTP enabled. The admin flag comes
+// first: TOTP on an account that is not an admin grants nothing.
+func (s *AuthService) checkSuperLogin(user *models.User, mfa bool) error {
+
+	if !user.IsAdmin {
+		return errs.Forbidden("super admin login requires an admin account")
+	}
+
+	if !mfa && s.cfg.LoginSuperRequireMFA {
+		return errs.Forbidden("two-factor authentication must be enabled for super admin login, enroll with POST /mfa/totp")
+	}
+
+	return nil
+}
+
+// issue signs an access token of kind and records its session.
+func (s *AuthService) issue(ctx context.Context, userID, kind, userAgent, ip string) (*models.LoginResponse, error) {
+
+	var (
+		jti       = uuid.New().String()
+		now       = time.Now()
+		expiresIn = config.TimeExpiredAt
+	)
+
+	if kind == LoginSuper {
+		expiresIn = config.SuperTimeExpiredAt
+	}
+
+	claims := token.Claims{
+		UserID: userID,
+		Type:   token.TypeAccess,
+		Role:   kind,
+	}
+	claims.ID = jti
+
+	accessToken, err := s.tokens.Issue(claims, expiresIn)
+	if err != nil {
+		return nil, errs.Internal(err)
+	}
+
+	// Without the record the token still works and can be revoked, it is
+	// only missing from the session list.
+	err = s.cache.Session().Create(ctx, &models.Session{
+		Id:        jti,
+		User_id:   userID,
+		Kind:      kind,
+		UserAgent: userAgent,
+		IP:        ip,
+		CreatedAt: now.UTC().Format(time.RFC3339),
+		ExpiresAt: now.Add(expiresIn).UTC().Format(time.RFC3339),
+	}, expiresIn)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling create session", slog.Any("error", err))
+	}
+
+	return &models.LoginResponse{AccessToken: accessToken}, nil
+}
+
+// Unlock lifts the lockout of the user's login and forgets its failures.
+func (s *AuthService) Unlock(ctx context.Context, req *models.UserPrimarKey) error {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.storage.User().GetByPKey(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.LoginAttempts().Reset(ctx, user.Login)
+}
+
+// checkLocked rejects a login locked by too many failures. The lockout is
+// best effort: when the cache is down logins are let through.
+func (s *AuthService) checkLocked(ctx context.Context, login string) error {
+
+	locked, err := s.cache.LoginAttempts().Locked(ctx, login)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling get login lockout", slog.Any("error", err))
+		return nil
+	}
+
+	if locked > 0 {
+		return errs.TooManyRequests(locked, "too many failed logins, try again later")
+	}
+
+	return nil
+}
+
+// failed counts a failed login and rejects it.
+func (s *AuthService) failed(ctx context.Context, login, kind string) error {
+
+	s.countFailure(ctx, login, kind)
+
+	return errs.Unauthorized("login or password is not correct")
+}
+
+// countFailure counts a failed login and locks it once it reaches the
+// threshold. Every further failure doubles the lockout, up to
+// LoginLockoutMax.
+func (s *AuthService) countFailure(ctx context.Context, login, kind string) {
+
+	s.metrics.FailedLogin(kind)
+
+	failures, err := s.cache.LoginAttempts().Fail(ctx, login, time.Duration(s.cfg.LoginFailureWindow))
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling count failed login", slog.Any("error", err))
+		return
+	}
+
+	over := failures - int64(s.cfg.LoginLockoutThreshold)
+	if over < 0 {
+		return
+	}
+
+	lockout := time.Duration(s.cfg.LoginLockoutBase)
+	for ; over > 0 && lockout < time.Duration(s.cfg.LoginLockoutMax); over-- {
+		lockout *= 2
+	}
+	if lockout > time.Duration(s.cfg.LoginLockoutMax) {
+		lockout = time.Duration(s.cfg.LoginLockoutMax)
+	}
+
+	err = s.cache.LoginAttempts().Lock(ctx, login, lockout)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling lock login", slog.Any("error", err))
+	}
+}
+
+func (s *AuthService) resetAttempts(ctx context.Context, login string) {
+
+	err := s.cache.LoginAttempts().Reset(ctx, login)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling reset login attempts", slog.Any("error", err))
+	}
+}
+
+// JWKS returns the public keys tokens can be verified with.
+func (s *AuthService) JWKS() token.JWKS {
+	return s.tokens.Keys().JWKS()
+}
+
+// ParseToken verifies an access token from the Authorization header.
+func (s *AuthService) ParseToken(raw string) (*token.Claims, error) {
+
+	claims, err := s.tokens.Parse(raw, token.TypeAccess)
+	if err != nil {
+		return nil, errs.Unauthorized("invalid or expired token")
+	}
+
+	return claims, nil
+}
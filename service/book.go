@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"crud/models"
+	"crud/pkg/audit"
+	"crud/pkg/errs"
+	"crud/pkg/validation"
+	"crud/storage"
+)
+
+type BookService struct {
+	storage storage.StorageI
+}
+
+func NewBookService(storage storage.StorageI) *BookService {
+	return &BookService{
+		storage: storage,
+	}
+}
+
+func (s *BookService) Create(ctx context.Context, req *models.CreateBook) (*models.Book, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var book *models.Book
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		id, err := tx.Book().Create(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		book, err = tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionCreate, audit.EntityBook, id, nil, book)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+func (s *BookService) GetByPKey(ctx context.Context, req *models.BookPrimarKey) (*models.Book, error) {
+	return s.storage.Book().GetByPKey(ctx, req)
+}
+
+func (s *BookService) GetList(ctx context.Context, req *models.GetListBookRequest) (*models.GetListBookResponse, error) {
+	return s.storage.Book().GetList(ctx, req)
+}
+
+func (s *BookService) Update(ctx context.Context, req *models.UpdateBook) (*models.Book, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var book *models.Book
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := tx.Book().Update(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return s.noRows(ctx, tx, req.Id)
+		}
+
+		book, err = tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionUpdate, audit.EntityBook, req.Id, current, book)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+// Patch applies a merge or JSON patch to the book and writes only the
+// fields it changed.
+func (s *BookService) Patch(ctx context.Context, req *models.PatchRequest) (*models.Book, error) {
+
+	var book *models.Book
+
+	err := s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion("book", req.Version, current.Version)
+		if err != nil {
+			return err
+		}
+
+		var update models.UpdateBook
+
+		err = applyPatch(req, &models.UpdateBook{
+			Id:     current.Id,
+			Title:  current.Title,
+			Author: current.Author,
+			Price:  current.Price,
+			ISBN:   current.ISBN,
+		}, &update)
+		if err != nil {
+			return err
+		}
+
+		var dirty bool
+
+		patch := models.PatchBook{
+			Id:      current.Id,
+			Version: current.Version,
+			Title:   changed(current.Title, update.Title, &dirty),
+			Author:  changed(current.Author, update.Author, &dirty),
+			Price:   changed(current.Price, update.Price, &dirty),
+			ISBN:    changed(current.ISBN, update.ISBN, &dirty),
+		}
+
+		if !dirty {
+			book = current
+			return nil
+		}
+
+		rowsAffected, err := tx.Book().Patch(ctx, &patch)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return s.noRows(ctx, tx, req.Id)
+		}
+
+		book, err = tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionUpdate, audit.EntityBook, req.Id, current, book)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+// Delete marks the book deleted, which Restore undoes until the purge job
+// removes it for good. Deleting a missing book succeeds unless a version was
+// expected.
+func (s *BookService) Delete(ctx context.Context, req *models.BookPrimarKey) error {
+
+	return s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: req.Id})
+		if errors.Is(err, errs.ErrNotFound) && req.Version == 0 {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		rowsAffected, err := tx.Book().Delete(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return s.noRows(ctx, tx, req.Id)
+		}
+
+		return record(ctx, tx, audit.ActionDelete, audit.EntityBook, req.Id, current, nil)
+	})
+}
+
+// Restore undeletes the book. A book that is not deleted is returned as it
+// is.
+func (s *BookService) Restore(ctx context.Context, req *models.BookPrimarKey) (*models.Book, error) {
+
+	var book *models.Book
+
+	err := s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: req.Id, IncludeDeleted: true})
+		if err != nil {
+			return err
+		}
+
+		if current.DeletedAt == "" {
+			book = current
+			return nil
+		}
+
+		_, err = tx.Book().Restore(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		book, err = tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionRestore, audit.EntityBook, req.Id, current, book)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+// noRows explains a write that affected no rows: the book is missing, or it
+// no longer has the version the write expected.
+func (s *BookService) noRows(ctx context.Context, tx storage.StorageI, id string) error {
+
+	book, err := tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return preconditionFailed("book", book.Version)
+}
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/validation"
+	"crud/storage"
+)
+
+var discardLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// TestMain registers the validators the API registers on start up.
+func TestMain(m *testing.M) {
+
+	err := validation.Register()
+	if err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakeStore is an in-memory storage.StorageI with the methods the service
+// tests use; the embedded interfaces make any other call panic. WithTx
+// restores a snapshot when fn fails, like a rollback.
+type fakeStore struct {
+	storage.StorageI
+	data *fakeData
+	// failAudit, when set, is returned by audit writes.
+	failAudit error
+}
+
+type fakeData struct {
+	books  map[string]models.Book
+	users  map[string]models.User
+	orders map[string]models.Order
+	// identities are keyed by issuer and subject.
+	identities map[models.IdentityPrimarKey]models.Identity
+	audit      []models.CreateAuditLog
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{data: &fakeData{
+		books:      map[string]models.Book{},
+		users:      map[string]models.User{},
+		orders:     map[string]models.Order{},
+		identities: map[models.IdentityPrimarKey]models.Identity{},
+	}}
+}
+
+func (d *fakeData) clone() *fakeData {
+
+	c := &fakeData{
+		books:      map[string]models.Book{},
+		users:      map[string]models.User{},
+		orders:     map[string]models.Order{},
+		identities: map[models.IdentityPrimarKey]models.Identity{},
+		audit:      append([]models.CreateAuditLog(nil), d.audit...),
+	}
+
+	for k, v := range d.books {
+		c.books[k] = v
+	}
+	for k, v := range d.users {
+		c.users[k] = v
+	}
+	for k, v := range d.orders {
+		c.orders[k] = v
+	}
+	for k, v := range d.identities {
+		c.identities[k] = v
+	}
+
+	return c
+}
+
+func (s *fakeStore) WithTx(ctx context.Context, fn func(tx storage.StorageI) error) error {
+
+	saved := s.data.clone()
+
+	err := fn(s)
+	if err != nil {
+		*s.data = *saved
+	}
+
+	return err
+}
+
+func (s *fakeStore) Book() storage.BookRepoI   { return fakeBooks{s: s} }
+func (s *fakeStore) User() storage.UserRepoI   { return fakeUsers{s: s} }
+func (s *fakeStore) Order() storage.OrderRepoI { return fakeOrders{s: s} }
+func (s *fakeStore) Audit() storage.AuditRepoI { return fakeAudit{s: s} }
+func (s *fakeStore) TOTP() storage.TOTPRepoI   { return fakeTOTP{} }
+
+func (s *fakeStore) Identity() storage.IdentityRepoI { return fakeIdentities{s: s} }
+
+func (s *fakeStore) addBook(price float64) string {
+
+	id := uuid.New().String()
+	s.data.books[id] = models.Book{Id: id, Title: "book", Price: price, Version: 1}
+
+	return id
+}
+
+func (s *fakeStore) addUser(login string, balance float64) string {
+
+	id := uuid.New().String()
+	s.data.users[id] = models.User{Id: id, First_name: "a", Last_name: "b", Login: login, Password: "secret", Phone_number: "997191323", Balance: balance, Version: 1}
+
+	return id
+}
+
+func (s *fakeStore) balance(id string) float64 {
+	return s.data.users[id].Balance
+}
+
+type fakeBooks struct {
+	storage.BookRepoI
+	s *fakeStore
+}
+
+func (r fakeBooks) GetByPKey(ctx context.Context, req *models.BookPrimarKey) (*models.Book, error) {
+
+	book, ok := r.s.data.books[req.Id]
+	if !ok {
+		return nil, errs.NotFound("book not found")
+	}
+
+	return &book, nil
+}
+
+type fakeUsers struct {
+	storage.UserRepoI
+	s *fakeStore
+}
+
+func (r fakeUsers) Create(ctx context.Context, req *models.CreateUser) (string, error) {
+
+	id := uuid.New().String()
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	r.s.data.users[id] = models.User{Id: id, First_name: req.First_name, Last_name: req.Last_name, Login: req.Login, Password: req.Password, Phone_number: req.Phone_number, Email: req.Email, CreatedAt: now, UpdatedAt: now, Version: 1}
+
+	return id, nil
+}
+
+func (r fakeUsers) GetByPKey(ctx context.Context, req *models.UserPrimarKey) (*models.User, error) {
+
+	for _, user := range r.s.data.users {
+
+		if req.Id != "" && user.Id != req.Id || req.Login != "" && user.Login != req.Login || req.Email != "" && user.Email != req.Email {
+			continue
+		}
+		if user.DeletedAt != "" && !req.IncludeDeleted {
+			continue
+		}
+
+		return &user, nil
+	}
+
+	return nil, errs.NotFound("user not found")
+}
+
+func (r fakeUsers) Update(ctx context.Context, req *models.UpdateUser) (int64, error) {
+
+	user, ok := r.s.data.users[req.Id]
+	if !ok || user.DeletedAt != "" || req.Version > 0 && req.Version != user.Version {
+		return 0, nil
+	}
+
+	user.First_name = req.First_name
+	user.Last_name = req.Last_name
+	user.Login = req.Login
+	user.Password = req.Password
+	user.Phone_number = req.Phone_number
+	user.Balance = req.Balance
+	user.Version++
+
+	r.s.data.users[req.Id] = user
+
+	return 1, nil
+}
+
+func (r fakeUsers) Patch(ctx context.Context, req *models.PatchUser) (int64, error) {
+
+	user, ok := r.s.data.users[req.Id]
+	if !ok || user.DeletedAt != "" || req.Version > 0 && req.Version != user.Version {
+		return 0, nil
+	}
+
+	set := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	set(&user.First_name, req.First_name)
+	set(&user.Last_name, req.Last_name)
+	set(&user.Login, req.Login)
+	set(&user.Password, req.Password)
+	set(&user.Phone_number, req.Phone_number)
+	if req.Balance != nil {
+		user.Balance = *req.Balance
+	}
+	if req.EmailVerified {
+		user.EmailVerified = true
+	}
+	user.Version++
+
+	r.s.data.users[req.Id] = user
+
+	return 1, nil
+}
+
+func (r fakeUsers) UpdateBalance(ctx context.Context, req *models.UpdateBalance) (int64, error) {
+
+	user, ok := r.s.data.users[req.Id]
+	if !ok || user.Balance+req.Amount < 0 {
+		return 0, nil
+	}
+
+	user.Balance += req.Amount
+	user.Version++
+
+	r.s.data.users[req.Id] = user
+
+	return 1, nil
+}
+
+type fakeOrders struct {
+	storage.OrderRepoI
+	s *fakeStore
+}
+
+func (r fakeOrders) Create(ctx context.Context, req *models.CreateOrder) (string, error) {
+
+	id := uuid.New().String()
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	r.s.data.orders[id] = models.Order{Id: id, User_id: req.User_id, Book_id: req.Book_id, Payed: req.Payed, CreatedAt: now, UpdatedAt: now, Version: 1}
+
+	return id, nil
+}
+
+func (r fakeOrders) GetByPKey(ctx context.Context, req *models.OrderPrimarKey) (*models.Order, error) {
+
+	order, ok := r.s.data.orders[req.Id]
+	if !ok {
+		return nil, errs.NotFound("order not found")
+	}
+
+	return &order, nil
+}
+
+func (r fakeOrders) Update(ctx context.Context, req *models.UpdateOrder) (int64, error) {
+
+	order, ok := r.s.data.orders[req.Id]
+	if !ok || req.Version > 0 && req.Version != order.Version {
+		return 0, nil
+	}
+
+	order.User_id = req.User_id
+	order.Book_id = req.Book_id
+	order.Payed = req.Payed
+	order.Version++
+
+	r.s.data.orders[req.Id] = order
+
+	return 1, nil
+}
+
+// fakeTOTP has no enrollments.
+type fakeTOTP struct {
+	storage.TOTPRepoI
+}
+
+func (fakeTOTP) GetByPKey(ctx context.Context, req *models.TOTPPrimarKey) (*models.TOTP, error) {
+	return nil, errs.NotFound("totp not found")
+}
+
+type fakeIdentities struct {
+	storage.IdentityRepoI
+	s *fakeStore
+}
+
+func (r fakeIdentities) Create(ctx context.Context, req *models.CreateIdentity) error {
+
+	key := models.IdentityPrimarKey{Issuer: req.Issuer, Subject: req.Subject}
+	if _, ok := r.s.data.identities[key]; ok {
+		return errs.Conflict("identity already linked")
+	}
+
+	r.s.data.identities[key] = models.Identity{Issuer: req.Issuer, Subject: req.Subject, User_id: req.User_id, Email: req.Email}
+
+	return nil
+}
+
+func (r fakeIdentities) GetByPKey(ctx context.Context, req *models.IdentityPrimarKey) (*models.Identity, error) {
+
+	identity, ok := r.s.data.identities[*req]
+	if !ok {
+		return nil, errs.NotFound("identity not found")
+	}
+
+	return &identity, nil
+}
+
+type fakeAudit struct {
+	storage.AuditRepoI
+	s *fakeStore
+}
+
+func (r fakeAudit) Create(ctx context.Context, req *models.CreateAuditLog) error {
+
+	if r.s.failAudit != nil {
+		return r.s.failAudit
+	}
+
+	r.s.data.audit = append(r.s.data.audit, *req)
+
+	return nil
+}
+
+// fakeCache is a storage.CacheI that caches nothing, records the users
+// whose sessions were revoked and the sessions created, and keeps one-time
+// tokens, by purpose and token, in tokens.
+type fakeCache struct {
+	storage.CacheI
+	revoked  []string
+	sessions []models.Session
+	tokens   map[string]string
+}
+
+func (c *fakeCache) User() storage.UserCacheI           { return fakeUserCache{} }
+func (c *fakeCache) Order() storage.OrderCacheI         { return fakeOrderCache{} }
+func (c *fakeCache) Session() storage.SessionCacheI     { return fakeSessions{c: c} }
+func (c *fakeCache) Tokens() storage.OneTimeTokenCacheI { return fakeTokens{c: c} }
+
+type fakeUserCache struct {
+	storage.UserCacheI
+}
+
+func (fakeUserCache) Delete(ctx context.Context) error {
+	return nil
+}
+
+type fakeOrderCache struct {
+	storage.OrderCacheI
+}
+
+func (fakeOrderCache) Delete(ctx context.Context) error {
+	return nil
+}
+
+type fakeSessions struct {
+	storage.SessionCacheI
+	c *fakeCache
+}
+
+func (s fakeSessions) RevokeAll(ctx context.Context, userID string, before time.Time, ttl time.Duration) error {
+
+	s.c.revoked = append(s.c.revoked, userID)
+
+	return nil
+}
+
+func (s fakeSessions) Create(ctx context.Context, req *models.Session, ttl time.Duration) error {
+
+	s.c.sessions = append(s.c.sessions, *req)
+
+	return nil
+}
+
+type fakeTokens struct {
+	c *fakeCache
+}
+
+func (t fakeTokens) Create(ctx context.Context, purpose, token, userID string, ttl time.Duration) error {
+
+	if t.c.tokens == nil {
+		t.c.tokens = map[string]string{}
+	}
+	t.c.tokens[purpose+":"+token] = userID
+
+	return nil
+}
+
+func (t fakeTokens) Consume(ctx context.Context, purpose, token string) (string, error) {
+
+	userID, ok := t.c.tokens[purpose+":"+token]
+	if !ok {
+		return "", storage.ErrCacheMiss
+	}
+	delete(t.c.tokens, purpose+":"+token)
+
+	return userID, nil
+}
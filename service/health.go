@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"crud/config"
+	"crud/models"
+	"crud/storage"
+)
+
+type HealthService struct {
+	cfg     *config.Config
+	storage storage.StorageI
+	cache   storage.CacheI
+}
+
+func NewHealthService(cfg *config.Config, storage storage.StorageI, cache storage.CacheI) *HealthService {
+	return &HealthService{
+		cfg:     cfg,
+		storage: storage,
+		cache:   cache,
+	}
+}
+
+// Ready checks the database, the cache and the schema version. The service
+// is ready only when every check passes.
+func (s *HealthService) Ready(ctx context.Context) *models.Readiness {
+
+	resp := &models.Readiness{
+		Status: models.HealthOK,
+		Checks: map[string]models.HealthCheck{},
+	}
+
+	add := func(name string, check models.HealthCheck) {
+		resp.Checks[name] = check
+		if check.Status != models.HealthOK {
+			resp.Status = models.HealthFail
+		}
+	}
+
+	add(s.cfg.StorageDriver, result("", s.storage.Ping(ctx)))
+	add("redis", result("", s.cache.Ping(ctx)))
+
+	current, latest, err := s.storage.SchemaVersion(ctx)
+	if err == nil && current != latest {
+		err = fmt.Errorf("schema version %d does not match the expected version %d", current, latest)
+	}
+	add("schema", result(fmt.Sprintf("schema version %d (latest %d)", current, latest), err))
+
+	return resp
+}
+
+func result(message string, err error) models.HealthCheck {
+
+	if err != nil {
+		return models.HealthCheck{Status: models.HealthFail, Message: message, Error: err.Error()}
+	}
+
+	return models.HealthCheck{Status: models.HealthOK, Message: message}
+}
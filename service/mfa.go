@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/token"
+	"crud/pkg/totp"
+	"crud/pkg/validation"
+	"crud/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// recoveryCodes is how many recovery codes a user gets.
+	recoveryCodes = 10
+	// qrSize is the width and height of the enrollment QR code in pixels.
+	qrSize = 256
+)
+
+// LoginMFA finishes a login of a user with TOTP enabled. The MFA token from
+// the first step is good for one login; wrong codes count as failed logins.
+func (s *AuthService) LoginMFA(ctx context.Context, req *models.LoginMFA) (*models.LoginResponse, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.tokens.Parse(req.MFAToken, token.TypeMFA)
+	if err != nil {
+		return nil, errs.Unauthorized("invalid or expired mfa token")
+	}
+
+	mfaToken := &models.Token{
+		Id:        claims.ID,
+		User_id:   claims.UserID,
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+
+	err = s.CheckToken(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.storage.User().GetByPKey(ctx, &models.UserPrimarKey{Id: claims.UserID})
+	if err != nil {
+		return nil, err
+	}
+
+	// The flag may have been revoked since the first step. TOTP was
+	// enabled then, and the code is checked below.
+	if claims.Role == LoginSuper {
+		err = s.checkSuperLogin(user, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.checkLocked(ctx, user.Login)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.secondFactor(ctx, user.Id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		s.countFailure(ctx, user.Login, claims.Role)
+		return nil, errs.Unauthorized("two-factor code is not correct")
+	}
+
+	err = s.cache.Session().Revoke(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	s.resetAttempts(ctx, user.Login)
+
+	return s.issue(ctx, user.Id, claims.Role, req.UserAgent, req.IP)
+}
+
+// GetTOTP reports whether the user has TOTP enabled and how many recovery
+// codes are left.
+func (s *AuthService) GetTOTP(ctx context.Context, req *models.TOTPPrimarKey) (*models.TOTP, error) {
+
+	mfa, err := s.storage.TOTP().GetByPKey(ctx, req)
+	if errors.Is(err, errs.ErrNotFound) {
+		return &models.TOTP{User_id: req.User_id}, nil
+	}
+
+	return mfa, err
+}
+
+// EnrollTOTP creates a secret for the user. It takes effect once confirmed
+// with a code; until then it can be replaced by enrolling again.
+func (s *AuthService) EnrollTOTP(ctx context.Context, req *models.TOTPPrimarKey) (*models.TOTPEnrollResponse, error) {
+
+	user, err := s.storage.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.User_id})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := totp.Generate(s.cfg.TOTPIssuer, user.Login)
+	if err != nil {
+		return nil, errs.Internal(err)
+	}
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		mfa, err := tx.TOTP().GetByPKey(ctx, req)
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+
+		if mfa != nil && mfa.Enabled {
+			return errs.Conflict("two-factor authentication is already enabled")
+		}
+
+		return tx.TOTP().Create(ctx, &models.CreateTOTP{User_id: req.User_id, Secret: key.Secret})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPEnrollResponse{
+		Secret: key.Secret,
+		URL:    key.URL,
+	}, nil
+}
+
+// TOTPQR renders the pending secret of the user as a PNG QR code. Enabled
+// secrets are not shown again.
+func (s *AuthService) TOTPQR(ctx context.Context, req *models.TOTPPrimarKey) ([]byte, error) {
+
+	mfa, err := s.pendingTOTP(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.storage.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.User_id})
+	if err != nil {
+		return nil, err
+	}
+
+	png, err := totp.QR(totp.URL(s.cfg.TOTPIssuer, user.Login, mfa.Secret), qrSize)
+	if err != nil {
+		return nil, errs.Internal(err)
+	}
+
+	return png, nil
+}
+
+// ConfirmTOTP enables the pending secret with a code from the app and
+// returns the recovery codes, which are not shown again.
+func (s *AuthService) ConfirmTOTP(ctx context.Context, req *models.TOTPPrimarKey, code *models.TOTPCode) (*models.RecoveryCodesResponse, error) {
+
+	err := validation.Struct(code)
+	if err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.pendingTOTP(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkCode(ctx, mfa, code.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		rowsAffected, err := tx.TOTP().Enable(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return errs.Conflict("two-factor authentication is already enabled")
+		}
+
+		codes, err = s.setRecoveryCodes(ctx, tx, req.User_id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, e.g.
+// after most of them were used.
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, req *models.TOTPPrimarKey, code *models.TOTPCode) (*models.RecoveryCodesResponse, error) {
+
+	err := validation.Struct(code)
+	if err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.enabledTOTP(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkCode(ctx, mfa, code.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+		codes, err = s.setRecoveryCodes(ctx, tx, req.User_id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP removes the secret and the recovery codes of the user.
+func (s *AuthService) DisableTOTP(ctx context.Context, req *models.TOTPPrimarKey, code *models.TOTPCode) error {
+
+	err := validation.Struct(code)
+	if err != nil {
+		return err
+	}
+
+	mfa, err := s.enabledTOTP(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	err = s.checkCode(ctx, mfa, code.Code)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.storage.TOTP().Delete(ctx, req)
+	return err
+}
+
+// mfaChallenge answers the first step of a login with TOTP.
+func (s *AuthService) mfaChallenge(userID, kind string) (*models.LoginResponse, error) {
+
+	claims := token.Claims{
+		UserID: userID,
+		Type:   token.TypeMFA,
+		Role:   kind,
+	}
+	claims.ID = uuid.New().String()
+
+	mfaToken, err := s.tokens.Issue(claims, time.Duration(s.cfg.MFATokenTTL))
+	if err != nil {
+		return nil, errs.Internal(err)
+	}
+
+	return &models.LoginResponse{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+	}, nil
+}
+
+// secondFactor checks the TOTP or recovery code of a login.
+func (s *AuthService) secondFactor(ctx context.Context, userID string, req *models.LoginMFA) (bool, error) {
+
+	if req.Code == "" {
+		rowsAffected, err := s.storage.TOTP().UseRecoveryCode(ctx, &models.UseRecoveryCode{
+			User_id:  userID,
+			CodeHash: totp.HashRecoveryCode(req.RecoveryCode),
+		})
+
+		return rowsAffected == 1, err
+	}
+
+	mfa, err := s.enabledTOTP(ctx, &models.TOTPPrimarKey{User_id: userID})
+	if err != nil {
+		return false, err
+	}
+
+	return s.useCode(ctx, mfa, req.Code)
+}
+
+// checkCode rejects a wrong or replayed code.
+func (s *AuthService) checkCode(ctx context.Context, mfa *models.TOTP, code string) error {
+
+	ok, err := s.useCode(ctx, mfa, code)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errs.InvalidArgument("two-factor code is not correct")
+	}
+
+	return nil
+}
+
+// useCode accepts a code of the secret unless it, or a later one, was
+// accepted before.
+func (s *AuthService) useCode(ctx context.Context, mfa *models.TOTP, code string) (bool, error) {
+
+	step, ok := totp.Match(mfa.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	rowsAffected, err := s.storage.TOTP().UseStep(ctx, &models.UseTOTPStep{User_id: mfa.User_id, Step: step})
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (s *AuthService) setRecoveryCodes(ctx context.Context, tx storage.StorageI, userID string) ([]string, error) {
+
+	codes, err := totp.RecoveryCodes(recoveryCodes)
+	if err != nil {
+		return nil, errs.Internal(err)
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, totp.HashRecoveryCode(code))
+	}
+
+	err = tx.TOTP().SetRecoveryCodes(ctx, &models.SetRecoveryCodes{User_id: userID, CodeHashes: hashes})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *AuthService) totpEnabled(ctx context.Context, userID string) (bool, error) {
+
+	mfa, err := s.GetTOTP(ctx, &models.TOTPPrimarKey{User_id: userID})
+	if err != nil {
+		return false, err
+	}
+
+	return mfa.Enabled, nil
+}
+
+func (s *AuthService) pendingTOTP(ctx context.Context, req *models.TOTPPrimarKey) (*models.TOTP, error) {
+
+	mfa, err := s.storage.TOTP().GetByPKey(ctx, req)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.NotFound("no two-factor enrollment in progress, start one with POST /mfa/totp")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa.Enabled {
+		return nil, errs.Conflict("two-factor authentication is already enabled")
+	}
+
+	return mfa, nil
+}
+
+func (s *AuthService) enabledTOTP(ctx context.Context, req *models.TOTPPrimarKey) (*models.TOTP, error) {
+
+	mfa, err := s.storage.TOTP().GetByPKey(ctx, req)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return nil, err
+	}
+
+	if mfa == nil || !mfa.Enabled {
+		return nil, errs.NotFound("two-factor authentication is not enabled")
+	}
+
+	return mfa, nil
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"crud/models"
+	"crud/pkg/audit"
+	"crud/pkg/errs"
+	"crud/pkg/oidc"
+	"crud/pkg/password"
+	"crud/pkg/validation"
+	"crud/storage"
+)
+
+// tokenOIDCState is the purpose of the one-time token that carries the
+// state of a login at the identity provider.
+const tokenOIDCState = "oidc_state"
+
+// OIDCAuthURL starts a login at the identity provider and returns the URL
+// to send the user to. The state, nonce and PKCE verifier of the login are
+// kept for OIDCStateTTL, and the state can be used once.
+func (s *AuthService) OIDCAuthURL(ctx context.Context) (string, error) {
+
+	if s.oidc == nil {
+		return "", errs.NotFound("oidc login is not enabled")
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	verifier := oidc.GenerateVerifier()
+
+	err = s.cache.Tokens().Create(ctx, tokenOIDCState, state, nonce+" "+verifier, time.Duration(s.cfg.OIDCStateTTL))
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := s.oidc.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", errs.Internal(err)
+	}
+
+	return authURL, nil
+}
+
+// LoginOIDC finishes a login at the identity provider. The user is found by
+// the provider account, then by its verified email, and is created on the
+// first login otherwise. Users with TOTP enabled still get an MFA token.
+func (s *AuthService) LoginOIDC(ctx context.Context, req *models.LoginOIDC) (*models.LoginResponse, error) {
+
+	if s.oidc == nil {
+		return nil, errs.NotFound("oidc login is not enabled")
+	}
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := s.cache.Tokens().Consume(ctx, tokenOIDCState, req.State)
+	if errors.Is(err, storage.ErrCacheMiss) {
+		return nil, errs.Unauthorized("invalid or expired state")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if req.Error != "" {
+		return nil, errs.Unauthorized("identity provider refused the login: %s", req.Error)
+	}
+
+	nonce, verifier, _ := strings.Cut(value, " ")
+
+	identity, err := s.oidc.Exchange(ctx, req.Code, nonce, verifier)
+	if errors.Is(err, oidc.ErrVerification) {
+		return nil, errs.Unauthorized("identity provider login failed").Wrap(err)
+	} else if err != nil {
+		return nil, errs.Internal(err)
+	}
+
+	user, err := s.oidcUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.totpEnabled(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa {
+		return s.mfaChallenge(user.Id, LoginClient)
+	}
+
+	return s.issue(ctx, user.Id, LoginClient, req.UserAgent, req.IP)
+}
+
+// oidcUser returns the user linked to the provider account, linking or
+// creating one by the account's email the first time.
+func (s *AuthService) oidcUser(ctx context.Context, identity *oidc.Identity) (*models.User, error) {
+
+	linked, err := s.storage.Identity().GetByPKey(ctx, &models.IdentityPrimarKey{Issuer: identity.Issuer, Subject: identity.Subject})
+	if err == nil {
+		return s.storage.User().GetByPKey(ctx, &models.UserPrimarKey{Id: linked.User_id})
+	} else if !errors.Is(err, errs.ErrNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errs.Forbidden("identity provider did not return a verified email")
+	}
+
+	email := strings.ToLower(identity.Email)
+
+	user, err := s.storage.User().GetByPKey(ctx, &models.UserPrimarKey{Email: email})
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return nil, err
+	}
+
+	// Whoever registered an unverified email might not own it, and would
+	// keep their password to the account.
+	if user != nil && !user.EmailVerified {
+		return nil, errs.Conflict("an account with this email exists but the email is not verified; verify it or reset the password first")
+	}
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		if user == nil {
+			user, err = s.provision(ctx, tx, identity, email)
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Identity().Create(ctx, &models.CreateIdentity{
+			Issuer:  identity.Issuer,
+			Subject: identity.Subject,
+			User_id: user.Id,
+			Email:   email,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.cache.User().Delete(ctx)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling cache delete", slog.Any("error", err))
+	}
+
+	return user, nil
+}
+
+// provision creates the user of a provider account with a verified email.
+// Its password is random; the user can set one with POST /password/forgot.
+func (s *AuthService) provision(ctx context.Context, tx storage.StorageI, identity *oidc.Identity, email string) (*models.User, error) {
+
+	login, err := s.availableLogin(ctx, tx, identity, email)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := password.Hash(secret)
+	if err != nil {
+		return nil, errs.Internal(err)
+	}
+
+	firstName, lastName := identity.GivenName, identity.FamilyName
+	if firstName == "" {
+		firstName, lastName, _ = strings.Cut(identity.Name, " ")
+	}
+	if firstName == "" {
+		firstName = login
+	}
+
+	id, err := tx.User().Create(ctx, &models.CreateUser{
+		First_name: truncate(firstName, 45),
+		Last_name:  truncate(lastName, 45),
+		Login:      login,
+		Password:   hash,
+		Email:      email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.User().Patch(ctx, &models.PatchUser{Id: id, EmailVerified: true})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: id})
+	if err != nil {
+		return nil, err
+	}
+
+	err = record(ctx, tx, audit.ActionCreate, audit.EntityUser, id, nil, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// availableLogin derives a free login from the preferred username or the
+// email of the provider account, adding a number when it is taken.
+func (s *AuthService) availableLogin(ctx context.Context, tx storage.StorageI, identity *oidc.Identity, email string) (string, error) {
+
+	base := identity.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+
+	base = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, base)
+	base = truncate(base, 58)
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	login := base
+	for i := 2; i <= 100; i++ {
+
+		_, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Login: login})
+		if errors.Is(err, errs.ErrNotFound) {
+			return login, nil
+		} else if err != nil {
+			return "", err
+		}
+
+		login = fmt.Sprintf("%s%d", base, i)
+	}
+
+	return "", errs.Conflict("no login derived from %q is free", base)
+}
+
+// randomToken returns 32 random bytes, base64url encoded.
+func randomToken() (string, error) {
+
+	raw := make([]byte, 32)
+
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", errs.Internal(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// truncate cuts s to at most n runes.
+func truncate(s string, n int) string {
+
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"crud/config"
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/oidc/oidctest"
+	"crud/pkg/token"
+)
+
+// newOIDCService returns an AuthService that signs in through a local
+// provider.
+func newOIDCService(t *testing.T, store *fakeStore, cache *fakeCache) (*AuthService, *oidctest.Server) {
+
+	provider, err := oidctest.NewServer("crud", "crud-secret")
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(provider.Close)
+
+	keys, err := token.NewKeySet(token.KeySetOptions{
+		Dir:       t.TempDir(),
+		Algorithm: "EdDSA",
+		Rotation:  time.Hour,
+		Retention: time.Hour,
+	}, discardLog)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	cfg := &config.Config{
+		OIDCIssuer:       provider.URL,
+		OIDCClientID:     provider.ClientID,
+		OIDCClientSecret: provider.ClientSecret,
+		OIDCRedirectURL:  "http://localhost/v1/login/oidc/callback",
+		OIDCScopes:       "email profile",
+		OIDCStateTTL:     config.Duration(time.Minute),
+	}
+
+	return NewAuthService(cfg, discardLog, nil, store, cache, token.NewIssuer(keys, "crud", "crud")), provider
+}
+
+// loginOIDC signs in at the provider and returns the callback request the
+// provider sends the user back with.
+func loginOIDC(t *testing.T, s *AuthService, provider *oidctest.Server) *models.LoginOIDC {
+
+	authURL, err := s.OIDCAuthURL(context.Background())
+	if err != nil {
+		t.Fatalf("OIDCAuthURL: %v", err)
+	}
+
+	callback, err := provider.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	u, err := url.Parse(callback)
+	if err != nil {
+		t.Fatalf("parse callback: %v", err)
+	}
+	q := u.Query()
+
+	return &models.LoginOIDC{Code: q.Get("code"), State: q.Get("state"), Error: q.Get("error")}
+}
+
+func TestLoginOIDCProvisions(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	cache := &fakeCache{}
+	s, provider := newOIDCService(t, store, cache)
+
+	provider.SetUser(oidctest.User{Subject: "sub-1", Email: "Samandar@Example.com", EmailVerified: true, GivenName: "Samandar", FamilyName: "Aliev", PreferredUsername: "samandar"})
+
+	resp, err := s.LoginOIDC(ctx, loginOIDC(t, s, provider))
+	if err != nil {
+		t.Fatalf("LoginOIDC: %v", err)
+	}
+	if resp.AccessToken == "" {
+		t.Errorf("LoginOIDC returned no access token")
+	}
+
+	if len(store.data.users) != 1 {
+		t.Fatalf("users = %+v, want the provisioned one", store.data.users)
+	}
+	var user models.User
+	for _, u := range store.data.users {
+		user = u
+	}
+	if user.Login != "samandar" || user.Email != "samandar@example.com" || !user.EmailVerified || user.First_name != "Samandar" || user.Last_name != "Aliev" {
+		t.Errorf("provisioned user = %+v", user)
+	}
+
+	identity, ok := store.data.identities[models.IdentityPrimarKey{Issuer: provider.URL, Subject: "sub-1"}]
+	if !ok || identity.User_id != user.Id {
+		t.Errorf("identity = %+v, want one linked to %s", identity, user.Id)
+	}
+	if len(cache.sessions) != 1 || cache.sessions[0].User_id != user.Id {
+		t.Errorf("sessions = %+v, want one for the user", cache.sessions)
+	}
+
+	// The next login finds the user by the linked identity, even when the
+	// provider no longer returns a verified email.
+	provider.SetUser(oidctest.User{Subject: "sub-1"})
+
+	_, err = s.LoginOIDC(ctx, loginOIDC(t, s, provider))
+	if err != nil {
+		t.Fatalf("LoginOIDC again: %v", err)
+	}
+	if len(store.data.users) != 1 || len(cache.sessions) != 2 || cache.sessions[1].User_id != user.Id {
+		t.Errorf("second login: users = %d, sessions = %+v", len(store.data.users), cache.sessions)
+	}
+}
+
+func TestLoginOIDCLinksVerifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	cache := &fakeCache{}
+	s, provider := newOIDCService(t, store, cache)
+
+	id := store.addUser("samandar", 0)
+	user := store.data.users[id]
+	user.Email, user.EmailVerified = "samandar@example.com", true
+	store.data.users[id] = user
+
+	provider.SetUser(oidctest.User{Subject: "sub-1", Email: "samandar@example.com", EmailVerified: true})
+
+	_, err := s.LoginOIDC(ctx, loginOIDC(t, s, provider))
+	if err != nil {
+		t.Fatalf("LoginOIDC: %v", err)
+	}
+
+	if len(store.data.users) != 1 {
+		t.Errorf("users = %d, want the existing user only", len(store.data.users))
+	}
+	identity, ok := store.data.identities[models.IdentityPrimarKey{Issuer: provider.URL, Subject: "sub-1"}]
+	if !ok || identity.User_id != id {
+		t.Errorf("identity = %+v, want one linked to %s", identity, id)
+	}
+	if len(cache.sessions) != 1 || cache.sessions[0].User_id != id {
+		t.Errorf("sessions = %+v, want one for the existing user", cache.sessions)
+	}
+}
+
+func TestLoginOIDCUnverifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	cache := &fakeCache{}
+	s, provider := newOIDCService(t, store, cache)
+
+	id := store.addUser("samandar", 0)
+	user := store.data.users[id]
+	user.Email = "samandar@example.com"
+	store.data.users[id] = user
+
+	provider.SetUser(oidctest.User{Subject: "sub-1", Email: "samandar@example.com", EmailVerified: true})
+
+	_, err := s.LoginOIDC(ctx, loginOIDC(t, s, provider))
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("LoginOIDC with the email of an unverified user = %v, want conflict", err)
+	}
+
+	// Nor is an email the provider did not verify trusted.
+	provider.SetUser(oidctest.User{Subject: "sub-2", Email: "new@example.com"})
+
+	_, err = s.LoginOIDC(ctx, loginOIDC(t, s, provider))
+	if !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("LoginOIDC with an unverified email = %v, want forbidden", err)
+	}
+
+	if len(store.data.users) != 1 || len(store.data.identities) != 0 || len(cache.sessions) != 0 {
+		t.Errorf("users = %d, identities = %d, sessions = %d, want nothing created", len(store.data.users), len(store.data.identities), len(cache.sessions))
+	}
+}
+
+func TestLoginOIDCDenied(t *testing.T) {
+	store := newFakeStore()
+	cache := &fakeCache{}
+	s, provider := newOIDCService(t, store, cache)
+
+	provider.SetDeny(true)
+
+	req := loginOIDC(t, s, provider)
+	if req.Error != "access_denied" || req.Code != "" {
+		t.Fatalf("callback = %+v, want access_denied", req)
+	}
+
+	_, err := s.LoginOIDC(context.Background(), req)
+	if !errors.Is(err, errs.ErrUnauthorized) {
+		t.Errorf("LoginOIDC after a denial = %v, want unauthorized", err)
+	}
+	if len(store.data.users) != 0 || len(cache.sessions) != 0 {
+		t.Errorf("users = %d, sessions = %d, want none", len(store.data.users), len(cache.sessions))
+	}
+}
+
+func TestLoginOIDCState(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	cache := &fakeCache{}
+	s, provider := newOIDCService(t, store, cache)
+
+	provider.SetUser(oidctest.User{Subject: "sub-1", Email: "samandar@example.com", EmailVerified: true})
+
+	req := loginOIDC(t, s, provider)
+	state := req.State
+	req.State = "unknown"
+
+	_, err := s.LoginOIDC(ctx, req)
+	if !errors.Is(err, errs.ErrUnauthorized) {
+		t.Errorf("LoginOIDC with an unknown state = %v, want unauthorized", err)
+	}
+
+	req.State = state
+	_, err = s.LoginOIDC(ctx, req)
+	if err != nil {
+		t.Fatalf("LoginOIDC: %v", err)
+	}
+
+	_, err = s.LoginOIDC(ctx, req)
+	if !errors.Is(err, errs.ErrUnauthorized) {
+		t.Errorf("LoginOIDC with a used state = %v, want unauthorized", err)
+	}
+}
+
+func TestLoginOIDCNonce(t *testing.T) {
+	store := newFakeStore()
+	cache := &fakeCache{}
+	s, provider := newOIDCService(t, store, cache)
+
+	provider.SetUser(oidctest.User{Subject: "sub-1", Email: "samandar@example.com", EmailVerified: true})
+
+	req := loginOIDC(t, s, provider)
+
+	// The ID token carries the nonce of the login; swap the one kept with
+	// the state as if the code came from another login.
+	key := tokenOIDCState + ":" + req.State
+	_, verifier, _ := strings.Cut(cache.tokens[key], " ")
+	cache.tokens[key] = "other-nonce " + verifier
+
+	_, err := s.LoginOIDC(context.Background(), req)
+	if !errors.Is(err, errs.ErrUnauthorized) {
+		t.Errorf("LoginOIDC with another nonce = %v, want unauthorized", err)
+	}
+	if len(store.data.users) != 0 || len(cache.sessions) != 0 {
+		t.Errorf("users = %d, sessions = %d, want none", len(store.data.users), len(cache.sessions))
+	}
+}
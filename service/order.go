@@ -0,0 +1,386 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"crud/models"
+	"crud/pkg/audit"
+	"crud/pkg/errs"
+	"crud/pkg/metrics"
+	"crud/pkg/validation"
+	"crud/storage"
+)
+
+type OrderService struct {
+	log     *slog.Logger
+	metrics *metrics.Metrics
+	storage storage.StorageI
+	cache   storage.CacheI
+}
+
+func NewOrderService(log *slog.Logger, metrics *metrics.Metrics, storage storage.StorageI, cache storage.CacheI) *OrderService {
+	return &OrderService{
+		log:     log,
+		metrics: metrics,
+		storage: storage,
+		cache:   cache,
+	}
+}
+
+// Create charges the book price to the user's balance and records the order
+// in one transaction.
+func (s *OrderService) Create(ctx context.Context, req *models.CreateOrder) (*models.Order, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var order *models.Order
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		book, err := tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: req.Book_id})
+		if err != nil {
+			return err
+		}
+
+		err = charge(ctx, tx, req.User_id, book.Price)
+		if err != nil {
+			return err
+		}
+
+		req.Payed = book.Price
+
+		id, err := tx.Order().Create(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		order, err = tx.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionCreate, audit.EntityOrder, id, nil, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.metrics.OrderCreated(req.Payed)
+
+	s.invalidate(ctx)
+
+	return order, nil
+}
+
+func (s *OrderService) GetByPKey(ctx context.Context, req *models.OrderPrimarKey) (*models.Order, error) {
+	return s.storage.Order().GetByPKey(ctx, req)
+}
+
+// GetList serves the unpaged list from the cache and fills it on a miss.
+// Paged requests, those of one user and those including deleted orders always
+// go to storage.
+func (s *OrderService) GetList(ctx context.Context, req *models.GetListOrderRequest) (*models.GetListOrderResponse, error) {
+
+	cacheable := req.Limit == 0 && req.Offset == 0 && !req.IncludeDeleted && req.User_id == ""
+
+	if cacheable {
+		orders, err := s.cache.Order().GetList(ctx)
+		if err == nil {
+			return orders, nil
+		}
+		if !errors.Is(err, storage.ErrCacheMiss) {
+			s.log.WarnContext(ctx, "error whiling get list cache", slog.Any("error", err))
+		}
+	}
+
+	resp, err := s.storage.Order().GetList(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		err = s.cache.Order().Create(ctx, resp)
+		if err != nil {
+			s.log.WarnContext(ctx, "error whiling create cache list", slog.Any("error", err))
+		}
+	}
+
+	return resp, nil
+}
+
+// Update moves the order to another user and/or book. The old payment is
+// refunded and the current price of the new book is charged.
+func (s *OrderService) Update(ctx context.Context, req *models.UpdateOrder) (*models.Order, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *models.Order
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		order, err := tx.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion("order", req.Version, order.Version)
+		if err != nil {
+			return err
+		}
+
+		book, err := tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: req.Book_id})
+		if err != nil {
+			return err
+		}
+
+		err = refund(ctx, tx, order.User_id, order.Payed)
+		if err != nil {
+			return err
+		}
+
+		err = charge(ctx, tx, req.User_id, book.Price)
+		if err != nil {
+			return err
+		}
+
+		req.Payed = book.Price
+		// The refund above is based on the order as read, so the write must
+		// not succeed if the order changed since.
+		req.Version = order.Version
+
+		rowsAffected, err := tx.Order().Update(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return s.noRows(ctx, tx, req.Id)
+		}
+
+		updated, err = tx.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionUpdate, audit.EntityOrder, req.Id, order, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx)
+
+	return updated, nil
+}
+
+// Patch applies a merge or JSON patch to the user and book of the order.
+// When either changes the order is repriced as in Update; the payed amount
+// can not be patched.
+func (s *OrderService) Patch(ctx context.Context, req *models.PatchRequest) (*models.Order, error) {
+
+	order, err := s.storage.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: req.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkVersion("order", req.Version, order.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	var update models.UpdateOrder
+
+	err = applyPatch(req, &models.UpdateOrder{
+		Id:      order.Id,
+		User_id: order.User_id,
+		Book_id: order.Book_id,
+		Payed:   order.Payed,
+	}, &update)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.User_id == order.User_id && update.Book_id == order.Book_id {
+		return order, nil
+	}
+
+	update.Id = order.Id
+	update.Version = order.Version
+
+	return s.Update(ctx, &update)
+}
+
+// Delete marks the order deleted, which Restore undoes until the purge job
+// removes it for good. Deleting a missing order succeeds unless a version was
+// expected.
+func (s *OrderService) Delete(ctx context.Context, req *models.OrderPrimarKey) error {
+
+	err := s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: req.Id})
+		if errors.Is(err, errs.ErrNotFound) && req.Version == 0 {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		rowsAffected, err := tx.Order().Delete(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return s.noRows(ctx, tx, req.Id)
+		}
+
+		return record(ctx, tx, audit.ActionDelete, audit.EntityOrder, req.Id, current, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidate(ctx)
+
+	return nil
+}
+
+// Restore undeletes the order, whose user and book must not be deleted. An
+// order that is not deleted is returned as it is. The payment stays as it
+// was: deleting an order does not refund it.
+func (s *OrderService) Restore(ctx context.Context, req *models.OrderPrimarKey) (*models.Order, error) {
+
+	var (
+		order    *models.Order
+		restored bool
+	)
+
+	err := s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: req.Id, IncludeDeleted: true})
+		if err != nil {
+			return err
+		}
+
+		if current.DeletedAt == "" {
+			order = current
+			return nil
+		}
+
+		_, err = tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: current.User_id})
+		if errors.Is(err, errs.ErrNotFound) {
+			return errs.Conflict("the user of the order is deleted; restore it first")
+		} else if err != nil {
+			return err
+		}
+
+		_, err = tx.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: current.Book_id})
+		if errors.Is(err, errs.ErrNotFound) {
+			return errs.Conflict("the book of the order is deleted; restore it first")
+		} else if err != nil {
+			return err
+		}
+
+		_, err = tx.Order().Restore(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		restored = true
+
+		order, err = tx.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionRestore, audit.EntityOrder, req.Id, current, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if restored {
+		s.invalidate(ctx)
+	}
+
+	return order, nil
+}
+
+// noRows explains a write that affected no rows: the order is missing, or
+// it no longer has the version the write expected.
+func (s *OrderService) noRows(ctx context.Context, tx storage.StorageI, id string) error {
+
+	order, err := tx.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return preconditionFailed("order", order.Version)
+}
+
+func (s *OrderService) invalidate(ctx context.Context) {
+
+	err := s.cache.Order().Delete(ctx)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling cache delete", slog.Any("error", err))
+	}
+}
+
+// charge takes price from the user's balance inside tx.
+func charge(ctx context.Context, tx storage.StorageI, userId string, price float64) error {
+
+	user, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: userId})
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := tx.User().UpdateBalance(ctx, &models.UpdateBalance{Id: userId, Amount: -price})
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errs.InsufficientFunds("balance %.2f is less than the price %.2f", user.Balance, price).
+			WithDetails(map[string]float64{"balance": user.Balance, "price": price})
+	}
+
+	return recordBalance(ctx, tx, user)
+}
+
+// refund gives amount back to the user's balance inside tx. Deleted users
+// are refunded as well; a purged one has no balance left to refund to.
+func refund(ctx context.Context, tx storage.StorageI, userId string, amount float64) error {
+
+	user, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: userId, IncludeDeleted: true})
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	_, err = tx.User().UpdateBalance(ctx, &models.UpdateBalance{Id: userId, Amount: amount})
+	if err != nil {
+		return err
+	}
+
+	return recordBalance(ctx, tx, user)
+}
+
+// recordBalance records the change of the balance of before in the audit
+// log, which makes the balance history of the user.
+func recordBalance(ctx context.Context, tx storage.StorageI, before *models.User) error {
+
+	after, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: before.Id, IncludeDeleted: true})
+	if err != nil {
+		return err
+	}
+
+	return record(ctx, tx, audit.ActionUpdate, audit.EntityUser, before.Id, before, after)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/metrics"
+)
+
+func newOrderService() (*OrderService, *fakeStore) {
+	store := newFakeStore()
+	return NewOrderService(discardLog, metrics.New(), store, &fakeCache{}), store
+}
+
+func TestOrderCreateCharges(t *testing.T) {
+	ctx := context.Background()
+	s, store := newOrderService()
+
+	user := store.addUser("samandar", 100)
+	book := store.addBook(30)
+
+	order, err := s.Create(ctx, &models.CreateOrder{User_id: user, Book_id: book})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if order.Payed != 30 {
+		t.Errorf("Create payed = %v, want 30", order.Payed)
+	}
+	if balance := store.balance(user); balance != 70 {
+		t.Errorf("balance = %v, want 70", balance)
+	}
+	if len(store.data.audit) != 2 {
+		t.Errorf("audit entries = %d, want the charge and the order", len(store.data.audit))
+	}
+}
+
+func TestOrderCreateInsufficientBalance(t *testing.T) {
+	ctx := context.Background()
+	s, store := newOrderService()
+
+	user := store.addUser("samandar", 10)
+	book := store.addBook(30)
+
+	_, err := s.Create(ctx, &models.CreateOrder{User_id: user, Book_id: book})
+	if !errors.Is(err, errs.ErrInsufficientFunds) {
+		t.Fatalf("Create error = %v, want INSUFFICIENT_FUNDS", err)
+	}
+	if balance := store.balance(user); balance != 10 {
+		t.Errorf("balance = %v, want 10", balance)
+	}
+	if len(store.data.orders) != 0 || len(store.data.audit) != 0 {
+		t.Errorf("orders = %d, audit entries = %d, want none", len(store.data.orders), len(store.data.audit))
+	}
+}
+
+func TestOrderCreateRollsBack(t *testing.T) {
+	ctx := context.Background()
+	s, store := newOrderService()
+
+	user := store.addUser("samandar", 100)
+	book := store.addBook(30)
+	store.failAudit = errors.New("audit log is down")
+
+	_, err := s.Create(ctx, &models.CreateOrder{User_id: user, Book_id: book})
+	if err == nil {
+		t.Fatal("Create succeeded without an audit log")
+	}
+	if balance := store.balance(user); balance != 100 {
+		t.Errorf("balance = %v, want the charge rolled back to 100", balance)
+	}
+	if len(store.data.orders) != 0 {
+		t.Errorf("orders = %d, want the order rolled back", len(store.data.orders))
+	}
+}
+
+func TestOrderUpdateRefunds(t *testing.T) {
+	ctx := context.Background()
+	s, store := newOrderService()
+
+	first := store.addUser("samandar", 100)
+	second := store.addUser("samandevop", 100)
+	cheap := store.addBook(30)
+	dear := store.addBook(50)
+
+	order, err := s.Create(ctx, &models.CreateOrder{User_id: first, Book_id: cheap})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	order, err = s.Update(ctx, &models.UpdateOrder{Id: order.Id, User_id: first, Book_id: dear})
+	if err != nil {
+		t.Fatalf("Update book: %v", err)
+	}
+	if order.Payed != 50 {
+		t.Errorf("Update book payed = %v, want 50", order.Payed)
+	}
+	if balance := store.balance(first); balance != 50 {
+		t.Errorf("balance after a new book = %v, want 50", balance)
+	}
+
+	order, err = s.Update(ctx, &models.UpdateOrder{Id: order.Id, User_id: second, Book_id: cheap})
+	if err != nil {
+		t.Fatalf("Update user: %v", err)
+	}
+	if order.User_id != second || order.Payed != 30 {
+		t.Errorf("Update user = %+v", order)
+	}
+	if balance := store.balance(first); balance != 100 {
+		t.Errorf("balance of the old user = %v, want 100", balance)
+	}
+	if balance := store.balance(second); balance != 70 {
+		t.Errorf("balance of the new user = %v, want 70", balance)
+	}
+}
+
+func TestOrderUpdateRollsBackRefund(t *testing.T) {
+	ctx := context.Background()
+	s, store := newOrderService()
+
+	first := store.addUser("samandar", 100)
+	second := store.addUser("samandevop", 10)
+	book := store.addBook(30)
+
+	order, err := s.Create(ctx, &models.CreateOrder{User_id: first, Book_id: book})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	_, err = s.Update(ctx, &models.UpdateOrder{Id: order.Id, User_id: second, Book_id: book})
+	if !errors.Is(err, errs.ErrInsufficientFunds) {
+		t.Fatalf("Update error = %v, want INSUFFICIENT_FUNDS", err)
+	}
+	if balance := store.balance(first); balance != 70 {
+		t.Errorf("balance of the old user = %v, want the refund rolled back to 70", balance)
+	}
+	if balance := store.balance(second); balance != 10 {
+		t.Errorf("balance of the new user = %v, want 10", balance)
+	}
+	if current := store.data.orders[order.Id]; current != *order {
+		t.Errorf("order = %+v, want it unchanged %+v", current, *order)
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/validation"
+)
+
+// applyPatch applies the patch document of req to the JSON form of current
+// and decodes the result into out, which is then validated. Fields that out
+// does not know are rejected.
+func applyPatch(req *models.PatchRequest, current, out interface{}) error {
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return errs.Internal(err)
+	}
+
+	switch req.ContentType {
+	case models.MergePatchType, "application/json", "":
+		doc, err = jsonpatch.MergePatch(doc, req.Document)
+		if err != nil {
+			return errs.InvalidArgument("malformed merge patch: %v", err).Wrap(err)
+		}
+	case models.JSONPatchType:
+		patch, err := jsonpatch.DecodePatch(req.Document)
+		if err != nil {
+			return errs.InvalidArgument("malformed json patch: %v", err).Wrap(err)
+		}
+
+		doc, err = patch.Apply(doc)
+		if err != nil {
+			return errs.Validation("json patch can not be applied: %v", err).Wrap(err)
+		}
+	default:
+		return errs.UnsupportedMedia("content type %q is not supported, use %s or %s",
+			req.ContentType, models.MergePatchType, models.JSONPatchType)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(out)
+	if err != nil {
+		return validation.Error(err)
+	}
+
+	return validation.Struct(out)
+}
+
+// changed returns &new when it differs from old and nil otherwise, and
+// records the difference in dirty.
+func changed[T comparable](old, new T, dirty *bool) *T {
+
+	if old == new {
+		return nil
+	}
+
+	*dirty = true
+
+	return &new
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"crud/models"
+	"crud/pkg/audit"
+	"crud/pkg/errs"
+	"crud/storage"
+)
+
+// Placeholders that replace the names of an erased user, so order reports
+// still have something to show.
+const (
+	erasedFirstName = "Erased"
+	erasedLastName  = "User"
+)
+
+// Export returns a zip archive of the data held about the user, deleted or
+// not: its profile, orders, linked identity provider accounts and balance
+// history, each as JSON and CSV. There are no reviews to export; the API
+// does not have them. The password hash is a credential rather than data about
+// the user and is left out.
+func (s *UserService) Export(ctx context.Context, req *models.UserPrimarKey) ([]byte, error) {
+
+	user, err := s.storage.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id, IncludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := s.storage.Order().GetList(ctx, &models.GetListOrderRequest{User_id: req.Id, IncludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := s.storage.Identity().GetList(ctx, &models.GetListIdentityRequest{User_id: req.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := s.ledger(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := newArchive()
+
+	archive.json("user.json", user)
+	archive.csv("user.csv", []string{"user_id", "first_name", "last_name", "login", "phone_number", "email", "email_verified", "balance", "created_at", "updated_at", "deleted_at", "erased_at"}, [][]string{{
+		user.Id,
+		user.First_name,
+		user.Last_name,
+		user.Login,
+		user.Phone_number,
+		user.Email,
+		strconv.FormatBool(user.EmailVerified),
+		strconv.FormatFloat(user.Balance, 'f', -1, 64),
+		user.CreatedAt,
+		user.UpdatedAt,
+		user.DeletedAt,
+		user.ErasedAt,
+	}})
+
+	archive.json("orders.json", orders)
+	rows := make([][]string, 0, len(orders.Orders))
+	for _, order := range orders.Orders {
+		rows = append(rows, []string{
+			order.Id,
+			order.Book_id,
+			order.Title,
+			strconv.FormatFloat(order.Payed, 'f', -1, 64),
+			order.CreatedAt,
+			order.UpdatedAt,
+			order.DeletedAt,
+		})
+	}
+	archive.csv("orders.csv", []string{"order_id", "book_id", "title", "payed", "created_at", "updated_at", "deleted_at"}, rows)
+
+	archive.json("identities.json", identities)
+	rows = make([][]string, 0, len(identities.Identities))
+	for _, identity := range identities.Identities {
+		rows = append(rows, []string{
+			identity.Issuer,
+			identity.Subject,
+			identity.Email,
+			identity.CreatedAt,
+		})
+	}
+	archive.csv("identities.csv", []string{"issuer", "subject", "email", "created_at"}, rows)
+
+	archive.json("ledger.json", ledger)
+	rows = make([][]string, 0, len(ledger))
+	for _, change := range ledger {
+		rows = append(rows, []string{
+			change.CreatedAt,
+			change.Action,
+			strconv.FormatFloat(change.Before, 'f', -1, 64),
+			strconv.FormatFloat(change.After, 'f', -1, 64),
+			strconv.FormatFloat(change.Amount, 'f', -1, 64),
+			change.Actor_id,
+			change.RequestID,
+		})
+	}
+	archive.csv("ledger.csv", []string{"created_at", "action", "balance_before", "balance_after", "amount", "actor_id", "request_id"}, rows)
+
+	return archive.bytes()
+}
+
+// ledger returns the balance history of the user, oldest first, from the
+// audit entries of the user that changed its balance. It goes back as far
+// as the audit log is retained.
+func (s *UserService) ledger(ctx context.Context, id string) ([]*models.BalanceChange, error) {
+
+	entries, err := s.storage.Audit().GetList(ctx, &models.GetListAuditLogRequest{
+		EntityType: audit.EntityUser,
+		Entity_id:  id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ledger := []*models.BalanceChange{}
+
+	for i := len(entries.AuditLogs) - 1; i >= 0; i-- {
+		entry := entries.AuditLogs[i]
+
+		var before, after struct {
+			Balance *float64 `json:"balance"`
+		}
+
+		if len(entry.Before) > 0 {
+			err = json.Unmarshal(entry.Before, &before)
+			if err != nil {
+				return nil, errs.Internal(err)
+			}
+		}
+
+		if len(entry.After) > 0 {
+			err = json.Unmarshal(entry.After, &after)
+			if err != nil {
+				return nil, errs.Internal(err)
+			}
+		}
+
+		if after.Balance == nil {
+			continue
+		}
+
+		change := &models.BalanceChange{
+			Action:    entry.Action,
+			After:     *after.Balance,
+			Actor_id:  entry.Actor_id,
+			RequestID: entry.RequestID,
+			CreatedAt: entry.CreatedAt,
+		}
+		if before.Balance != nil {
+			change.Before = *before.Balance
+		}
+		change.Amount = change.After - change.Before
+
+		ledger = append(ledger, change)
+	}
+
+	return ledger, nil
+}
+
+// Erase replaces the personal data of the user, deleted or not, with
+// placeholders. The row stays, so its orders and balance remain valid for
+// accounting. Its TOTP, recovery codes and identity provider links are
+// dropped, its audit entries lose their values and IPs, and its sessions are
+// revoked. An erased user is returned as it is.
+func (s *UserService) Erase(ctx context.Context, req *models.UserPrimarKey) (*models.User, error) {
+
+	var (
+		user   *models.User
+		erased bool
+	)
+
+	err := s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id, IncludeDeleted: true})
+		if err != nil {
+			return err
+		}
+
+		if current.ErasedAt != "" {
+			user = current
+			return nil
+		}
+
+		_, err = tx.User().Erase(ctx, &models.EraseUser{
+			Id:         req.Id,
+			First_name: erasedFirstName,
+			Last_name:  erasedLastName,
+			Login:      "erased-" + req.Id,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TOTP().Delete(ctx, &models.TOTPPrimarKey{User_id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Identity().Unlink(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		erased = true
+
+		user, err = tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id, IncludeDeleted: true})
+		if err != nil {
+			return err
+		}
+
+		err = record(ctx, tx, audit.ActionErase, audit.EntityUser, req.Id, current, user)
+		if err != nil {
+			return err
+		}
+
+		// The entry of the erase is redacted as well; it only keeps that
+		// the user was erased, and by whom.
+		_, err = tx.Audit().Redact(ctx, &models.RedactAuditLog{
+			EntityType: audit.EntityUser,
+			Entity_id:  req.Id,
+			Actor_id:   req.Id,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if erased {
+		s.invalidate(ctx)
+
+		// The order list shows the names of the users.
+		err = s.cache.Order().Delete(ctx)
+		if err != nil {
+			s.log.WarnContext(ctx, "error whiling cache delete", slog.Any("error", err))
+		}
+
+		s.revokeSessions(ctx, req.Id)
+	}
+
+	return user, nil
+}
+
+// archive writes files to a zip archive. The first error is kept and
+// returned by bytes.
+type archive struct {
+	buf bytes.Buffer
+	zw  *zip.Writer
+	now time.Time
+	err error
+}
+
+func newArchive() *archive {
+
+	a := &archive{now: time.Now()}
+	a.zw = zip.NewWriter(&a.buf)
+
+	return a
+}
+
+func (a *archive) json(name string, v interface{}) {
+
+	w := a.create(name)
+	if a.err != nil {
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	a.err = enc.Encode(v)
+}
+
+func (a *archive) csv(name string, header []string, rows [][]string) {
+
+	w := a.create(name)
+	if a.err != nil {
+		return
+	}
+
+	cw := csv.NewWriter(w)
+
+	err := cw.Write(header)
+	if err == nil {
+		err = cw.WriteAll(rows)
+	}
+
+	a.err = err
+}
+
+func (a *archive) create(name string) io.Writer {
+
+	if a.err != nil {
+		return nil
+	}
+
+	var w io.Writer
+
+	w, a.err = a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: a.now})
+
+	return w
+}
+
+func (a *archive) bytes() ([]byte, error) {
+
+	if a.err == nil {
+		a.err = a.zw.Close()
+	}
+
+	if a.err != nil {
+		return nil, errs.Internal(a.err)
+	}
+
+	return a.buf.Bytes(), nil
+}
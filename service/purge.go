@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"crud/config"
+	"crud/storage"
+)
+
+// PurgeService removes the books, users and orders deleted longer than
+// DeletedRetention ago for good. Books and users still referenced by an
+// order are kept until the order is purged.
+type PurgeService struct {
+	cfg     *config.Config
+	log     *slog.Logger
+	storage storage.StorageI
+}
+
+func NewPurgeService(cfg *config.Config, log *slog.Logger, storage storage.StorageI) *PurgeService {
+	return &PurgeService{
+		cfg:     cfg,
+		log:     log,
+		storage: storage,
+	}
+}
+
+// Purge removes the deleted rows past retention, orders first so that the
+// books and users they reference can go in the same run. A zero retention
+// keeps them forever.
+func (s *PurgeService) Purge(ctx context.Context) error {
+
+	if s.cfg.DeletedRetention == 0 {
+		return nil
+	}
+
+	before := time.Now().Add(-time.Duration(s.cfg.DeletedRetention))
+
+	for _, repo := range []struct {
+		entity string
+		purge  func(context.Context, time.Time) (int64, error)
+	}{
+		{"order", s.storage.Order().Purge},
+		{"book", s.storage.Book().Purge},
+		{"user", s.storage.User().Purge},
+	} {
+		deleted, err := repo.purge(ctx, before)
+		if err != nil {
+			return err
+		}
+
+		if deleted > 0 {
+			s.log.InfoContext(ctx, "purged deleted rows", slog.String("entity", repo.entity), slog.Int64("deleted", deleted))
+		}
+	}
+
+	return nil
+}
+
+// Run purges every interval until ctx is done.
+func (s *PurgeService) Run(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.Purge(ctx)
+		if err != nil {
+			s.log.ErrorContext(ctx, "error whiling purge deleted rows", slog.Any("error", err))
+		}
+	}
+}
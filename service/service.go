@@ -0,0 +1,71 @@
+// Package service holds the business rules of the API: validation,
+// transactions, caching and domain checks. Handlers only translate HTTP to
+// service calls, and services only depend on the storage interfaces, so they
+// can be exercised with fake repositories.
+package service
+
+import (
+	"log/slog"
+
+	"crud/config"
+	"crud/pkg/mail"
+	"crud/pkg/metrics"
+	"crud/pkg/token"
+	"crud/storage"
+)
+
+type Service struct {
+	book    *BookService
+	user    *UserService
+	order   *OrderService
+	auth    *AuthService
+	account *AccountService
+	apiKey  *APIKeyService
+	audit   *AuditService
+	health  *HealthService
+}
+
+func NewService(cfg *config.Config, log *slog.Logger, metrics *metrics.Metrics, storage storage.StorageI, cache storage.CacheI, tokens *token.Issuer, mailer mail.Mailer) *Service {
+	return &Service{
+		book:    NewBookService(storage),
+		user:    NewUserService(log, storage, cache),
+		order:   NewOrderService(log, metrics, storage, cache),
+		auth:    NewAuthService(cfg, log, metrics, storage, cache, tokens),
+		account: NewAccountService(cfg, log, storage, cache, mailer),
+		apiKey:  NewAPIKeyService(log, storage),
+		audit:   NewAuditService(cfg, log, storage),
+		health:  NewHealthService(cfg, storage, cache),
+	}
+}
+
+func (s *Service) Book() *BookService {
+	return s.book
+}
+
+func (s *Service) User() *UserService {
+	return s.user
+}
+
+func (s *Service) Order() *OrderService {
+	return s.order
+}
+
+func (s *Service) Auth() *AuthService {
+	return s.auth
+}
+
+func (s *Service) Account() *AccountService {
+	return s.account
+}
+
+func (s *Service) APIKey() *APIKeyService {
+	return s.apiKey
+}
+
+func (s *Service) Audit() *AuditService {
+	return s.audit
+}
+
+func (s *Service) Health() *HealthService {
+	return s.health
+}
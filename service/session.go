@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"crud/config"
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/storage"
+)
+
+// tokenLifetime is the lifetime of the longest-lived token, which is how long
+// revoking all of a user's tokens has to be remembered.
+const tokenLifetime = config.TimeExpiredAt
+
+// Logout revokes the token the request was made with.
+func (s *AuthService) Logout(ctx context.Context, req *models.Token) error {
+	return s.cache.Session().Revoke(ctx, req)
+}
+
+// Sessions lists the live sessions of the token's user, marking the one of
+// the token itself.
+func (s *AuthService) Sessions(ctx context.Context, req *models.Token) (*models.GetListSessionResponse, error) {
+
+	sessions, err := s.cache.Session().GetList(ctx, req.User_id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.Id == req.Id
+	}
+
+	return &models.GetListSessionResponse{
+		Count:    int32(len(sessions)),
+		Sessions: sessions,
+	}, nil
+}
+
+// RevokeAll revokes every token issued to the user so far.
+func (s *AuthService) RevokeAll(ctx context.Context, req *models.UserPrimarKey) error {
+
+	_, err := s.storage.User().GetByPKey(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return revokeSessions(ctx, s.cache, req.Id)
+}
+
+// CheckToken rejects a revoked token.
+func (s *AuthService) CheckToken(ctx context.Context, req *models.Token) error {
+
+	revoked, err := s.cache.Session().Revoked(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return errs.Unauthorized("token has been revoked")
+	}
+
+	return nil
+}
+
+// CheckSuper rejects a super admin token whose user is no longer an admin,
+// so revoking the flag takes effect before the token expires.
+func (s *AuthService) CheckSuper(ctx context.Context, userID string) error {
+
+	user, err := s.storage.User().GetByPKey(ctx, &models.UserPrimarKey{Id: userID})
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.Forbidden("super admin token required")
+	} else if err != nil {
+		return err
+	}
+
+	if !user.IsAdmin {
+		return errs.Forbidden("super admin token required")
+	}
+
+	return nil
+}
+
+func revokeSessions(ctx context.Context, cache storage.CacheI, userID string) error {
+	return cache.Session().RevokeAll(ctx, userID, time.Now(), tokenLifetime)
+}
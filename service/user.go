@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"crud/models"
+	"crud/pkg/audit"
+	"crud/pkg/errs"
+	"crud/pkg/password"
+	"crud/pkg/validation"
+	"crud/storage"
+)
+
+type UserService struct {
+	log     *slog.Logger
+	storage storage.StorageI
+	cache   storage.CacheI
+}
+
+func NewUserService(log *slog.Logger, storage storage.StorageI, cache storage.CacheI) *UserService {
+	return &UserService{
+		log:     log,
+		storage: storage,
+		cache:   cache,
+	}
+}
+
+func (s *UserService) Create(ctx context.Context, req *models.CreateUser) (*models.User, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	create := *req
+
+	create.Password, err = password.Hash(req.Password)
+	if err != nil {
+		return nil, errs.Internal(err)
+	}
+
+	var user *models.User
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		id, err := tx.User().Create(ctx, &create)
+		if err != nil {
+			return err
+		}
+
+		user, err = tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionCreate, audit.EntityUser, id, nil, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx)
+
+	return user, nil
+}
+
+func (s *UserService) GetByPKey(ctx context.Context, req *models.UserPrimarKey) (*models.User, error) {
+	return s.storage.User().GetByPKey(ctx, req)
+}
+
+// GetList serves the unpaged list from the cache and fills it on a miss.
+// Paged requests and those including deleted users always go to storage.
+func (s *UserService) GetList(ctx context.Context, req *models.GetListUserRequest) (*models.GetListUserResponse, error) {
+
+	cacheable := req.Limit == 0 && req.Offset == 0 && !req.IncludeDeleted
+
+	if cacheable {
+		users, err := s.cache.User().GetList(ctx)
+		if err == nil {
+			return users, nil
+		}
+		if !errors.Is(err, storage.ErrCacheMiss) {
+			s.log.WarnContext(ctx, "error whiling get list cache", slog.Any("error", err))
+		}
+	}
+
+	resp, err := s.storage.User().GetList(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		err = s.cache.User().Create(ctx, resp)
+		if err != nil {
+			s.log.WarnContext(ctx, "error whiling create cache list", slog.Any("error", err))
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *UserService) Update(ctx context.Context, req *models.UpdateUser) (*models.User, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	update := *req
+
+	update.Password, err = password.Hash(req.Password)
+	if err != nil {
+		return nil, errs.Internal(err)
+	}
+
+	var (
+		user     *models.User
+		previous string
+	)
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		// Others have to send the balance and password the user has; they
+		// change the password with POST /password/reset.
+		if !req.Super {
+
+			if req.Balance != current.Balance || !password.Check(current.Password, req.Password) {
+				return errs.Forbidden("only a super admin can change the balance or password, change the password with POST /password/reset")
+			}
+
+			update.Password = current.Password
+		}
+
+		rowsAffected, err := tx.User().Update(ctx, &update)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return s.noRows(ctx, tx, req.Id)
+		}
+
+		previous = current.Password
+
+		user, err = tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionUpdate, audit.EntityUser, req.Id, current, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx)
+
+	// The password is always written anew, as a new hash; only a different
+	// one logs the user out.
+	if !password.Check(previous, req.Password) {
+		s.revokeSessions(ctx, req.Id)
+	}
+
+	return user, nil
+}
+
+// Patch applies a merge or JSON patch to the user and writes only the
+// fields it changed, so e.g. the balance survives a name change.
+func (s *UserService) Patch(ctx context.Context, req *models.PatchRequest) (*models.User, error) {
+
+	var (
+		user            *models.User
+		dirty           bool
+		passwordChanged bool
+	)
+
+	err := s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion("user", req.Version, current.Version)
+		if err != nil {
+			return err
+		}
+
+		var update models.UpdateUser
+
+		err = applyPatch(req, &models.UpdateUser{
+			Id:           current.Id,
+			First_name:   current.First_name,
+			Last_name:    current.Last_name,
+			Login:        current.Login,
+			Password:     current.Password,
+			Phone_number: current.Phone_number,
+			Balance:      current.Balance,
+		}, &update)
+		if err != nil {
+			return err
+		}
+
+		patch := models.PatchUser{
+			Id:           current.Id,
+			Version:      current.Version,
+			First_name:   changed(current.First_name, update.First_name, &dirty),
+			Last_name:    changed(current.Last_name, update.Last_name, &dirty),
+			Login:        changed(current.Login, update.Login, &dirty),
+			Password:     changed(current.Password, update.Password, &dirty),
+			Phone_number: changed(current.Phone_number, update.Phone_number, &dirty),
+			Balance:      changed(current.Balance, update.Balance, &dirty),
+		}
+
+		if !dirty {
+			user = current
+			return nil
+		}
+
+		if !req.Super && (patch.Password != nil || patch.Balance != nil) {
+			return errs.Forbidden("only a super admin can change the balance or password, change the password with POST /password/reset")
+		}
+
+		// The patch starts from the stored hash, so a changed password is
+		// a new one in plain text.
+		if patch.Password != nil {
+			hash, err := password.Hash(*patch.Password)
+			if err != nil {
+				return errs.Internal(err)
+			}
+
+			patch.Password = &hash
+			passwordChanged = true
+		}
+
+		rowsAffected, err := tx.User().Patch(ctx, &patch)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return s.noRows(ctx, tx, req.Id)
+		}
+
+		user, err = tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionUpdate, audit.EntityUser, req.Id, current, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dirty {
+		s.invalidate(ctx)
+	}
+
+	if passwordChanged {
+		s.revokeSessions(ctx, req.Id)
+	}
+
+	return user, nil
+}
+
+// Delete marks the user deleted, which Restore undoes until the purge job
+// removes it for good. Deleting a missing user succeeds unless a version was
+// expected.
+func (s *UserService) Delete(ctx context.Context, req *models.UserPrimarKey) error {
+
+	var deleted bool
+
+	err := s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id})
+		if errors.Is(err, errs.ErrNotFound) && req.Version == 0 {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		rowsAffected, err := tx.User().Delete(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return s.noRows(ctx, tx, req.Id)
+		}
+
+		deleted = true
+
+		return record(ctx, tx, audit.ActionDelete, audit.EntityUser, req.Id, current, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidate(ctx)
+
+	if deleted {
+		s.revokeSessions(ctx, req.Id)
+	}
+
+	return nil
+}
+
+// Restore undeletes the user. A user that is not deleted is returned as it
+// is. Its sessions stay revoked.
+func (s *UserService) Restore(ctx context.Context, req *models.UserPrimarKey) (*models.User, error) {
+
+	var (
+		user     *models.User
+		restored bool
+	)
+
+	err := s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id, IncludeDeleted: true})
+		if err != nil {
+			return err
+		}
+
+		if current.DeletedAt == "" {
+			user = current
+			return nil
+		}
+
+		_, err = tx.User().Restore(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		restored = true
+
+		user, err = tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: req.Id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionRestore, audit.EntityUser, req.Id, current, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if restored {
+		s.invalidate(ctx)
+	}
+
+	return user, nil
+}
+
+// SetAdmin grants or revokes super admin logins. It is only reachable from
+// the admin command, never over HTTP. Super admin tokens of a revoked admin
+// stop working at once, as every request with one checks the flag.
+func (s *UserService) SetAdmin(ctx context.Context, req *models.SetAdmin) (*models.User, error) {
+
+	err := validation.Struct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		user  *models.User
+		dirty bool
+	)
+
+	err = s.storage.WithTx(ctx, func(tx storage.StorageI) error {
+
+		current, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Login: req.Login})
+		if err != nil {
+			return err
+		}
+
+		if current.IsAdmin == req.IsAdmin {
+			user = current
+			return nil
+		}
+
+		rowsAffected, err := tx.User().Patch(ctx, &models.PatchUser{Id: current.Id, IsAdmin: &req.IsAdmin})
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return s.noRows(ctx, tx, current.Id)
+		}
+
+		dirty = true
+
+		user, err = tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: current.Id})
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, audit.ActionUpdate, audit.EntityUser, current.Id, current, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dirty {
+		s.invalidate(ctx)
+	}
+
+	return user, nil
+}
+
+// noRows explains a write that affected no rows: the user is missing, or it
+// no longer has the version the write expected.
+func (s *UserService) noRows(ctx context.Context, tx storage.StorageI, id string) error {
+
+	user, err := tx.User().GetByPKey(ctx, &models.UserPrimarKey{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return preconditionFailed("user", user.Version)
+}
+
+// invalidate drops the cached list. A failure only delays freshness until
+// the next successful write, so it is logged instead of failing the request.
+func (s *UserService) invalidate(ctx context.Context) {
+
+	err := s.cache.User().Delete(ctx)
+	if err != nil {
+		s.log.WarnContext(ctx, "error whiling cache delete", slog.Any("error", err))
+	}
+}
+
+// revokeSessions logs the user out everywhere after a password change or
+// deletion. The write already happened, so a failure is logged rather than
+// failing the request.
+func (s *UserService) revokeSessions(ctx context.Context, id string) {
+
+	err := revokeSessions(ctx, s.cache, id)
+	if err != nil {
+		s.log.ErrorContext(ctx, "error whiling revoke sessions", slog.Any("error", err))
+	}
+}
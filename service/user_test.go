@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/pkg/password"
+)
+
+func TestUserPatch(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	cache := &fakeCache{}
+	s := NewUserService(discardLog, store, cache)
+
+	id := store.addUser("samandar", 100)
+
+	user, err := s.Patch(ctx, &models.PatchRequest{
+		Id:          id,
+		ContentType: models.MergePatchType,
+		Document:    []byte(`{"first_name": "Saman"}`),
+	})
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if user.First_name != "Saman" || user.Last_name != "b" || user.Balance != 100 || user.Version != 2 {
+		t.Errorf("Patch = %+v", user)
+	}
+	if len(cache.revoked) != 0 {
+		t.Errorf("sessions revoked without a password change: %v", cache.revoked)
+	}
+	if len(store.data.audit) != 1 || string(store.data.audit[0].After) != `{"first_name":"Saman"}` {
+		t.Errorf("audit entries = %+v", store.data.audit)
+	}
+
+	for _, document := range []string{`{"password": "new secret"}`, `{"balance": 1000}`} {
+		_, err = s.Patch(ctx, &models.PatchRequest{
+			Id:          id,
+			ContentType: models.MergePatchType,
+			Document:    []byte(document),
+		})
+		if !errors.Is(err, errs.ErrForbidden) {
+			t.Errorf("Patch %s without super = %v, want forbidden", document, err)
+		}
+	}
+	if user := store.data.users[id]; user.Version != 2 || user.Balance != 100 {
+		t.Errorf("user after refused patches = %+v", user)
+	}
+
+	_, err = s.Patch(ctx, &models.PatchRequest{
+		Id:          id,
+		ContentType: models.MergePatchType,
+		Document:    []byte(`{"password": "new secret"}`),
+		Version:     2,
+		Super:       true,
+	})
+	if err != nil {
+		t.Fatalf("Patch password: %v", err)
+	}
+	if hash := store.data.users[id].Password; !password.Check(hash, "new secret") || password.NeedsRehash(hash) {
+		t.Errorf("password stored as %q, want a hash of the new one", hash)
+	}
+	if len(cache.revoked) != 1 || cache.revoked[0] != id {
+		t.Errorf("revoked sessions = %v, want the user's", cache.revoked)
+	}
+}
+
+func TestUserUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	cache := &fakeCache{}
+	s := NewUserService(discardLog, store, cache)
+
+	id := store.addUser("samandar", 100)
+
+	update := models.UpdateUser{Id: id, First_name: "Saman", Last_name: "b", Login: "samandar", Password: "secret", Phone_number: "997191323", Balance: 100}
+
+	user, err := s.Update(ctx, &update)
+	if err != nil {
+		t.Fatalf("Update with the current password and balance: %v", err)
+	}
+	if user.First_name != "Saman" || user.Password != "secret" || len(cache.revoked) != 0 {
+		t.Errorf("Update = %+v, revoked = %v, want the name changed only", user, cache.revoked)
+	}
+
+	for _, change := range []func(*models.UpdateUser){
+		func(u *models.UpdateUser) { u.Password = "new secret" },
+		func(u *models.UpdateUser) { u.Balance = 1000 },
+	} {
+		req := update
+		change(&req)
+
+		_, err = s.Update(ctx, &req)
+		if !errors.Is(err, errs.ErrForbidden) {
+			t.Errorf("Update %+v without super = %v, want forbidden", req, err)
+		}
+	}
+
+	update.Password, update.Balance, update.Super = "new secret", 1000, true
+
+	user, err = s.Update(ctx, &update)
+	if err != nil {
+		t.Fatalf("Update as super: %v", err)
+	}
+	if user.Balance != 1000 || !password.Check(user.Password, "new secret") || len(cache.revoked) != 1 {
+		t.Errorf("Update as super = %+v, revoked = %v", user, cache.revoked)
+	}
+}
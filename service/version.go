@@ -0,0 +1,21 @@
+package service
+
+import (
+	"crud/pkg/errs"
+)
+
+// checkVersion fails with PRECONDITION_FAILED when the request expects a
+// version (If-Match) other than the current one.
+func checkVersion(entity string, expected, current int64) error {
+
+	if expected > 0 && expected != current {
+		return preconditionFailed(entity, current)
+	}
+
+	return nil
+}
+
+func preconditionFailed(entity string, current int64) error {
+	return errs.PreconditionFailed("%s was modified, current version is %d", entity, current).
+		WithDetails(map[string]int64{"version": current})
+}
@@ -3,12 +3,23 @@ package storage
 import (
 	"context"
 	"crud/models"
+	"errors"
+	"time"
 )
 
+// ErrCacheMiss is returned by GetList when nothing is cached.
+var ErrCacheMiss = errors.New("cache miss")
+
 type CacheI interface {
 	CloseDB()
+	Ping(ctx context.Context) error
 	User() UserCacheI
 	Order() OrderCacheI
+	Idempotency() IdempotencyCacheI
+	RateLimit() RateLimitCacheI
+	LoginAttempts() LoginAttemptsCacheI
+	Session() SessionCacheI
+	Tokens() OneTimeTokenCacheI
 }
 
 type UserCacheI interface {
@@ -24,3 +35,54 @@ type OrderCacheI interface {
 	Update(ctx context.Context, req *models.GetListOrderResponse) error
 	Delete(ctx context.Context) error
 }
+
+type IdempotencyCacheI interface {
+	// Reserve stores a pending record with fingerprint under key for ttl,
+	// unless key is taken. It returns the record found under key, or nil
+	// when the reservation was made.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*models.IdempotentResponse, error)
+	// Save replaces the record under key with the final response.
+	Save(ctx context.Context, key string, resp *models.IdempotentResponse, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type RateLimitCacheI interface {
+	// Allow counts a request under key unless limit requests were already
+	// counted within the sliding window.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (*models.RateLimitResult, error)
+}
+
+type LoginAttemptsCacheI interface {
+	// Locked returns how long login stays locked, or 0.
+	Locked(ctx context.Context, login string) (time.Duration, error)
+	// Fail counts a failed login and returns the failures so far. They are
+	// forgotten once window passes without another failure.
+	Fail(ctx context.Context, login string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, login string, d time.Duration) error
+	// Reset forgets the failures and lifts the lock of login.
+	Reset(ctx context.Context, login string) error
+}
+
+type SessionCacheI interface {
+	// Create records a session until it expires after ttl.
+	Create(ctx context.Context, req *models.Session, ttl time.Duration) error
+	// GetList returns the live sessions of a user.
+	GetList(ctx context.Context, userID string) ([]*models.Session, error)
+	// Revoke denies the token until it expires and forgets its session.
+	Revoke(ctx context.Context, req *models.Token) error
+	// RevokeAll denies every token of the user issued before before. ttl is
+	// the lifetime of the longest-lived token.
+	RevokeAll(ctx context.Context, userID string, before time.Time, ttl time.Duration) error
+	// Revoked reports whether the token was revoked.
+	Revoked(ctx context.Context, req *models.Token) (bool, error)
+}
+
+type OneTimeTokenCacheI interface {
+	// Create stores token for purpose, such as email verification, until it
+	// expires after ttl. Only a hash of token is kept.
+	Create(ctx context.Context, purpose, token, userID string, ttl time.Duration) error
+	// Consume returns the user the token was created for and deletes it, so
+	// a token can be used once. It returns ErrCacheMiss for unknown or
+	// expired tokens.
+	Consume(ctx context.Context, purpose, token string) (string, error)
+}
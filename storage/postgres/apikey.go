@@ -0,0 +1,220 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"crud/models"
+)
+
+type APIKeyRepo struct {
+	db querier
+}
+
+func NewAPIKeyRepo(db querier) *APIKeyRepo {
+	return &APIKeyRepo{
+		db: db,
+	}
+}
+
+func (f *APIKeyRepo) Create(ctx context.Context, req *models.CreateAPIKey) (string, error) {
+
+	var (
+		id        = uuid.New().String()
+		expiresIn sql.NullInt64
+	)
+
+	if req.ExpiresIn != 0 {
+		expiresIn = sql.NullInt64{Int64: int64(req.ExpiresIn.Seconds()), Valid: true}
+	}
+
+	query := `
+		INSERT INTO api_keys(
+			api_key_id,
+			name,
+			prefix,
+			key_hash,
+			scopes,
+			created_by,
+			expires_at
+		) VALUES ( $1, $2, $3, $4, $5, $6, now() + $7 * INTERVAL '1 second' )
+	`
+
+	_, err := f.db.Exec(ctx, query,
+		id,
+		req.Name,
+		req.Prefix,
+		req.KeyHash,
+		strings.Join(req.Scopes, " "),
+		sql.NullString{String: req.Created_by, Valid: req.Created_by != ""},
+		expiresIn,
+	)
+	if err != nil {
+		return "", mapError(err, "api key")
+	}
+
+	return id, nil
+}
+
+func (f *APIKeyRepo) GetByPKey(ctx context.Context, pkey *models.APIKeyPrimarKey) (*models.APIKey, error) {
+
+	var (
+		where = "api_key_id = $1"
+		arg   = pkey.Id
+	)
+
+	if pkey.Id == "" {
+		where, arg = "prefix = $1", pkey.Prefix
+	}
+
+	query := `
+		SELECT
+			api_key_id,
+			name,
+			prefix,
+			key_hash,
+			scopes,
+			created_by,
+			expires_at,
+			expires_at IS NOT NULL AND expires_at <= now(),
+			last_used_at,
+			created_at
+		FROM api_keys
+		WHERE ` + where
+
+	return scanAPIKey(f.db.QueryRow(ctx, query, arg))
+}
+
+func (f *APIKeyRepo) GetList(ctx context.Context, req *models.GetListAPIKeyRequest) (*models.GetListAPIKeyResponse, error) {
+
+	var (
+		resp   = models.GetListAPIKeyResponse{}
+		offset = ""
+		limit  = ""
+	)
+
+	if req.Limit > 0 {
+		limit = fmt.Sprintf(" LIMIT %d", req.Limit)
+	}
+
+	if req.Offset > 0 {
+		offset = fmt.Sprintf(" OFFSET %d", req.Offset)
+	}
+
+	query := `
+		SELECT
+			COUNT(*) OVER(),
+			api_key_id,
+			name,
+			prefix,
+			key_hash,
+			scopes,
+			created_by,
+			expires_at,
+			expires_at IS NOT NULL AND expires_at <= now(),
+			last_used_at,
+			created_at
+		FROM
+			api_keys
+		ORDER BY created_at, api_key_id
+	`
+
+	query += offset + limit
+
+	rows, err := f.db.Query(ctx, query)
+	if err != nil {
+		return nil, mapError(err, "api key")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		key, err := scanAPIKey(rows, &resp.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.APIKeys = append(resp.APIKeys, key)
+	}
+
+	return &resp, rows.Err()
+}
+
+func (f *APIKeyRepo) Touch(ctx context.Context, req *models.TouchAPIKey) (int64, error) {
+
+	query := `
+		UPDATE
+			api_keys
+		SET
+			last_used_at = now()
+		WHERE api_key_id = $1 AND (last_used_at IS NULL OR last_used_at <= now() - $2 * INTERVAL '1 second')
+	`
+
+	result, err := f.db.Exec(ctx, query, req.Id, int64(req.Interval.Seconds()))
+	if err != nil {
+		return 0, mapError(err, "api key")
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (f *APIKeyRepo) Delete(ctx context.Context, req *models.APIKeyPrimarKey) (int64, error) {
+
+	result, err := f.db.Exec(ctx, "DELETE FROM api_keys WHERE api_key_id = $1", req.Id)
+	if err != nil {
+		return 0, mapError(err, "api key")
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// scanAPIKey reads a row of the api key queries, preceded by the columns in
+// dest.
+func scanAPIKey(row interface{ Scan(...interface{}) error }, dest ...interface{}) (*models.APIKey, error) {
+
+	var (
+		id         sql.NullString
+		name       sql.NullString
+		prefix     sql.NullString
+		keyHash    sql.NullString
+		scopes     sql.NullString
+		createdBy  sql.NullString
+		expiresAt  sql.NullString
+		expired    sql.NullBool
+		lastUsedAt sql.NullString
+		createdAt  sql.NullString
+	)
+
+	err := row.Scan(append(dest,
+		&id,
+		&name,
+		&prefix,
+		&keyHash,
+		&scopes,
+		&createdBy,
+		&expiresAt,
+		&expired,
+		&lastUsedAt,
+		&createdAt,
+	)...)
+	if err != nil {
+		return nil, mapError(err, "api key")
+	}
+
+	return &models.APIKey{
+		Id:         id.String,
+		Name:       name.String,
+		Prefix:     prefix.String,
+		KeyHash:    keyHash.String,
+		Scopes:     strings.Fields(scopes.String),
+		Created_by: createdBy.String,
+		ExpiresAt:  expiresAt.String,
+		Expired:    expired.Bool,
+		LastUsedAt: lastUsedAt.String,
+		CreatedAt:  createdAt.String,
+	}, nil
+}
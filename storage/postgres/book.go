@@ -4,19 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v4/pgxpool"
 
 	"crud/models"
 	"crud/pkg/helper"
 )
 
 type BookRepo struct {
-	db *pgxpool.Pool
+	db querier
 }
 
-func NewBookRepo(db *pgxpool.Pool) *BookRepo {
+func NewBookRepo(db querier) *BookRepo {
 	return &BookRepo{
 		db: db,
 	}
@@ -35,8 +35,9 @@ func (f *BookRepo) Create(ctx context.Context, book *models.CreateBook) (string,
 			title,
 			author,
 			price,
+			isbn,
 			updated_at
-		) VALUES ( $1, $2, $3, $4, now() )
+		) VALUES ( $1, $2, $3, $4, $5, now() )
 	`
 
 	_, err := f.db.Exec(ctx, query,
@@ -44,10 +45,11 @@ func (f *BookRepo) Create(ctx context.Context, book *models.CreateBook) (string,
 		book.Title,
 		book.Author,
 		book.Price,
+		nullString(book.ISBN),
 	)
 
 	if err != nil {
-		return "", err
+		return "", mapError(err, "book")
 	}
 
 	return id, nil
@@ -60,8 +62,11 @@ func (f *BookRepo) GetByPKey(ctx context.Context, pkey *models.BookPrimarKey) (*
 		title     sql.NullString
 		author    sql.NullString
 		price     sql.NullFloat64
+		isbn      sql.NullString
 		createdAt sql.NullString
 		updatedAt sql.NullString
+		version   sql.NullInt64
+		deletedAt sql.NullString
 	)
 
 	query := `
@@ -70,25 +75,35 @@ func (f *BookRepo) GetByPKey(ctx context.Context, pkey *models.BookPrimarKey) (*
 			title,
 			author,
 			price,
+			isbn,
 			created_at,
-			updated_at
+			updated_at,
+			version,
+			deleted_at
 		FROM
 			books
 		WHERE book_id = $1
 	`
 
+	if !pkey.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+
 	err := f.db.QueryRow(ctx, query, pkey.Id).
 		Scan(
 			&id,
 			&title,
 			&author,
 			&price,
+			&isbn,
 			&createdAt,
 			&updatedAt,
+			&version,
+			&deletedAt,
 		)
 
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "book")
 	}
 
 	return &models.Book{
@@ -96,8 +111,11 @@ func (f *BookRepo) GetByPKey(ctx context.Context, pkey *models.BookPrimarKey) (*
 		Title:     title.String,
 		Author:    author.String,
 		Price:     price.Float64,
+		ISBN:      isbn.String,
 		CreatedAt: createdAt.String,
 		UpdatedAt: updatedAt.String,
+		Version:   version.Int64,
+		DeletedAt: deletedAt.String,
 	}, nil
 }
 
@@ -107,6 +125,7 @@ func (f *BookRepo) GetList(ctx context.Context, req *models.GetListBookRequest)
 		resp   = models.GetListBookResponse{}
 		offset = ""
 		limit  = ""
+		where  = " WHERE deleted_at IS NULL"
 	)
 
 	if req.Limit > 0 {
@@ -117,6 +136,10 @@ func (f *BookRepo) GetList(ctx context.Context, req *models.GetListBookRequest)
 		offset = fmt.Sprintf(" OFFSET %d", req.Offset)
 	}
 
+	if req.IncludeDeleted {
+		where = ""
+	}
+
 	query := `
 		SELECT
 			COUNT(*) OVER(),
@@ -124,15 +147,22 @@ func (f *BookRepo) GetList(ctx context.Context, req *models.GetListBookRequest)
 			title,
 			author,
 			price,
+			isbn,
 			created_at,
-			updated_at
+			updated_at,
+			version,
+			deleted_at
 		FROM
 			books
-	`
+	` + where
 
 	query += offset + limit
 
 	rows, err := f.db.Query(ctx, query)
+	if err != nil {
+		return nil, mapError(err, "book")
+	}
+	defer rows.Close()
 
 	for rows.Next() {
 
@@ -141,8 +171,11 @@ func (f *BookRepo) GetList(ctx context.Context, req *models.GetListBookRequest)
 			title     sql.NullString
 			author    sql.NullString
 			price     sql.NullFloat64
+			isbn      sql.NullString
 			createdAt sql.NullString
 			updatedAt sql.NullString
+			version   sql.NullInt64
+			deletedAt sql.NullString
 		)
 
 		err := rows.Scan(
@@ -151,12 +184,15 @@ func (f *BookRepo) GetList(ctx context.Context, req *models.GetListBookRequest)
 			&title,
 			&author,
 			&price,
+			&isbn,
 			&createdAt,
 			&updatedAt,
+			&version,
+			&deletedAt,
 		)
 
 		if err != nil {
-			return nil, err
+			return nil, mapError(err, "book")
 		}
 
 		resp.Books = append(resp.Books, &models.Book{
@@ -164,56 +200,118 @@ func (f *BookRepo) GetList(ctx context.Context, req *models.GetListBookRequest)
 			Title:     title.String,
 			Author:    author.String,
 			Price:     price.Float64,
+			ISBN:      isbn.String,
 			CreatedAt: createdAt.String,
 			UpdatedAt: updatedAt.String,
+			Version:   version.Int64,
+			DeletedAt: deletedAt.String,
 		})
 
 	}
 
-	return &resp, err
+	return &resp, rows.Err()
 }
 
 func (f *BookRepo) Update(ctx context.Context, req *models.UpdateBook) (int64, error) {
+	return f.Patch(ctx, &models.PatchBook{
+		Id:      req.Id,
+		Title:   &req.Title,
+		Author:  &req.Author,
+		Price:   &req.Price,
+		ISBN:    &req.ISBN,
+		Version: req.Version,
+	})
+}
 
-	var (
-		query  = ""
-		params map[string]interface{}
-	)
+func (f *BookRepo) Patch(ctx context.Context, req *models.PatchBook) (int64, error) {
 
-	query = `
-		UPDATE
-			books
-		SET
-			title = :title,
-			author = :author,
-			price = :price,
-			updated_at = now()
-		WHERE book_id = :book_id
-	`
+	q := helper.NewUpdateQuery("books", helper.Dollar)
+
+	if req.Title != nil {
+		q.Set("title", *req.Title)
+	}
+	if req.Author != nil {
+		q.Set("author", *req.Author)
+	}
+	if req.Price != nil {
+		q.Set("price", *req.Price)
+	}
+	if req.ISBN != nil {
+		q.Set("isbn", nullString(*req.ISBN))
+	}
+
+	q.SetExpr("updated_at", "now()").
+		SetExpr("version", "version + 1").
+		Where("book_id", req.Id).
+		WhereNull("deleted_at", true)
 
-	params = map[string]interface{}{
-		"book_id": req.Id,
-		"title":   req.Title,
-		"author":  req.Author,
-		"price":   req.Price,
+	if req.Version > 0 {
+		q.Where("version", req.Version)
 	}
 
-	query, args := helper.ReplaceQueryParams(query, params)
+	query, args := q.Build()
 
-	rowsAffected, err := f.db.Exec(ctx, query, args...)
+	result, err := f.db.Exec(ctx, query, args...)
 	if err != nil {
-		return 0, err
+		return 0, mapError(err, "book")
 	}
 
-	return rowsAffected.RowsAffected(), nil
+	return result.RowsAffected(), nil
 }
 
-func (f *BookRepo) Delete(ctx context.Context, req *models.BookPrimarKey) error {
+func (f *BookRepo) Delete(ctx context.Context, req *models.BookPrimarKey) (int64, error) {
+
+	q := helper.NewUpdateQuery("books", helper.Dollar).
+		SetExpr("deleted_at", "now()").
+		SetExpr("updated_at", "now()").
+		SetExpr("version", "version + 1").
+		Where("book_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
+	}
+
+	query, args := q.Build()
+
+	result, err := f.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "book")
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (f *BookRepo) Restore(ctx context.Context, req *models.BookPrimarKey) (int64, error) {
+
+	query, args := helper.NewUpdateQuery("books", helper.Dollar).
+		SetExpr("deleted_at", "NULL").
+		SetExpr("updated_at", "now()").
+		SetExpr("version", "version + 1").
+		Where("book_id", req.Id).
+		WhereNull("deleted_at", false).
+		Build()
+
+	result, err := f.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "book")
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (f *BookRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+
+	query := `
+		DELETE FROM books
+		WHERE deleted_at < LOCALTIMESTAMP - $1::interval
+			AND NOT EXISTS (SELECT 1 FROM orders WHERE orders.book_id = books.book_id)
+	`
 
-	_, err := f.db.Exec(ctx, "DELETE FROM books WHERE book_id = $1", req.Id)
+	result, err := f.db.Exec(ctx, query, age(before))
 	if err != nil {
-		return err
+		return 0, mapError(err, "book")
 	}
 
-	return err
+	return result.RowsAffected(), nil
 }
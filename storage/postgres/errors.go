@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"crud/pkg/errs"
+)
+
+// mapError translates driver errors into domain errors from pkg/errs.
+// entity names the record the query was about, e.g. "book".
+func mapError(err error, entity string) error {
+
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errs.NotFound("%s not found", entity).Wrap(err)
+	}
+
+	if pgconn.Timeout(err) {
+		return errs.Timeout("%s query timed out", entity).Wrap(err)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case "23505":
+		return errs.Conflict("%s already exists", entity).
+			WithDetails(map[string]string{"constraint": pgErr.ConstraintName}).
+			Wrap(err)
+	case "23503":
+		if strings.HasPrefix(pgErr.Message, "insert or update") {
+			return errs.Conflict("%s references a record that does not exist", entity).
+				WithDetails(map[string]string{"constraint": pgErr.ConstraintName}).
+				Wrap(err)
+		}
+		return errs.Conflict("%s is still referenced by other records", entity).
+			WithDetails(map[string]string{"constraint": pgErr.ConstraintName}).
+			Wrap(err)
+	case "57014":
+		return errs.Timeout("%s query exceeded the statement timeout", entity).Wrap(err)
+	case "22P02":
+		return errs.InvalidArgument("invalid %s identifier", entity).Wrap(err)
+	case "22001", "23502", "23514":
+		return errs.Validation("%s violates a database constraint", entity).
+			WithDetails(map[string]string{"column": pgErr.ColumnName, "constraint": pgErr.ConstraintName}).
+			Wrap(err)
+	}
+
+	return err
+}
+
+// nullString stores empty optional values as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"crud/models"
+)
+
+type IdentityRepo struct {
+	db querier
+}
+
+func NewIdentityRepo(db querier) *IdentityRepo {
+	return &IdentityRepo{
+		db: db,
+	}
+}
+
+func (f *IdentityRepo) Create(ctx context.Context, req *models.CreateIdentity) error {
+
+	query := `
+		INSERT INTO user_identities(
+			issuer,
+			subject,
+			user_id,
+			email
+		) VALUES ( $1, $2, $3, $4 )
+	`
+
+	_, err := f.db.Exec(ctx, query,
+		req.Issuer,
+		req.Subject,
+		req.User_id,
+		nullString(req.Email),
+	)
+	if err != nil {
+		return mapError(err, "identity")
+	}
+
+	return nil
+}
+
+func (f *IdentityRepo) GetByPKey(ctx context.Context, pkey *models.IdentityPrimarKey) (*models.Identity, error) {
+
+	var (
+		issuer    sql.NullString
+		subject   sql.NullString
+		userID    sql.NullString
+		email     sql.NullString
+		createdAt sql.NullString
+	)
+
+	query := `
+		SELECT
+			issuer,
+			subject,
+			user_id,
+			email,
+			created_at
+		FROM user_identities
+		WHERE issuer = $1 AND subject = $2
+	`
+
+	err := f.db.QueryRow(ctx, query, pkey.Issuer, pkey.Subject).
+		Scan(
+			&issuer,
+			&subject,
+			&userID,
+			&email,
+			&createdAt,
+		)
+	if err != nil {
+		return nil, mapError(err, "identity")
+	}
+
+	return &models.Identity{
+		Issuer:    issuer.String,
+		Subject:   subject.String,
+		User_id:   userID.String,
+		Email:     email.String,
+		CreatedAt: createdAt.String,
+	}, nil
+}
+
+func (f *IdentityRepo) GetList(ctx context.Context, req *models.GetListIdentityRequest) (*models.GetListIdentityResponse, error) {
+
+	var resp = models.GetListIdentityResponse{}
+
+	query := `
+		SELECT
+			COUNT(*) OVER(),
+			issuer,
+			subject,
+			user_id,
+			email,
+			created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at, issuer, subject
+	`
+
+	rows, err := f.db.Query(ctx, query, req.User_id)
+	if err != nil {
+		return nil, mapError(err, "identity")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var (
+			issuer    sql.NullString
+			subject   sql.NullString
+			userID    sql.NullString
+			email     sql.NullString
+			createdAt sql.NullString
+		)
+
+		err := rows.Scan(
+			&resp.Count,
+			&issuer,
+			&subject,
+			&userID,
+			&email,
+			&createdAt,
+		)
+		if err != nil {
+			return nil, mapError(err, "identity")
+		}
+
+		resp.Identities = append(resp.Identities, &models.Identity{
+			Issuer:    issuer.String,
+			Subject:   subject.String,
+			User_id:   userID.String,
+			Email:     email.String,
+			CreatedAt: createdAt.String,
+		})
+	}
+
+	return &resp, rows.Err()
+}
+
+func (f *IdentityRepo) Unlink(ctx context.Context, req *models.UserPrimarKey) (int64, error) {
+
+	result, err := f.db.Exec(ctx, "DELETE FROM user_identities WHERE user_id = $1", req.Id)
+	if err != nil {
+		return 0, mapError(err, "identity")
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"crud/pkg/tracing"
+)
+
+// instrumentedQuerier traces every statement and logs it at debug level. It
+// uses the caller's context, so spans nest under the request span and log
+// lines carry the request id.
+type instrumentedQuerier struct {
+	db  querier
+	log *slog.Logger
+}
+
+func (q instrumentedQuerier) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	ctx, done := q.start(ctx, sql)
+	tag, err := q.db.Exec(ctx, sql, arguments...)
+	done(err)
+	return tag, err
+}
+
+func (q instrumentedQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, done := q.start(ctx, sql)
+	rows, err := q.db.Query(ctx, sql, args...)
+	done(err)
+	return rows, err
+}
+
+func (q instrumentedQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, done := q.start(ctx, sql)
+	row := q.db.QueryRow(ctx, sql, args...)
+	done(nil)
+	return row
+}
+
+func (q instrumentedQuerier) start(ctx context.Context, sql string) (context.Context, func(error)) {
+
+	start := time.Now()
+	ctx, span := tracing.StartQuery(ctx, semconv.DBSystemPostgreSQL, sql)
+
+	return ctx, func(err error) {
+
+		tracing.End(span, err)
+
+		if !q.log.Enabled(ctx, slog.LevelDebug) {
+			return
+		}
+
+		attrs := []any{
+			slog.String("query", strings.Join(strings.Fields(sql), " ")),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		q.log.DebugContext(ctx, "query", attrs...)
+	}
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"crud/pkg/metrics"
+)
+
+var (
+	poolAcquiredDesc = prometheus.NewDesc(metrics.Namespace+"_db_pool_acquired_connections", "Connections currently in use.", nil, nil)
+	poolIdleDesc     = prometheus.NewDesc(metrics.Namespace+"_db_pool_idle_connections", "Idle connections.", nil, nil)
+	poolTotalDesc    = prometheus.NewDesc(metrics.Namespace+"_db_pool_total_connections", "Open connections.", nil, nil)
+	poolMaxDesc      = prometheus.NewDesc(metrics.Namespace+"_db_pool_max_connections", "Maximum size of the pool.", nil, nil)
+	poolAcquireDesc  = prometheus.NewDesc(metrics.Namespace+"_db_pool_acquires_total", "Successful connection acquires.", nil, nil)
+	poolWaitDesc     = prometheus.NewDesc(metrics.Namespace+"_db_pool_acquire_wait_seconds_total", "Time spent waiting for a connection.", nil, nil)
+	poolEmptyDesc    = prometheus.NewDesc(metrics.Namespace+"_db_pool_empty_acquires_total", "Acquires that had to wait because the pool was empty.", nil, nil)
+	poolCanceledDesc = prometheus.NewDesc(metrics.Namespace+"_db_pool_canceled_acquires_total", "Acquires cancelled by their context.", nil, nil)
+)
+
+// Describe and Collect make Store a prometheus.Collector exposing the pool
+// statistics at scrape time.
+func (s *Store) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredDesc
+	ch <- poolIdleDesc
+	ch <- poolTotalDesc
+	ch <- poolMaxDesc
+	ch <- poolAcquireDesc
+	ch <- poolWaitDesc
+	ch <- poolEmptyDesc
+	ch <- poolCanceledDesc
+}
+
+func (s *Store) Collect(ch chan<- prometheus.Metric) {
+
+	stat := s.db.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredDesc, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDesc, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolWaitDesc, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolEmptyDesc, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledDesc, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
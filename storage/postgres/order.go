@@ -4,19 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v4/pgxpool"
 
 	"crud/models"
 	"crud/pkg/helper"
 )
 
 type OrderRepo struct {
-	db *pgxpool.Pool
+	db querier
 }
 
-func NewOrderRepo(db *pgxpool.Pool) *OrderRepo {
+func NewOrderRepo(db querier) *OrderRepo {
 	return &OrderRepo{
 		db: db,
 	}
@@ -25,20 +25,10 @@ func NewOrderRepo(db *pgxpool.Pool) *OrderRepo {
 func (f *OrderRepo) Create(ctx context.Context, order *models.CreateOrder) (string, error) {
 
 	var (
-		id            = uuid.New().String()
-		payed         sql.NullFloat64
-		queryGetPrice string
-		query         string
+		id    = uuid.New().String()
+		query string
 	)
 
-	queryGetPrice = `
-		SELECT
-			price
-		FROM
-			books
-		WHERE book_id = $1
-	`
-
 	query = `
 		INSERT INTO orders(
 			order_id,
@@ -49,24 +39,15 @@ func (f *OrderRepo) Create(ctx context.Context, order *models.CreateOrder) (stri
 		) VALUES ( $1, $2, $3, $4, now() )
 	`
 
-	err := f.db.QueryRow(ctx, queryGetPrice, order.Book_id).
-		Scan(
-			&payed,
-		)
-
-	if err != nil {
-		return "", err
-	}
-
-	_, err = f.db.Exec(ctx, query,
+	_, err := f.db.Exec(ctx, query,
 		id,
 		order.User_id,
 		order.Book_id,
-		payed,
+		order.Payed,
 	)
 
 	if err != nil {
-		return "", err
+		return "", mapError(err, "order")
 	}
 
 	return id, nil
@@ -80,6 +61,9 @@ func (f *OrderRepo) GetByPKey(ctx context.Context, pkey *models.OrderPrimarKey)
 		book_id    sql.NullString
 		payed      sql.NullFloat64
 		created_at sql.NullString
+		updated_at sql.NullString
+		version    sql.NullInt64
+		deleted_at sql.NullString
 	)
 
 	query := `
@@ -88,12 +72,19 @@ func (f *OrderRepo) GetByPKey(ctx context.Context, pkey *models.OrderPrimarKey)
 			user_id,
 			book_id,
 			payed,
-			updated_at
+			created_at,
+			updated_at,
+			version,
+			deleted_at
 		FROM
 			orders
 		WHERE order_id = $1
 	`
 
+	if !pkey.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+
 	err := f.db.QueryRow(ctx, query, pkey.Id).
 		Scan(
 			&id,
@@ -101,10 +92,13 @@ func (f *OrderRepo) GetByPKey(ctx context.Context, pkey *models.OrderPrimarKey)
 			&book_id,
 			&payed,
 			&created_at,
+			&updated_at,
+			&version,
+			&deleted_at,
 		)
 
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "order")
 	}
 
 	return &models.Order{
@@ -113,6 +107,9 @@ func (f *OrderRepo) GetByPKey(ctx context.Context, pkey *models.OrderPrimarKey)
 		Book_id:   book_id.String,
 		Payed:     payed.Float64,
 		CreatedAt: created_at.String,
+		UpdatedAt: updated_at.String,
+		Version:   version.Int64,
+		DeletedAt: deleted_at.String,
 	}, nil
 }
 
@@ -122,6 +119,7 @@ func (f *OrderRepo) GetList(ctx context.Context, req *models.GetListOrderRequest
 		resp   = models.GetListOrderResponse{}
 		offset = ""
 		limit  = ""
+		filter = helper.NewFilter(helper.Dollar)
 	)
 
 	if req.Limit > 0 {
@@ -132,120 +130,162 @@ func (f *OrderRepo) GetList(ctx context.Context, req *models.GetListOrderRequest
 		offset = fmt.Sprintf(" OFFSET %d", req.Offset)
 	}
 
+	if !req.IncludeDeleted {
+		filter.WhereNull("orders.deleted_at", true)
+	}
+	if req.User_id != "" {
+		filter.Where("orders.user_id", "=", req.User_id)
+	}
+
+	where, args := filter.Build()
+
 	query := `
 		SELECT 
 			COUNT(*) OVER(),
+			orders.order_id,
+			orders.book_id,
 			users.first_name || ' ' || users.last_name as fullname,
 			books.title,
 			orders.payed,
 			orders.created_at,
-			orders.updated_at
+			orders.updated_at,
+			orders.deleted_at
 		FROM
 			orders
 		JOIN users ON orders.user_id = users.user_id
 		JOIN books ON orders.book_id = books.book_id
-	`
+	` + where
 
 	query += offset + limit
 
-	rows, err := f.db.Query(ctx, query)
+	rows, err := f.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(err, "order")
+	}
+	defer rows.Close()
 
 	for rows.Next() {
 
 		var (
+			id         sql.NullString
+			bookID     sql.NullString
 			fullname   sql.NullString
 			title      sql.NullString
 			payed      sql.NullFloat64
 			created_at sql.NullString
 			updated_at sql.NullString
+			deleted_at sql.NullString
 		)
 
 		err := rows.Scan(
 			&resp.Count,
+			&id,
+			&bookID,
 			&fullname,
 			&title,
 			&payed,
 			&created_at,
 			&updated_at,
+			&deleted_at,
 		)
 
 		if err != nil {
-			return nil, err
+			return nil, mapError(err, "order")
 		}
 
 		resp.Orders = append(resp.Orders, &models.OrderGroup{
+			Id:        id.String,
+			Book_id:   bookID.String,
 			FullName:  fullname.String,
 			Title:     title.String,
 			Payed:     payed.Float64,
 			CreatedAt: created_at.String,
 			UpdatedAt: updated_at.String,
+			DeletedAt: deleted_at.String,
 		})
 
 	}
 
-	return &resp, err
+	return &resp, rows.Err()
 }
 
 func (f *OrderRepo) Update(ctx context.Context, req *models.UpdateOrder) (int64, error) {
 
-	var (
-		query         = ""
-		queryGetPrice = ""
-		params        map[string]interface{}
-	)
+	q := helper.NewUpdateQuery("orders", helper.Dollar).
+		Set("user_id", req.User_id).
+		Set("book_id", req.Book_id).
+		Set("payed", req.Payed).
+		SetExpr("updated_at", "now()").
+		SetExpr("version", "version + 1").
+		Where("order_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
+	}
 
-	queryGetPrice = `
-		SELECT
-			price
-		FROM
-			books
-		WHERE book_id = $1
-	`
+	query, args := q.Build()
 
-	query = `
-		UPDATE
-			orders
-		SET
-			user_id = :user_id,
-			book_id = :book_id,
-			payed = :payed,
-			updated_at = now()
-		WHERE order_id = :order_id
-	`
+	result, err := f.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "order")
+	}
 
-	err := f.db.QueryRow(ctx, queryGetPrice, req.Book_id).
-		Scan(
-			&req.Payed,
-		)
+	return result.RowsAffected(), nil
+}
 
-	fmt.Println(req.Payed)
-	if err != nil {
-		return 0, err
+func (f *OrderRepo) Delete(ctx context.Context, req *models.OrderPrimarKey) (int64, error) {
+
+	q := helper.NewUpdateQuery("orders", helper.Dollar).
+		SetExpr("deleted_at", "now()").
+		SetExpr("updated_at", "now()").
+		SetExpr("version", "version + 1").
+		Where("order_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
 	}
 
-	params = map[string]interface{}{
-		"order_id": req.Id,
-		"user_id":  req.User_id,
-		"book_id":  req.Book_id,
-		"payed":    req.Payed,
+	query, args := q.Build()
+
+	result, err := f.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "order")
 	}
 
-	query, args := helper.ReplaceQueryParams(query, params)
+	return result.RowsAffected(), nil
+}
+
+func (f *OrderRepo) Restore(ctx context.Context, req *models.OrderPrimarKey) (int64, error) {
+
+	query, args := helper.NewUpdateQuery("orders", helper.Dollar).
+		SetExpr("deleted_at", "NULL").
+		SetExpr("updated_at", "now()").
+		SetExpr("version", "version + 1").
+		Where("order_id", req.Id).
+		WhereNull("deleted_at", false).
+		Build()
 
-	rowsAffected, err := f.db.Exec(ctx, query, args...)
+	result, err := f.db.Exec(ctx, query, args...)
 	if err != nil {
-		return 0, err
+		return 0, mapError(err, "order")
 	}
 
-	return rowsAffected.RowsAffected(), nil
+	return result.RowsAffected(), nil
 }
 
-func (f *OrderRepo) Delete(ctx context.Context, req *models.OrderPrimarKey) error {
+func (f *OrderRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+
+	query := `
+		DELETE FROM orders
+		WHERE deleted_at < LOCALTIMESTAMP - $1::interval
+	`
 
-	_, err := f.db.Exec(ctx, "DELETE FROM orders WHERE order_id = $1", req.Id)
+	result, err := f.db.Exec(ctx, query, age(before))
 	if err != nil {
-		return err
+		return 0, mapError(err, "order")
 	}
 
-	return err
+	return result.RowsAffected(), nil
 }
@@ -2,36 +2,57 @@ package postgres
 
 import (
 	"context"
-	"fmt"
-
+	"database/sql"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	_ "github.com/jackc/pgx/v4/stdlib"
 
 	"crud/config"
+	"crud/migrations"
+	"crud/pkg/migrate"
 	"crud/storage"
 )
 
+// querier is implemented by both *pgxpool.Pool and pgx.Tx, so the repos work
+// the same inside and outside a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
 type Store struct {
 	db    *pgxpool.Pool
+	tx    pgx.Tx
+	log   *slog.Logger
 	book  *BookRepo
 	user  *UserRepo
 	order *OrderRepo
+	totp  *TOTPRepo
+	keys  *APIKeyRepo
+	ids   *IdentityRepo
+	audit *AuditRepo
 }
 
-func NewPostgres(ctx context.Context, cfg config.Config) (storage.StorageI, error) {
-	config, err := pgxpool.ParseConfig(fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		cfg.PostgresUser,
-		cfg.PostgresPassword,
-		cfg.PostgresHost,
-		cfg.PostgresPort,
-		cfg.PostgresDatabase,
-	))
+func NewPostgres(ctx context.Context, cfg config.Config, log *slog.Logger) (storage.StorageI, error) {
+	config, err := pgxpool.ParseConfig(connString(cfg))
 	if err != nil {
 		return nil, err
 	}
 
 	config.MaxConns = cfg.PostgresMaxConnections
 
+	if cfg.PostgresStatementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(time.Duration(cfg.PostgresStatementTimeout).Milliseconds(), 10)
+	}
+
 	pool, err := pgxpool.ConnectConfig(ctx, config)
 	if err != nil {
 		return nil, err
@@ -39,20 +60,89 @@ func NewPostgres(ctx context.Context, cfg config.Config) (storage.StorageI, erro
 
 	return &Store{
 		db:    pool,
-		book:  NewBookRepo(pool),
-		user:  NewUserRepo(pool),
-		order: NewOrderRepo(pool),
+		log:   log,
+		book:  NewBookRepo(instrumentedQuerier{pool, log}),
+		user:  NewUserRepo(instrumentedQuerier{pool, log}),
+		order: NewOrderRepo(instrumentedQuerier{pool, log}),
+		totp:  NewTOTPRepo(instrumentedQuerier{pool, log}),
+		keys:  NewAPIKeyRepo(instrumentedQuerier{pool, log}),
+		ids:   NewIdentityRepo(instrumentedQuerier{pool, log}),
+		audit: NewAuditRepo(instrumentedQuerier{pool, log}),
 	}, err
 }
 
+// OpenDB opens a database/sql handle to the same database, used by the
+// migration runner.
+func OpenDB(cfg config.Config) (*sql.DB, error) {
+	return sql.Open("pgx", connString(cfg))
+}
+
+// connString builds the URL of the database. The user and password are
+// escaped, so they may contain characters such as @ or /.
+func connString(cfg config.Config) string {
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.PostgresUser, cfg.PostgresPassword),
+		Host:     net.JoinHostPort(cfg.PostgresHost, cfg.PostgresPort),
+		Path:     "/" + cfg.PostgresDatabase,
+		RawQuery: "sslmode=disable",
+	}
+
+	return dsn.String()
+}
+
 func (s *Store) CloseDB() {
 	s.db.Close()
 }
 
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+func (s *Store) SchemaVersion(ctx context.Context) (uint, uint, error) {
+
+	latest, err := migrate.LatestVersion(migrations.Postgres())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var current int64
+
+	err = s.db.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return 0, latest, err
+	}
+
+	return uint(current), latest, nil
+}
+
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.StorageI) error) error {
+
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		return fn(&Store{
+			db:    s.db,
+			tx:    tx,
+			log:   s.log,
+			book:  NewBookRepo(instrumentedQuerier{tx, s.log}),
+			user:  NewUserRepo(instrumentedQuerier{tx, s.log}),
+			order: NewOrderRepo(instrumentedQuerier{tx, s.log}),
+			totp:  NewTOTPRepo(instrumentedQuerier{tx, s.log}),
+			keys:  NewAPIKeyRepo(instrumentedQuerier{tx, s.log}),
+			ids:   NewIdentityRepo(instrumentedQuerier{tx, s.log}),
+			audit: NewAuditRepo(instrumentedQuerier{tx, s.log}),
+		})
+	})
+}
+
 func (s *Store) Book() storage.BookRepoI {
 
 	if s.book == nil {
-		s.book = NewBookRepo(s.db)
+		s.book = NewBookRepo(instrumentedQuerier{s.db, s.log})
 	}
 
 	return s.book
@@ -61,7 +151,7 @@ func (s *Store) Book() storage.BookRepoI {
 func (s *Store) User() storage.UserRepoI {
 
 	if s.user == nil {
-		s.user = NewUserRepo(s.db)
+		s.user = NewUserRepo(instrumentedQuerier{s.db, s.log})
 	}
 
 	return s.user
@@ -70,8 +160,52 @@ func (s *Store) User() storage.UserRepoI {
 func (s *Store) Order() storage.OrderRepoI {
 
 	if s.order == nil {
-		s.order = NewOrderRepo(s.db)
+		s.order = NewOrderRepo(instrumentedQuerier{s.db, s.log})
 	}
 
 	return s.order
 }
+
+func (s *Store) TOTP() storage.TOTPRepoI {
+
+	if s.totp == nil {
+		s.totp = NewTOTPRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.totp
+}
+
+func (s *Store) APIKey() storage.APIKeyRepoI {
+
+	if s.keys == nil {
+		s.keys = NewAPIKeyRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.keys
+}
+
+func (s *Store) Identity() storage.IdentityRepoI {
+
+	if s.ids == nil {
+		s.ids = NewIdentityRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.ids
+}
+
+func (s *Store) Audit() storage.AuditRepoI {
+
+	if s.audit == nil {
+		s.audit = NewAuditRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.audit
+}
+
+// age is how long ago before was, for cutoffs such as
+// "deleted_at < LOCALTIMESTAMP - $1::interval". The TIMESTAMP columns hold
+// now() in the session's TimeZone, so comparing them with a UTC time would
+// be off by its offset.
+func age(before time.Time) time.Duration {
+	return time.Since(before)
+}
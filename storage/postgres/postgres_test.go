@@ -0,0 +1,75 @@
+package postgres_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"crud/config"
+	"crud/migrations"
+	"crud/pkg/migrate"
+	"crud/storage"
+	"crud/storage/postgres"
+	"crud/storage/storagetest"
+)
+
+// testDatabaseEnv names the database the suite runs against. Every subtest
+// reverts and reapplies its migrations, so it must be a throwaway database.
+// The other connection settings are read like the server's, from POSTGRES_*.
+const testDatabaseEnv = "POSTGRES_TEST_DATABASE"
+
+func TestStorage(t *testing.T) {
+
+	database := os.Getenv(testDatabaseEnv)
+	if database == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	cfg, _, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.StorageDriver = config.StorageDriverPostgres
+	cfg.PostgresDatabase = database
+
+	storagetest.Run(t, func(t *testing.T) storage.StorageI {
+		return newStorage(t, cfg)
+	})
+}
+
+// newStorage empties the database by reverting every migration and then
+// applies them again.
+func newStorage(t *testing.T, cfg config.Config) storage.StorageI {
+	ctx := context.Background()
+
+	db, err := postgres.OpenDB(cfg)
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, migrate.Postgres, migrations.Postgres())
+	if err != nil {
+		t.Fatalf("migrate.New: %v", err)
+	}
+
+	err = migrator.Goto(ctx, 0)
+	if err != nil {
+		t.Fatalf("migrate goto 0: %v", err)
+	}
+
+	err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	store, err := postgres.NewPostgres(ctx, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewPostgres: %v", err)
+	}
+	t.Cleanup(store.CloseDB)
+
+	return store
+}
@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"crud/models"
+)
+
+type TOTPRepo struct {
+	db querier
+}
+
+func NewTOTPRepo(db querier) *TOTPRepo {
+	return &TOTPRepo{
+		db: db,
+	}
+}
+
+func (f *TOTPRepo) Create(ctx context.Context, req *models.CreateTOTP) error {
+
+	query := `
+		INSERT INTO user_totp(
+			user_id,
+			secret
+		) VALUES ( $1, $2 )
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = excluded.secret,
+			enabled_at = NULL,
+			last_step = 0,
+			created_at = now()
+	`
+
+	_, err := f.db.Exec(ctx, query, req.User_id, req.Secret)
+	if err != nil {
+		return mapError(err, "totp")
+	}
+
+	_, err = f.db.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", req.User_id)
+	if err != nil {
+		return mapError(err, "recovery code")
+	}
+
+	return nil
+}
+
+func (f *TOTPRepo) GetByPKey(ctx context.Context, pkey *models.TOTPPrimarKey) (*models.TOTP, error) {
+
+	var (
+		userID    sql.NullString
+		secret    sql.NullString
+		enabled   sql.NullBool
+		lastStep  sql.NullInt64
+		codesLeft sql.NullInt32
+		createdAt sql.NullString
+	)
+
+	query := `
+		SELECT
+			user_id,
+			secret,
+			enabled_at IS NOT NULL,
+			last_step,
+			(SELECT COUNT(*) FROM recovery_codes WHERE recovery_codes.user_id = user_totp.user_id AND used_at IS NULL),
+			created_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	err := f.db.QueryRow(ctx, query, pkey.User_id).
+		Scan(
+			&userID,
+			&secret,
+			&enabled,
+			&lastStep,
+			&codesLeft,
+			&createdAt,
+		)
+	if err != nil {
+		return nil, mapError(err, "totp")
+	}
+
+	return &models.TOTP{
+		User_id:           userID.String,
+		Secret:            secret.String,
+		Enabled:           enabled.Bool,
+		LastStep:          lastStep.Int64,
+		RecoveryCodesLeft: codesLeft.Int32,
+		CreatedAt:         createdAt.String,
+	}, nil
+}
+
+func (f *TOTPRepo) Enable(ctx context.Context, req *models.TOTPPrimarKey) (int64, error) {
+
+	query := `
+		UPDATE
+			user_totp
+		SET
+			enabled_at = now()
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`
+
+	result, err := f.db.Exec(ctx, query, req.User_id)
+	if err != nil {
+		return 0, mapError(err, "totp")
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (f *TOTPRepo) UseStep(ctx context.Context, req *models.UseTOTPStep) (int64, error) {
+
+	query := `
+		UPDATE
+			user_totp
+		SET
+			last_step = $1
+		WHERE user_id = $2 AND last_step < $3
+	`
+
+	result, err := f.db.Exec(ctx, query, req.Step, req.User_id, req.Step)
+	if err != nil {
+		return 0, mapError(err, "totp")
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (f *TOTPRepo) SetRecoveryCodes(ctx context.Context, req *models.SetRecoveryCodes) error {
+
+	_, err := f.db.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", req.User_id)
+	if err != nil {
+		return mapError(err, "recovery code")
+	}
+
+	for _, hash := range req.CodeHashes {
+
+		_, err = f.db.Exec(ctx, "INSERT INTO recovery_codes(user_id, code_hash) VALUES ( $1, $2 )", req.User_id, hash)
+		if err != nil {
+			return mapError(err, "recovery code")
+		}
+	}
+
+	return nil
+}
+
+func (f *TOTPRepo) UseRecoveryCode(ctx context.Context, req *models.UseRecoveryCode) (int64, error) {
+
+	query := `
+		UPDATE
+			recovery_codes
+		SET
+			used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := f.db.Exec(ctx, query, req.User_id, req.CodeHash)
+	if err != nil {
+		return 0, mapError(err, "recovery code")
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (f *TOTPRepo) Delete(ctx context.Context, req *models.TOTPPrimarKey) (int64, error) {
+
+	_, err := f.db.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", req.User_id)
+	if err != nil {
+		return 0, mapError(err, "recovery code")
+	}
+
+	result, err := f.db.Exec(ctx, "DELETE FROM user_totp WHERE user_id = $1", req.User_id)
+	if err != nil {
+		return 0, mapError(err, "totp")
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -4,19 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v4/pgxpool"
 
 	"crud/models"
 	"crud/pkg/helper"
 )
 
 type UserRepo struct {
-	db *pgxpool.Pool
+	db querier
 }
 
-func NewUserRepo(db *pgxpool.Pool) *UserRepo {
+func NewUserRepo(db querier) *UserRepo {
 	return &UserRepo{
 		db: db,
 	}
@@ -38,8 +38,9 @@ func (f *UserRepo) Create(ctx context.Context, user *models.CreateUser) (string,
 			password,
 			phone_number,
 			balance,
+			email,
 			updated_at
-		) VALUES ( $1, $2, $3, $4, $5, $6, $7, now() )
+		) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, now() )
 	`
 
 	_, err := f.db.Exec(ctx, query,
@@ -50,10 +51,11 @@ func (f *UserRepo) Create(ctx context.Context, user *models.CreateUser) (string,
 		user.Password,
 		user.Phone_number,
 		user.Balance,
+		sql.NullString{String: user.Email, Valid: user.Email != ""},
 	)
 
 	if err != nil {
-		return "", err
+		return "", mapError(err, "user")
 	}
 
 	return id, nil
@@ -69,8 +71,14 @@ func (f *UserRepo) GetByPKey(ctx context.Context, pkey *models.UserPrimarKey) (*
 		password     sql.NullString
 		phone_number sql.NullString
 		balance      sql.NullFloat64
+		email        sql.NullString
+		verified     sql.NullBool
+		isAdmin      sql.NullBool
 		createdAt    sql.NullString
 		updatedAt    sql.NullString
+		version      sql.NullInt64
+		deletedAt    sql.NullString
+		erasedAt     sql.NullString
 	)
 
 	if len(pkey.Login) > 0 {
@@ -79,7 +87,18 @@ func (f *UserRepo) GetByPKey(ctx context.Context, pkey *models.UserPrimarKey) (*
 			Scan(&pkey.Id)
 
 		if err != nil {
-			return nil, err
+			return nil, mapError(err, "user")
+		}
+
+	}
+
+	if len(pkey.Email) > 0 {
+
+		err := f.db.QueryRow(ctx, "SELECT user_id FROM users WHERE email = $1", pkey.Email).
+			Scan(&pkey.Id)
+
+		if err != nil {
+			return nil, mapError(err, "user")
 		}
 
 	}
@@ -93,13 +112,23 @@ func (f *UserRepo) GetByPKey(ctx context.Context, pkey *models.UserPrimarKey) (*
 			password,
 			phone_number,
 			balance,
+			email,
+			email_verified_at IS NOT NULL,
+			is_admin,
 			created_at,
-			updated_at
+			updated_at,
+			version,
+			deleted_at,
+			erased_at
 		FROM
 			users
 		WHERE user_id = $1
 	`
 
+	if !pkey.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+
 	err := f.db.QueryRow(ctx, query, pkey.Id).
 		Scan(
 			&id,
@@ -109,24 +138,36 @@ func (f *UserRepo) GetByPKey(ctx context.Context, pkey *models.UserPrimarKey) (*
 			&password,
 			&phone_number,
 			&balance,
+			&email,
+			&verified,
+			&isAdmin,
 			&createdAt,
 			&updatedAt,
+			&version,
+			&deletedAt,
+			&erasedAt,
 		)
 
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "user")
 	}
 
 	return &models.User{
-		Id:           id.String,
-		First_name:   first_name.String,
-		Last_name:    last_name.String,
-		Login:        login.String,
-		Password:     password.String,
-		Phone_number: phone_number.String,
-		Balance:      balance.Float64,
-		CreatedAt:    createdAt.String,
-		UpdatedAt:    updatedAt.String,
+		Id:            id.String,
+		First_name:    first_name.String,
+		Last_name:     last_name.String,
+		Login:         login.String,
+		Password:      password.String,
+		Phone_number:  phone_number.String,
+		Balance:       balance.Float64,
+		Email:         email.String,
+		EmailVerified: verified.Bool,
+		IsAdmin:       isAdmin.Bool,
+		CreatedAt:     createdAt.String,
+		UpdatedAt:     updatedAt.String,
+		Version:       version.Int64,
+		DeletedAt:     deletedAt.String,
+		ErasedAt:      erasedAt.String,
 	}, nil
 }
 
@@ -136,6 +177,7 @@ func (f *UserRepo) GetList(ctx context.Context, req *models.GetListUserRequest)
 		resp   = models.GetListUserResponse{}
 		offset = ""
 		limit  = ""
+		where  = " WHERE deleted_at IS NULL"
 	)
 
 	if req.Limit > 0 {
@@ -146,6 +188,10 @@ func (f *UserRepo) GetList(ctx context.Context, req *models.GetListUserRequest)
 		offset = fmt.Sprintf(" OFFSET %d", req.Offset)
 	}
 
+	if req.IncludeDeleted {
+		where = ""
+	}
+
 	query := `
 		SELECT
 			COUNT(*) OVER(),
@@ -156,15 +202,25 @@ func (f *UserRepo) GetList(ctx context.Context, req *models.GetListUserRequest)
 			password,
 			phone_number,
 			balance,
+			email,
+			email_verified_at IS NOT NULL,
+			is_admin,
 			created_at,
-			updated_at
+			updated_at,
+			version,
+			deleted_at,
+			erased_at
 		FROM
 			users
-	`
+	` + where
 
 	query += offset + limit
 
 	rows, err := f.db.Query(ctx, query)
+	if err != nil {
+		return nil, mapError(err, "user")
+	}
+	defer rows.Close()
 
 	for rows.Next() {
 
@@ -176,8 +232,14 @@ func (f *UserRepo) GetList(ctx context.Context, req *models.GetListUserRequest)
 			password     sql.NullString
 			phone_number sql.NullString
 			balance      sql.NullFloat64
+			email        sql.NullString
+			verified     sql.NullBool
+			isAdmin      sql.NullBool
 			createdAt    sql.NullString
 			updatedAt    sql.NullString
+			version      sql.NullInt64
+			deletedAt    sql.NullString
+			erasedAt     sql.NullString
 		)
 
 		err := rows.Scan(
@@ -189,78 +251,203 @@ func (f *UserRepo) GetList(ctx context.Context, req *models.GetListUserRequest)
 			&password,
 			&phone_number,
 			&balance,
+			&email,
+			&verified,
+			&isAdmin,
 			&createdAt,
 			&updatedAt,
+			&version,
+			&deletedAt,
+			&erasedAt,
 		)
 
 		if err != nil {
-			return nil, err
+			return nil, mapError(err, "user")
 		}
 
 		resp.Users = append(resp.Users, &models.User{
-			Id:           id.String,
-			First_name:   first_name.String,
-			Last_name:    last_name.String,
-			Login:        login.String,
-			Password:     password.String,
-			Phone_number: phone_number.String,
-			Balance:      balance.Float64,
-			CreatedAt:    createdAt.String,
-			UpdatedAt:    updatedAt.String,
+			Id:            id.String,
+			First_name:    first_name.String,
+			Last_name:     last_name.String,
+			Login:         login.String,
+			Password:      password.String,
+			Phone_number:  phone_number.String,
+			Balance:       balance.Float64,
+			Email:         email.String,
+			EmailVerified: verified.Bool,
+			IsAdmin:       isAdmin.Bool,
+			CreatedAt:     createdAt.String,
+			UpdatedAt:     updatedAt.String,
+			Version:       version.Int64,
+			DeletedAt:     deletedAt.String,
+			ErasedAt:      erasedAt.String,
 		})
 
 	}
 
-	return &resp, err
+	return &resp, rows.Err()
 }
 
 func (f *UserRepo) Update(ctx context.Context, req *models.UpdateUser) (int64, error) {
+	return f.Patch(ctx, &models.PatchUser{
+		Id:           req.Id,
+		First_name:   &req.First_name,
+		Last_name:    &req.Last_name,
+		Login:        &req.Login,
+		Password:     &req.Password,
+		Phone_number: &req.Phone_number,
+		Balance:      &req.Balance,
+		Version:      req.Version,
+	})
+}
 
-	var (
-		query  = ""
-		params map[string]interface{}
-	)
+func (f *UserRepo) Patch(ctx context.Context, req *models.PatchUser) (int64, error) {
 
-	query = `
+	q := helper.NewUpdateQuery("users", helper.Dollar)
+
+	if req.First_name != nil {
+		q.Set("first_name", *req.First_name)
+	}
+	if req.Last_name != nil {
+		q.Set("last_name", *req.Last_name)
+	}
+	if req.Login != nil {
+		q.Set("login", *req.Login)
+	}
+	if req.Password != nil {
+		q.Set("password", *req.Password)
+	}
+	if req.Phone_number != nil {
+		q.Set("phone_number", *req.Phone_number)
+	}
+	if req.Balance != nil {
+		q.Set("balance", *req.Balance)
+	}
+	if req.IsAdmin != nil {
+		q.Set("is_admin", *req.IsAdmin)
+	}
+	if req.EmailVerified {
+		q.SetExpr("email_verified_at", "now()")
+	}
+
+	q.SetExpr("updated_at", "now()").
+		SetExpr("version", "version + 1").
+		Where("user_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
+	}
+
+	query, args := q.Build()
+
+	result, err := f.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (f *UserRepo) UpdateBalance(ctx context.Context, req *models.UpdateBalance) (int64, error) {
+
+	query := `
 		UPDATE
 			users
 		SET
-			first_name = :first_name,
-			last_name = :last_name,
-			login = :login,
-			password = :password,
-			phone_number = :phone_number,
-			balance = :balance,
+			balance = balance + $2,
+			version = version + 1,
 			updated_at = now()
-		WHERE user_id = :user_id
+		WHERE user_id = $1 AND balance + $2 >= 0
 	`
 
-	params = map[string]interface{}{
-		"user_id":      req.Id,
-		"first_name":   req.First_name,
-		"last_name":    req.Last_name,
-		"login":        req.Login,
-		"password":     req.Password,
-		"phone_number": req.Phone_number,
-		"balance":      req.Balance,
+	rowsAffected, err := f.db.Exec(ctx, query, req.Id, req.Amount)
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+
+	return rowsAffected.RowsAffected(), nil
+}
+
+func (f *UserRepo) Delete(ctx context.Context, req *models.UserPrimarKey) (int64, error) {
+
+	q := helper.NewUpdateQuery("users", helper.Dollar).
+		SetExpr("deleted_at", "now()").
+		SetExpr("updated_at", "now()").
+		SetExpr("version", "version + 1").
+		Where("user_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
 	}
 
-	query, args := helper.ReplaceQueryParams(query, params)
+	query, args := q.Build()
 
-	rowsAffected, err := f.db.Exec(ctx, query, args...)
+	result, err := f.db.Exec(ctx, query, args...)
 	if err != nil {
-		return 0, err
+		return 0, mapError(err, "user")
 	}
 
-	return rowsAffected.RowsAffected(), nil
+	return result.RowsAffected(), nil
+}
+
+func (f *UserRepo) Restore(ctx context.Context, req *models.UserPrimarKey) (int64, error) {
+
+	query, args := helper.NewUpdateQuery("users", helper.Dollar).
+		SetExpr("deleted_at", "NULL").
+		SetExpr("updated_at", "now()").
+		SetExpr("version", "version + 1").
+		Where("user_id", req.Id).
+		WhereNull("deleted_at", false).
+		Build()
+
+	result, err := f.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+
+	return result.RowsAffected(), nil
 }
 
-func (f *UserRepo) Delete(ctx context.Context, req *models.UserPrimarKey) error {
+func (f *UserRepo) Erase(ctx context.Context, req *models.EraseUser) (int64, error) {
+
+	query, args := helper.NewUpdateQuery("users", helper.Dollar).
+		Set("first_name", req.First_name).
+		Set("last_name", req.Last_name).
+		Set("login", req.Login).
+		Set("password", "").
+		Set("phone_number", "").
+		SetExpr("email", "NULL").
+		SetExpr("email_verified_at", "NULL").
+		Set("is_admin", false).
+		SetExpr("erased_at", "now()").
+		SetExpr("updated_at", "now()").
+		SetExpr("version", "version + 1").
+		Where("user_id", req.Id).
+		WhereNull("erased_at", true).
+		Build()
+
+	result, err := f.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (f *UserRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+
+	query := `
+		DELETE FROM users
+		WHERE deleted_at < LOCALTIMESTAMP - $1::interval
+			AND NOT EXISTS (SELECT 1 FROM orders WHERE orders.user_id = users.user_id)
+	`
 
-	_, err := f.db.Exec(ctx, "DELETE FROM users WHERE user_id = $1", req.Id)
+	result, err := f.db.Exec(ctx, query, age(before))
 	if err != nil {
-		return err
+		return 0, mapError(err, "user")
 	}
 
-	return err
+	return result.RowsAffected(), nil
 }
@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"crud/models"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// idempotencyPrefix namespaces the keys, which are chosen by clients.
+const idempotencyPrefix = "idempotency:"
+
+type IdempotencyRepo struct {
+	client *redis.Client
+}
+
+func NewIdempotencyRepo(client *redis.Client) *IdempotencyRepo {
+	return &IdempotencyRepo{
+		client: client,
+	}
+}
+
+func (i *IdempotencyRepo) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*models.IdempotentResponse, error) {
+
+	pending, err := json.Marshal(models.IdempotentResponse{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+
+	// The record can expire between SETNX and GET, so try twice.
+	for attempt := 0; attempt < 2; attempt++ {
+
+		ok, err := i.client.SetNX(ctx, idempotencyPrefix+key, pending, ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return nil, nil
+		}
+
+		stored, err := i.client.Get(ctx, idempotencyPrefix+key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var resp models.IdempotentResponse
+
+		err = json.Unmarshal(stored, &resp)
+		if err != nil {
+			return nil, err
+		}
+
+		return &resp, nil
+	}
+
+	return nil, errors.New("idempotency key expired while it was reserved")
+}
+
+func (i *IdempotencyRepo) Save(ctx context.Context, key string, resp *models.IdempotentResponse, ttl time.Duration) error {
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	return i.client.Set(ctx, idempotencyPrefix+key, body, ttl).Err()
+}
+
+func (i *IdempotencyRepo) Delete(ctx context.Context, key string) error {
+	return i.client.Del(ctx, idempotencyPrefix+key).Err()
+}
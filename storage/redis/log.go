@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type startKey struct{}
+
+// logHook logs every command at debug level with the caller's context, so
+// the lines carry the request id.
+type logHook struct {
+	log *slog.Logger
+}
+
+func (h logHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (h logHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+
+	if !h.log.Enabled(ctx, slog.LevelDebug) {
+		return nil
+	}
+
+	attrs := []any{slog.String("command", cmd.Name())}
+	if start, ok := ctx.Value(startKey{}).(time.Time); ok {
+		attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+	}
+	if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	h.log.DebugContext(ctx, "redis command", attrs...)
+
+	return nil
+}
+
+func (h logHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h logHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	loginFailuresPrefix = "login:failures:"
+	loginLockPrefix     = "login:lock:"
+)
+
+type LoginAttemptsRepo struct {
+	client *redis.Client
+}
+
+func NewLoginAttemptsRepo(client *redis.Client) *LoginAttemptsRepo {
+	return &LoginAttemptsRepo{
+		client: client,
+	}
+}
+
+func (l *LoginAttemptsRepo) Locked(ctx context.Context, login string) (time.Duration, error) {
+
+	ttl, err := l.client.PTTL(ctx, loginLockPrefix+login).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// PTTL is negative when the key does not exist.
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (l *LoginAttemptsRepo) Fail(ctx context.Context, login string, window time.Duration) (int64, error) {
+
+	var incr *redis.IntCmd
+
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, loginFailuresPrefix+login)
+		// Failures are forgotten after window without another one.
+		pipe.Expire(ctx, loginFailuresPrefix+login, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+func (l *LoginAttemptsRepo) Lock(ctx context.Context, login string, d time.Duration) error {
+	return l.client.Set(ctx, loginLockPrefix+login, 1, d).Err()
+}
+
+func (l *LoginAttemptsRepo) Reset(ctx context.Context, login string) error {
+	return l.client.Del(ctx, loginFailuresPrefix+login, loginLockPrefix+login).Err()
+}
@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"crud/storage"
+)
+
+const oneTimeTokenPrefix = "token:"
+
+type OneTimeTokenRepo struct {
+	client *redis.Client
+}
+
+func NewOneTimeTokenRepo(client *redis.Client) *OneTimeTokenRepo {
+	return &OneTimeTokenRepo{
+		client: client,
+	}
+}
+
+func (t *OneTimeTokenRepo) Create(ctx context.Context, purpose, token, userID string, ttl time.Duration) error {
+	return t.client.Set(ctx, oneTimeTokenKey(purpose, token), userID, ttl).Err()
+}
+
+func (t *OneTimeTokenRepo) Consume(ctx context.Context, purpose, token string) (string, error) {
+
+	var (
+		key = oneTimeTokenKey(purpose, token)
+		get *redis.StringCmd
+	)
+
+	_, err := t.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err == redis.Nil {
+		return "", storage.ErrCacheMiss
+	} else if err != nil {
+		return "", err
+	}
+
+	return get.Val(), nil
+}
+
+// oneTimeTokenKey hashes the token so a dump of the cache cannot be used to
+// verify an email or reset a password.
+func oneTimeTokenKey(purpose, token string) string {
+
+	sum := sha256.Sum256([]byte(token))
+
+	return oneTimeTokenPrefix + purpose + ":" + hex.EncodeToString(sum[:])
+}
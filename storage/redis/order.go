@@ -3,18 +3,22 @@ package redis
 import (
 	"context"
 	"crud/models"
+	"crud/pkg/metrics"
+	"crud/storage"
 	"encoding/json"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 )
 
 type OrderRepo struct {
-	client *redis.Client
+	client  *redis.Client
+	metrics *metrics.Metrics
 }
 
-func NewOrderRepo(client *redis.Client) *OrderRepo {
+func NewOrderRepo(client *redis.Client, metrics *metrics.Metrics) *OrderRepo {
 	return &OrderRepo{
-		client: client,
+		client:  client,
+		metrics: metrics,
 	}
 }
 
@@ -25,7 +29,7 @@ func (u *OrderRepo) Create(ctx context.Context, req *models.GetListOrderResponse
 		return err
 	}
 
-	err = u.client.Set("orders", orders, 0).Err()
+	err = u.client.Set(ctx, "orders", orders, 0).Err()
 	if err != nil {
 		return err
 	}
@@ -33,11 +37,16 @@ func (u *OrderRepo) Create(ctx context.Context, req *models.GetListOrderResponse
 	return nil
 }
 
-func (u *OrderRepo) GetList(ctx context.Context) (*models.GetListOrderResponse, error) {
+func (u *OrderRepo) GetList(ctx context.Context) (_ *models.GetListOrderResponse, err error) {
+
+	defer func() { u.metrics.CacheResult("orders", err) }()
 
 	var resp = models.GetListOrderResponse{}
 
-	orders, err := u.client.Get("orders").Result()
+	orders, err := u.client.Get(ctx, "orders").Result()
+	if err == redis.Nil {
+		return nil, storage.ErrCacheMiss
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +68,7 @@ func (u *OrderRepo) Update(ctx context.Context, req *models.GetListOrderResponse
 		return err
 	}
 
-	err = u.client.Set("orders", orders, 0).Err()
+	err = u.client.Set(ctx, "orders", orders, 0).Err()
 	if err != nil {
 		return err
 	}
@@ -69,6 +78,6 @@ func (u *OrderRepo) Update(ctx context.Context, req *models.GetListOrderResponse
 
 func (u *OrderRepo) Delete(ctx context.Context) error {
 
-	err := u.client.Del("orders").Err()
+	err := u.client.Del(ctx, "orders").Err()
 	return err
 }
@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"context"
+	"crud/models"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const rateLimitPrefix = "ratelimit:"
+
+// slidingWindow keeps the timestamps of the requests within the window in a
+// sorted set. It returns {allowed, count, retry after in ms}.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local member = ARGV[4]
+
+redis.call("ZREMRANGEBYSCORE", key, "-inf", now - window)
+
+local count = redis.call("ZCARD", key)
+if count >= limit then
+	local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+	return {0, count, tonumber(oldest[2]) + window - now}
+end
+
+redis.call("ZADD", key, now, member)
+redis.call("PEXPIRE", key, window)
+
+return {1, count + 1, 0}
+`)
+
+// rateLimitSeq tells apart requests counted in the same nanosecond.
+var rateLimitSeq atomic.Uint64
+
+type RateLimitRepo struct {
+	client *redis.Client
+}
+
+func NewRateLimitRepo(client *redis.Client) *RateLimitRepo {
+	return &RateLimitRepo{
+		client: client,
+	}
+}
+
+func (r *RateLimitRepo) Allow(ctx context.Context, key string, limit int, window time.Duration) (*models.RateLimitResult, error) {
+
+	now := time.Now()
+
+	// The member only has to be unique, or concurrent requests would be
+	// counted once.
+	member := strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.FormatUint(rateLimitSeq.Add(1), 36)
+
+	values, err := slidingWindow.Run(ctx, r.client,
+		[]string{rateLimitPrefix + key},
+		now.UnixMilli(), window.Milliseconds(), limit, member,
+	).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := models.RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  limit - int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}
+	if resp.Remaining < 0 {
+		resp.Remaining = 0
+	}
+
+	return &resp, nil
+}
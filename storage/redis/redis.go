@@ -2,20 +2,28 @@ package redis
 
 import (
 	"context"
+	"log/slog"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 
 	"crud/config"
+	"crud/pkg/metrics"
 	"crud/storage"
 )
 
 type Cache struct {
-	client *redis.Client
-	user   *UserRepo
-	order  *OrderRepo
+	client      *redis.Client
+	metrics     *metrics.Metrics
+	user        *UserRepo
+	order       *OrderRepo
+	idempotency *IdempotencyRepo
+	rateLimit   *RateLimitRepo
+	login       *LoginAttemptsRepo
+	session     *SessionRepo
+	tokens      *OneTimeTokenRepo
 }
 
-func NewRedis(ctx context.Context, cfg config.Config) (storage.CacheI, error) {
+func NewRedis(ctx context.Context, cfg config.Config, log *slog.Logger, metrics *metrics.Metrics) (storage.CacheI, error) {
 
 	var client = redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisAddr,
@@ -23,12 +31,22 @@ func NewRedis(ctx context.Context, cfg config.Config) (storage.CacheI, error) {
 		DB:       cfg.RedisDB,
 	})
 
-	err := client.Ping().Err()
+	client.AddHook(traceHook{})
+	client.AddHook(logHook{log: log})
+
+	err := client.Ping(ctx).Err()
 
 	return &Cache{
-		client: client,
-		user:   NewUserRepo(client),
-		order:  NewOrderRepo(client),
+		client:  client,
+		metrics: metrics,
+		user:    NewUserRepo(client, metrics),
+		order:   NewOrderRepo(client, metrics),
+
+		idempotency: NewIdempotencyRepo(client),
+		rateLimit:   NewRateLimitRepo(client),
+		login:       NewLoginAttemptsRepo(client),
+		session:     NewSessionRepo(client),
+		tokens:      NewOneTimeTokenRepo(client),
 	}, err
 }
 
@@ -36,10 +54,14 @@ func (c *Cache) CloseDB() {
 	c.client.Close()
 }
 
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
 func (c *Cache) User() storage.UserCacheI {
 
 	if c.user == nil {
-		c.user = NewUserRepo(c.user.client)
+		c.user = NewUserRepo(c.client, c.metrics)
 	}
 
 	return c.user
@@ -48,8 +70,53 @@ func (c *Cache) User() storage.UserCacheI {
 func (c *Cache) Order() storage.OrderCacheI {
 
 	if c.order == nil {
-		c.order = NewOrderRepo(c.order.client)
+		c.order = NewOrderRepo(c.client, c.metrics)
 	}
 
 	return c.order
 }
+
+func (c *Cache) Idempotency() storage.IdempotencyCacheI {
+
+	if c.idempotency == nil {
+		c.idempotency = NewIdempotencyRepo(c.client)
+	}
+
+	return c.idempotency
+}
+
+func (c *Cache) RateLimit() storage.RateLimitCacheI {
+
+	if c.rateLimit == nil {
+		c.rateLimit = NewRateLimitRepo(c.client)
+	}
+
+	return c.rateLimit
+}
+
+func (c *Cache) LoginAttempts() storage.LoginAttemptsCacheI {
+
+	if c.login == nil {
+		c.login = NewLoginAttemptsRepo(c.client)
+	}
+
+	return c.login
+}
+
+func (c *Cache) Session() storage.SessionCacheI {
+
+	if c.session == nil {
+		c.session = NewSessionRepo(c.client)
+	}
+
+	return c.session
+}
+
+func (c *Cache) Tokens() storage.OneTimeTokenCacheI {
+
+	if c.tokens == nil {
+		c.tokens = NewOneTimeTokenRepo(c.client)
+	}
+
+	return c.tokens
+}
@@ -0,0 +1,198 @@
+package redis
+
+import (
+	"context"
+	"crud/models"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// A session is stored under sessionPrefix+jti and indexed in a sorted set
+// per user scored by its expiry. Revoked tokens are denied by jti until they
+// expire, and revokedBeforePrefix+user holds the time before which all the
+// user's tokens are denied.
+const (
+	sessionPrefix       = "session:"
+	userSessionsPrefix  = "sessions:"
+	revokedPrefix       = "revoked:"
+	revokedBeforePrefix = "revoked_before:"
+)
+
+type SessionRepo struct {
+	client *redis.Client
+}
+
+func NewSessionRepo(client *redis.Client) *SessionRepo {
+	return &SessionRepo{
+		client: client,
+	}
+}
+
+func (s *SessionRepo) Create(ctx context.Context, req *models.Session, ttl time.Duration) error {
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var (
+		index     = userSessionsPrefix + req.User_id
+		expiresAt = time.Now().Add(ttl)
+	)
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionPrefix+req.Id, body, ttl)
+		pipe.ZAdd(ctx, index, &redis.Z{Score: float64(expiresAt.Unix()), Member: req.Id})
+		pipe.ZRemRangeByScore(ctx, index, "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The index lives as long as its longest session.
+	current, err := s.client.PTTL(ctx, index).Result()
+	if err != nil {
+		return err
+	}
+
+	if current < ttl {
+		return s.client.PExpire(ctx, index, ttl).Err()
+	}
+
+	return nil
+}
+
+func (s *SessionRepo) GetList(ctx context.Context, userID string) ([]*models.Session, error) {
+
+	index := userSessionsPrefix + userID
+
+	ids, err := s.client.ZRangeByScore(ctx, index, &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, sessionPrefix+id)
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*models.Session
+
+	for _, value := range values {
+
+		// Revoked or expired.
+		body, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		var session models.Session
+
+		err = json.Unmarshal([]byte(body), &session)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, nil
+}
+
+func (s *SessionRepo) Revoke(ctx context.Context, req *models.Token) error {
+
+	ttl := time.Until(req.ExpiresAt)
+	if ttl <= 0 || req.Id == "" {
+		return nil
+	}
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, revokedPrefix+req.Id, 1, ttl)
+		pipe.Del(ctx, sessionPrefix+req.Id)
+		pipe.ZRem(ctx, userSessionsPrefix+req.User_id, req.Id)
+		return nil
+	})
+
+	return err
+}
+
+func (s *SessionRepo) RevokeAll(ctx context.Context, userID string, before time.Time, ttl time.Duration) error {
+
+	index := userSessionsPrefix + userID
+
+	sessions, err := s.client.ZRangeWithScores(ctx, index, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+
+		// iat has a one second resolution, so tokens issued in the same
+		// second as before are denied by jti as well.
+		for _, session := range sessions {
+			id, _ := session.Member.(string)
+			expiresIn := time.Until(time.Unix(int64(session.Score), 0))
+			if expiresIn > 0 {
+				pipe.Set(ctx, revokedPrefix+id, 1, expiresIn)
+			}
+			pipe.Del(ctx, sessionPrefix+id)
+		}
+
+		pipe.Del(ctx, index)
+		pipe.Set(ctx, revokedBeforePrefix+userID, before.Unix(), ttl)
+
+		return nil
+	})
+
+	return err
+}
+
+func (s *SessionRepo) Revoked(ctx context.Context, req *models.Token) (bool, error) {
+
+	var (
+		denied *redis.IntCmd
+		before *redis.StringCmd
+	)
+
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if req.Id != "" {
+			denied = pipe.Exists(ctx, revokedPrefix+req.Id)
+		}
+		before = pipe.Get(ctx, revokedBeforePrefix+req.User_id)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	if denied != nil && denied.Val() > 0 {
+		return true, nil
+	}
+
+	if before.Err() == redis.Nil {
+		return false, nil
+	}
+
+	revokedBefore, err := before.Int64()
+	if err != nil {
+		return false, err
+	}
+
+	return req.IssuedAt.Unix() < revokedBefore, nil
+}
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"crud/pkg/tracing"
+)
+
+// traceHook starts a client span for every command, e.g. "redis get".
+type traceHook struct{}
+
+func (traceHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+
+	ctx, _ = tracing.Tracer().Start(ctx, "redis "+cmd.Name(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemRedis,
+			semconv.DBStatement(statement(cmd)),
+		),
+	)
+
+	return ctx, nil
+}
+
+func (traceHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+
+	err := cmd.Err()
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+
+	tracing.End(trace.SpanFromContext(ctx), err)
+
+	return nil
+}
+
+func (traceHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+
+	ctx, _ = tracing.Tracer().Start(ctx, "redis pipeline",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis),
+	)
+
+	return ctx, nil
+}
+
+func (traceHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	tracing.End(trace.SpanFromContext(ctx), nil)
+	return nil
+}
+
+// statement is the command with its key only; values may be large or
+// contain personal data.
+func statement(cmd redis.Cmder) string {
+
+	args := cmd.Args()
+	if len(args) > 1 {
+		if key, ok := args[1].(string); ok {
+			return strings.ToUpper(cmd.Name()) + " " + key
+		}
+	}
+
+	return strings.ToUpper(cmd.Name())
+}
@@ -3,18 +3,22 @@ package redis
 import (
 	"context"
 	"crud/models"
+	"crud/pkg/metrics"
+	"crud/storage"
 	"encoding/json"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 )
 
 type UserRepo struct {
-	client *redis.Client
+	client  *redis.Client
+	metrics *metrics.Metrics
 }
 
-func NewUserRepo(client *redis.Client) *UserRepo {
+func NewUserRepo(client *redis.Client, metrics *metrics.Metrics) *UserRepo {
 	return &UserRepo{
-		client: client,
+		client:  client,
+		metrics: metrics,
 	}
 }
 
@@ -25,7 +29,7 @@ func (u *UserRepo) Create(ctx context.Context, req *models.GetListUserResponse)
 		return err
 	}
 
-	err = u.client.Set("users", users, 0).Err()
+	err = u.client.Set(ctx, "users", users, 0).Err()
 	if err != nil {
 		return err
 	}
@@ -33,11 +37,16 @@ func (u *UserRepo) Create(ctx context.Context, req *models.GetListUserResponse)
 	return nil
 }
 
-func (u *UserRepo) GetList(ctx context.Context) (*models.GetListUserResponse, error) {
+func (u *UserRepo) GetList(ctx context.Context) (_ *models.GetListUserResponse, err error) {
+
+	defer func() { u.metrics.CacheResult("users", err) }()
 
 	var resp = models.GetListUserResponse{}
 
-	users, err := u.client.Get("users").Result()
+	users, err := u.client.Get(ctx, "users").Result()
+	if err == redis.Nil {
+		return nil, storage.ErrCacheMiss
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +68,7 @@ func (u *UserRepo) Update(ctx context.Context, req *models.GetListUserResponse)
 		return err
 	}
 
-	err = u.client.Set("users", users, 0).Err()
+	err = u.client.Set(ctx, "users", users, 0).Err()
 	if err != nil {
 		return err
 	}
@@ -69,6 +78,6 @@ func (u *UserRepo) Update(ctx context.Context, req *models.GetListUserResponse)
 
 func (u *UserRepo) Delete(ctx context.Context) error {
 
-	err := u.client.Del("users").Err()
+	err := u.client.Del(ctx, "users").Err()
 	return err
 }
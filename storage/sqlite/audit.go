@@ -0,0 +1,236 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"crud/models"
+	"crud/pkg/helper"
+)
+
+// timestampLayout is how CURRENT_TIMESTAMP is stored, which times have to
+// be written in to compare with it.
+const timestampLayout = "2006-01-02 15:04:05"
+
+type AuditRepo struct {
+	db querier
+}
+
+func NewAuditRepo(db querier) *AuditRepo {
+	return &AuditRepo{
+		db: db,
+	}
+}
+
+func (f *AuditRepo) Create(ctx context.Context, req *models.CreateAuditLog) error {
+
+	query := `
+		INSERT INTO audit_log(
+			audit_id,
+			actor_id,
+			api_key_id,
+			action,
+			entity_type,
+			entity_id,
+			before,
+			after,
+			ip,
+			request_id
+		) VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, ?, ? )
+	`
+
+	_, err := f.db.ExecContext(ctx, query,
+		uuid.New().String(),
+		nullString(req.Actor_id),
+		nullString(req.APIKey_id),
+		req.Action,
+		req.EntityType,
+		req.Entity_id,
+		nullString(string(req.Before)),
+		nullString(string(req.After)),
+		nullString(req.IP),
+		nullString(req.RequestID),
+	)
+	if err != nil {
+		return mapError(err, "audit log")
+	}
+
+	return nil
+}
+
+func (f *AuditRepo) GetList(ctx context.Context, req *models.GetListAuditLogRequest) (*models.GetListAuditLogResponse, error) {
+
+	var (
+		resp   = models.GetListAuditLogResponse{}
+		offset = ""
+		limit  = " LIMIT -1"
+		filter = helper.NewFilter(helper.Question)
+	)
+
+	if req.Limit > 0 {
+		limit = fmt.Sprintf(" LIMIT %d", req.Limit)
+	}
+
+	if req.Offset > 0 {
+		offset = fmt.Sprintf(" OFFSET %d", req.Offset)
+	}
+
+	if req.Actor_id != "" {
+		filter.Where("actor_id", "=", req.Actor_id)
+	}
+	if req.Action != "" {
+		filter.Where("action", "=", req.Action)
+	}
+	if req.EntityType != "" {
+		filter.Where("entity_type", "=", req.EntityType)
+	}
+	if req.Entity_id != "" {
+		filter.Where("entity_id", "=", req.Entity_id)
+	}
+	if !req.From.IsZero() {
+		filter.Where("created_at", ">=", req.From.UTC().Format(timestampLayout))
+	}
+	if !req.To.IsZero() {
+		filter.Where("created_at", "<", req.To.UTC().Format(timestampLayout))
+	}
+
+	where, args := filter.Build()
+
+	query := `
+		SELECT
+			COUNT(*) OVER(),
+			audit_id,
+			actor_id,
+			api_key_id,
+			action,
+			entity_type,
+			entity_id,
+			before,
+			after,
+			ip,
+			request_id,
+			created_at
+		FROM
+			audit_log
+	` + where + `
+		ORDER BY created_at DESC, rowid DESC
+	`
+
+	// SQLite only accepts OFFSET after LIMIT.
+	query += limit + offset
+
+	rows, err := f.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(err, "audit log")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var (
+			id         sql.NullString
+			actorID    sql.NullString
+			apiKeyID   sql.NullString
+			action     sql.NullString
+			entityType sql.NullString
+			entityID   sql.NullString
+			before     sql.NullString
+			after      sql.NullString
+			ip         sql.NullString
+			requestID  sql.NullString
+			createdAt  sql.NullString
+		)
+
+		err := rows.Scan(
+			&resp.Count,
+			&id,
+			&actorID,
+			&apiKeyID,
+			&action,
+			&entityType,
+			&entityID,
+			&before,
+			&after,
+			&ip,
+			&requestID,
+			&createdAt,
+		)
+		if err != nil {
+			return nil, mapError(err, "audit log")
+		}
+
+		resp.AuditLogs = append(resp.AuditLogs, &models.AuditLog{
+			Id:         id.String,
+			Actor_id:   actorID.String,
+			APIKey_id:  apiKeyID.String,
+			Action:     action.String,
+			EntityType: entityType.String,
+			Entity_id:  entityID.String,
+			Before:     rawJSON(before),
+			After:      rawJSON(after),
+			IP:         ip.String,
+			RequestID:  requestID.String,
+			CreatedAt:  createdAt.String,
+		})
+	}
+
+	return &resp, rows.Err()
+}
+
+func (f *AuditRepo) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+
+	result, err := f.db.ExecContext(ctx, "DELETE FROM audit_log WHERE created_at < ?", before.UTC().Format(timestampLayout))
+	if err != nil {
+		return 0, mapError(err, "audit log")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *AuditRepo) Redact(ctx context.Context, req *models.RedactAuditLog) (int64, error) {
+
+	query := `
+		UPDATE
+			audit_log
+		SET
+			before = NULL,
+			after = NULL
+		WHERE entity_type = ? AND entity_id = ?
+	`
+
+	result, err := f.db.ExecContext(ctx, query, req.EntityType, req.Entity_id)
+	if err != nil {
+		return 0, mapError(err, "audit log")
+	}
+
+	values, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err = f.db.ExecContext(ctx, "UPDATE audit_log SET ip = NULL WHERE actor_id = ?", req.Actor_id)
+	if err != nil {
+		return 0, mapError(err, "audit log")
+	}
+
+	ips, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return values + ips, nil
+}
+
+func rawJSON(s sql.NullString) json.RawMessage {
+
+	if !s.Valid {
+		return nil
+	}
+
+	return json.RawMessage(s.String)
+}
@@ -0,0 +1,318 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"crud/models"
+	"crud/pkg/helper"
+)
+
+type BookRepo struct {
+	db querier
+}
+
+func NewBookRepo(db querier) *BookRepo {
+	return &BookRepo{
+		db: db,
+	}
+}
+
+func (f *BookRepo) Create(ctx context.Context, book *models.CreateBook) (string, error) {
+
+	var (
+		id    = uuid.New().String()
+		query string
+	)
+
+	query = `
+		INSERT INTO books(
+			book_id,
+			title,
+			author,
+			price,
+			isbn,
+			updated_at
+		) VALUES ( ?, ?, ?, ?, ?, CURRENT_TIMESTAMP )
+	`
+
+	_, err := f.db.ExecContext(ctx, query,
+		id,
+		book.Title,
+		book.Author,
+		book.Price,
+		nullString(book.ISBN),
+	)
+
+	if err != nil {
+		return "", mapError(err, "book")
+	}
+
+	return id, nil
+}
+
+func (f *BookRepo) GetByPKey(ctx context.Context, pkey *models.BookPrimarKey) (*models.Book, error) {
+
+	var (
+		id        sql.NullString
+		title     sql.NullString
+		author    sql.NullString
+		price     sql.NullFloat64
+		isbn      sql.NullString
+		createdAt sql.NullString
+		updatedAt sql.NullString
+		version   sql.NullInt64
+		deletedAt sql.NullString
+	)
+
+	query := `
+		SELECT
+			book_id,
+			title,
+			author,
+			price,
+			isbn,
+			created_at,
+			updated_at,
+			version,
+			deleted_at
+		FROM
+			books
+		WHERE book_id = ?
+	`
+
+	if !pkey.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+
+	err := f.db.QueryRowContext(ctx, query, pkey.Id).
+		Scan(
+			&id,
+			&title,
+			&author,
+			&price,
+			&isbn,
+			&createdAt,
+			&updatedAt,
+			&version,
+			&deletedAt,
+		)
+
+	if err != nil {
+		return nil, mapError(err, "book")
+	}
+
+	return &models.Book{
+		Id:        id.String,
+		Title:     title.String,
+		Author:    author.String,
+		Price:     price.Float64,
+		ISBN:      isbn.String,
+		CreatedAt: createdAt.String,
+		UpdatedAt: updatedAt.String,
+		Version:   version.Int64,
+		DeletedAt: deletedAt.String,
+	}, nil
+}
+
+func (f *BookRepo) GetList(ctx context.Context, req *models.GetListBookRequest) (*models.GetListBookResponse, error) {
+
+	var (
+		resp   = models.GetListBookResponse{}
+		offset = ""
+		limit  = " LIMIT -1"
+		where  = " WHERE deleted_at IS NULL"
+	)
+
+	if req.Limit > 0 {
+		limit = fmt.Sprintf(" LIMIT %d", req.Limit)
+	}
+
+	if req.Offset > 0 {
+		offset = fmt.Sprintf(" OFFSET %d", req.Offset)
+	}
+
+	if req.IncludeDeleted {
+		where = ""
+	}
+
+	query := `
+		SELECT
+			COUNT(*) OVER(),
+			book_id,
+			title,
+			author,
+			price,
+			isbn,
+			created_at,
+			updated_at,
+			version,
+			deleted_at
+		FROM
+			books
+	` + where
+
+	// SQLite only accepts OFFSET after LIMIT.
+	query += limit + offset
+
+	rows, err := f.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, mapError(err, "book")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var (
+			id        sql.NullString
+			title     sql.NullString
+			author    sql.NullString
+			price     sql.NullFloat64
+			isbn      sql.NullString
+			createdAt sql.NullString
+			updatedAt sql.NullString
+			version   sql.NullInt64
+			deletedAt sql.NullString
+		)
+
+		err := rows.Scan(
+			&resp.Count,
+			&id,
+			&title,
+			&author,
+			&price,
+			&isbn,
+			&createdAt,
+			&updatedAt,
+			&version,
+			&deletedAt,
+		)
+
+		if err != nil {
+			return nil, mapError(err, "book")
+		}
+
+		resp.Books = append(resp.Books, &models.Book{
+			Id:        id.String,
+			Title:     title.String,
+			Author:    author.String,
+			Price:     price.Float64,
+			ISBN:      isbn.String,
+			CreatedAt: createdAt.String,
+			UpdatedAt: updatedAt.String,
+			Version:   version.Int64,
+			DeletedAt: deletedAt.String,
+		})
+
+	}
+
+	return &resp, rows.Err()
+}
+
+func (f *BookRepo) Update(ctx context.Context, req *models.UpdateBook) (int64, error) {
+	return f.Patch(ctx, &models.PatchBook{
+		Id:      req.Id,
+		Title:   &req.Title,
+		Author:  &req.Author,
+		Price:   &req.Price,
+		ISBN:    &req.ISBN,
+		Version: req.Version,
+	})
+}
+
+func (f *BookRepo) Patch(ctx context.Context, req *models.PatchBook) (int64, error) {
+
+	q := helper.NewUpdateQuery("books", helper.Question)
+
+	if req.Title != nil {
+		q.Set("title", *req.Title)
+	}
+	if req.Author != nil {
+		q.Set("author", *req.Author)
+	}
+	if req.Price != nil {
+		q.Set("price", *req.Price)
+	}
+	if req.ISBN != nil {
+		q.Set("isbn", nullString(*req.ISBN))
+	}
+
+	q.SetExpr("updated_at", "CURRENT_TIMESTAMP").
+		SetExpr("version", "version + 1").
+		Where("book_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
+	}
+
+	query, args := q.Build()
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "book")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *BookRepo) Delete(ctx context.Context, req *models.BookPrimarKey) (int64, error) {
+
+	q := helper.NewUpdateQuery("books", helper.Question).
+		SetExpr("deleted_at", "CURRENT_TIMESTAMP").
+		SetExpr("updated_at", "CURRENT_TIMESTAMP").
+		SetExpr("version", "version + 1").
+		Where("book_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
+	}
+
+	query, args := q.Build()
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "book")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *BookRepo) Restore(ctx context.Context, req *models.BookPrimarKey) (int64, error) {
+
+	query, args := helper.NewUpdateQuery("books", helper.Question).
+		SetExpr("deleted_at", "NULL").
+		SetExpr("updated_at", "CURRENT_TIMESTAMP").
+		SetExpr("version", "version + 1").
+		Where("book_id", req.Id).
+		WhereNull("deleted_at", false).
+		Build()
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "book")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *BookRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+
+	query := `
+		DELETE FROM books
+		WHERE deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM orders WHERE orders.book_id = books.book_id)
+	`
+
+	result, err := f.db.ExecContext(ctx, query, before.UTC().Format(timestampLayout))
+	if err != nil {
+		return 0, mapError(err, "book")
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"crud/pkg/errs"
+)
+
+// mapError translates driver errors into domain errors from pkg/errs, in the
+// same way storage/postgres does. entity names the record the query was
+// about, e.g. "book".
+func mapError(err error, entity string) error {
+
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.NotFound("%s not found", entity).Wrap(err)
+	}
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return errs.Conflict("%s already exists", entity).Wrap(err)
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		// SQLite does not tell which side of the reference failed.
+		return errs.Conflict("%s violates a reference to another record", entity).Wrap(err)
+	case sqlite3.SQLITE_CONSTRAINT_NOTNULL, sqlite3.SQLITE_CONSTRAINT_CHECK:
+		return errs.Validation("%s violates a database constraint", entity).Wrap(err)
+	case sqlite3.SQLITE_INTERRUPT:
+		// The driver interrupts the statement when its context is done.
+		return errs.Timeout("%s query was interrupted", entity).Wrap(err)
+	}
+
+	return err
+}
+
+// nullString stores empty optional values as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
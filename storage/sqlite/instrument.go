@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"crud/pkg/tracing"
+)
+
+// instrumentedQuerier traces every statement and logs it at debug level. It
+// uses the caller's context, so spans nest under the request span and log
+// lines carry the request id.
+type instrumentedQuerier struct {
+	db  querier
+	log *slog.Logger
+}
+
+func (q instrumentedQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := q.start(ctx, query)
+	result, err := q.db.ExecContext(ctx, query, args...)
+	done(err)
+	return result, err
+}
+
+func (q instrumentedQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, done := q.start(ctx, query)
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (q instrumentedQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := q.start(ctx, query)
+	row := q.db.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+func (q instrumentedQuerier) start(ctx context.Context, query string) (context.Context, func(error)) {
+
+	start := time.Now()
+	ctx, span := tracing.StartQuery(ctx, semconv.DBSystemSqlite, query)
+
+	return ctx, func(err error) {
+
+		tracing.End(span, err)
+
+		if !q.log.Enabled(ctx, slog.LevelDebug) {
+			return
+		}
+
+		attrs := []any{
+			slog.String("query", strings.Join(strings.Fields(query), " ")),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		q.log.DebugContext(ctx, "query", attrs...)
+	}
+}
@@ -0,0 +1,291 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"crud/models"
+	"crud/pkg/helper"
+)
+
+type OrderRepo struct {
+	db querier
+}
+
+func NewOrderRepo(db querier) *OrderRepo {
+	return &OrderRepo{
+		db: db,
+	}
+}
+
+func (f *OrderRepo) Create(ctx context.Context, order *models.CreateOrder) (string, error) {
+
+	var (
+		id    = uuid.New().String()
+		query string
+	)
+
+	query = `
+		INSERT INTO orders(
+			order_id,
+			user_id,
+			book_id,
+			payed,
+			updated_at
+		) VALUES ( ?, ?, ?, ?, CURRENT_TIMESTAMP )
+	`
+
+	_, err := f.db.ExecContext(ctx, query,
+		id,
+		order.User_id,
+		order.Book_id,
+		order.Payed,
+	)
+
+	if err != nil {
+		return "", mapError(err, "order")
+	}
+
+	return id, nil
+}
+
+func (f *OrderRepo) GetByPKey(ctx context.Context, pkey *models.OrderPrimarKey) (*models.Order, error) {
+
+	var (
+		id         sql.NullString
+		user_id    sql.NullString
+		book_id    sql.NullString
+		payed      sql.NullFloat64
+		created_at sql.NullString
+		updated_at sql.NullString
+		version    sql.NullInt64
+		deleted_at sql.NullString
+	)
+
+	query := `
+		SELECT
+			order_id,
+			user_id,
+			book_id,
+			payed,
+			created_at,
+			updated_at,
+			version,
+			deleted_at
+		FROM
+			orders
+		WHERE order_id = ?
+	`
+
+	if !pkey.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+
+	err := f.db.QueryRowContext(ctx, query, pkey.Id).
+		Scan(
+			&id,
+			&user_id,
+			&book_id,
+			&payed,
+			&created_at,
+			&updated_at,
+			&version,
+			&deleted_at,
+		)
+
+	if err != nil {
+		return nil, mapError(err, "order")
+	}
+
+	return &models.Order{
+		Id:        id.String,
+		User_id:   user_id.String,
+		Book_id:   book_id.String,
+		Payed:     payed.Float64,
+		CreatedAt: created_at.String,
+		UpdatedAt: updated_at.String,
+		Version:   version.Int64,
+		DeletedAt: deleted_at.String,
+	}, nil
+}
+
+func (f *OrderRepo) GetList(ctx context.Context, req *models.GetListOrderRequest) (*models.GetListOrderResponse, error) {
+
+	var (
+		resp   = models.GetListOrderResponse{}
+		offset = ""
+		limit  = " LIMIT -1"
+		filter = helper.NewFilter(helper.Question)
+	)
+
+	if req.Limit > 0 {
+		limit = fmt.Sprintf(" LIMIT %d", req.Limit)
+	}
+
+	if req.Offset > 0 {
+		offset = fmt.Sprintf(" OFFSET %d", req.Offset)
+	}
+
+	if !req.IncludeDeleted {
+		filter.WhereNull("orders.deleted_at", true)
+	}
+	if req.User_id != "" {
+		filter.Where("orders.user_id", "=", req.User_id)
+	}
+
+	where, args := filter.Build()
+
+	query := `
+		SELECT
+			COUNT(*) OVER(),
+			orders.order_id,
+			orders.book_id,
+			users.first_name || ' ' || users.last_name as fullname,
+			books.title,
+			orders.payed,
+			orders.created_at,
+			orders.updated_at,
+			orders.deleted_at
+		FROM
+			orders
+		JOIN users ON orders.user_id = users.user_id
+		JOIN books ON orders.book_id = books.book_id
+	` + where
+
+	query += limit + offset
+
+	rows, err := f.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(err, "order")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var (
+			id         sql.NullString
+			bookID     sql.NullString
+			fullname   sql.NullString
+			title      sql.NullString
+			payed      sql.NullFloat64
+			created_at sql.NullString
+			updated_at sql.NullString
+			deleted_at sql.NullString
+		)
+
+		err := rows.Scan(
+			&resp.Count,
+			&id,
+			&bookID,
+			&fullname,
+			&title,
+			&payed,
+			&created_at,
+			&updated_at,
+			&deleted_at,
+		)
+
+		if err != nil {
+			return nil, mapError(err, "order")
+		}
+
+		resp.Orders = append(resp.Orders, &models.OrderGroup{
+			Id:        id.String,
+			Book_id:   bookID.String,
+			FullName:  fullname.String,
+			Title:     title.String,
+			Payed:     payed.Float64,
+			CreatedAt: created_at.String,
+			UpdatedAt: updated_at.String,
+			DeletedAt: deleted_at.String,
+		})
+
+	}
+
+	return &resp, rows.Err()
+}
+
+func (f *OrderRepo) Update(ctx context.Context, req *models.UpdateOrder) (int64, error) {
+
+	q := helper.NewUpdateQuery("orders", helper.Question).
+		Set("user_id", req.User_id).
+		Set("book_id", req.Book_id).
+		Set("payed", req.Payed).
+		SetExpr("updated_at", "CURRENT_TIMESTAMP").
+		SetExpr("version", "version + 1").
+		Where("order_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
+	}
+
+	query, args := q.Build()
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "order")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *OrderRepo) Delete(ctx context.Context, req *models.OrderPrimarKey) (int64, error) {
+
+	q := helper.NewUpdateQuery("orders", helper.Question).
+		SetExpr("deleted_at", "CURRENT_TIMESTAMP").
+		SetExpr("updated_at", "CURRENT_TIMESTAMP").
+		SetExpr("version", "version + 1").
+		Where("order_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
+	}
+
+	query, args := q.Build()
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "order")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *OrderRepo) Restore(ctx context.Context, req *models.OrderPrimarKey) (int64, error) {
+
+	query, args := helper.NewUpdateQuery("orders", helper.Question).
+		SetExpr("deleted_at", "NULL").
+		SetExpr("updated_at", "CURRENT_TIMESTAMP").
+		SetExpr("version", "version + 1").
+		Where("order_id", req.Id).
+		WhereNull("deleted_at", false).
+		Build()
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "order")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *OrderRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+
+	query := `
+		DELETE FROM orders
+		WHERE deleted_at < ?
+	`
+
+	result, err := f.db.ExecContext(ctx, query, before.UTC().Format(timestampLayout))
+	if err != nil {
+		return 0, mapError(err, "order")
+	}
+
+	return result.RowsAffected()
+}
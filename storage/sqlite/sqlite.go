@@ -0,0 +1,200 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	_ "modernc.org/sqlite"
+
+	"crud/config"
+	"crud/migrations"
+	"crud/pkg/migrate"
+	"crud/storage"
+)
+
+// querier is implemented by both *sql.DB and *sql.Tx, so the repos work the
+// same inside and outside a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type Store struct {
+	db    *sql.DB
+	tx    *sql.Tx
+	log   *slog.Logger
+	book  *BookRepo
+	user  *UserRepo
+	order *OrderRepo
+	totp  *TOTPRepo
+	keys  *APIKeyRepo
+	ids   *IdentityRepo
+	audit *AuditRepo
+}
+
+func NewSQLite(ctx context.Context, cfg config.Config, log *slog.Logger) (storage.StorageI, error) {
+	db, err := OpenDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{
+		db:    db,
+		log:   log,
+		book:  NewBookRepo(instrumentedQuerier{db, log}),
+		user:  NewUserRepo(instrumentedQuerier{db, log}),
+		order: NewOrderRepo(instrumentedQuerier{db, log}),
+		totp:  NewTOTPRepo(instrumentedQuerier{db, log}),
+		keys:  NewAPIKeyRepo(instrumentedQuerier{db, log}),
+		ids:   NewIdentityRepo(instrumentedQuerier{db, log}),
+		audit: NewAuditRepo(instrumentedQuerier{db, log}),
+	}, nil
+}
+
+// OpenDB opens the database file configured in cfg.SQLitePath.
+func OpenDB(cfg config.Config) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf(
+		"file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)",
+		cfg.SQLitePath,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer at a time, so one connection avoids
+	// "database is locked" errors under concurrent requests.
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+func (s *Store) CloseDB() {
+	s.db.Close()
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *Store) SchemaVersion(ctx context.Context) (uint, uint, error) {
+
+	latest, err := migrate.LatestVersion(migrations.SQLite())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var current int64
+
+	err = s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return 0, latest, err
+	}
+
+	return uint(current), latest, nil
+}
+
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.StorageI) error) error {
+
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rolls back when fn fails or panics, which would otherwise hold the
+	// only connection; after Commit it does nothing.
+	defer tx.Rollback()
+
+	err = fn(&Store{
+		db:    s.db,
+		tx:    tx,
+		log:   s.log,
+		book:  NewBookRepo(instrumentedQuerier{tx, s.log}),
+		user:  NewUserRepo(instrumentedQuerier{tx, s.log}),
+		order: NewOrderRepo(instrumentedQuerier{tx, s.log}),
+		totp:  NewTOTPRepo(instrumentedQuerier{tx, s.log}),
+		keys:  NewAPIKeyRepo(instrumentedQuerier{tx, s.log}),
+		ids:   NewIdentityRepo(instrumentedQuerier{tx, s.log}),
+		audit: NewAuditRepo(instrumentedQuerier{tx, s.log}),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) Book() storage.BookRepoI {
+
+	if s.book == nil {
+		s.book = NewBookRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.book
+}
+
+func (s *Store) User() storage.UserRepoI {
+
+	if s.user == nil {
+		s.user = NewUserRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.user
+}
+
+func (s *Store) Order() storage.OrderRepoI {
+
+	if s.order == nil {
+		s.order = NewOrderRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.order
+}
+
+func (s *Store) TOTP() storage.TOTPRepoI {
+
+	if s.totp == nil {
+		s.totp = NewTOTPRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.totp
+}
+
+func (s *Store) APIKey() storage.APIKeyRepoI {
+
+	if s.keys == nil {
+		s.keys = NewAPIKeyRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.keys
+}
+
+func (s *Store) Identity() storage.IdentityRepoI {
+
+	if s.ids == nil {
+		s.ids = NewIdentityRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.ids
+}
+
+func (s *Store) Audit() storage.AuditRepoI {
+
+	if s.audit == nil {
+		s.audit = NewAuditRepo(instrumentedQuerier{s.db, s.log})
+	}
+
+	return s.audit
+}
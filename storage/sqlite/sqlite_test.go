@@ -0,0 +1,53 @@
+package sqlite_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"crud/config"
+	"crud/migrations"
+	"crud/pkg/migrate"
+	"crud/storage"
+	"crud/storage/sqlite"
+	"crud/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, newStorage)
+}
+
+// newStorage migrates a database in a new temporary file.
+func newStorage(t *testing.T) storage.StorageI {
+	ctx := context.Background()
+
+	cfg := config.Default()
+	cfg.StorageDriver = config.StorageDriverSQLite
+	cfg.SQLitePath = filepath.Join(t.TempDir(), "book.db")
+
+	db, err := sqlite.OpenDB(cfg)
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, migrate.SQLite, migrations.SQLite())
+	if err != nil {
+		t.Fatalf("migrate.New: %v", err)
+	}
+
+	err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	store, err := sqlite.NewSQLite(ctx, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewSQLite: %v", err)
+	}
+	t.Cleanup(store.CloseDB)
+
+	return store
+}
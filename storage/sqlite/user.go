@@ -0,0 +1,453 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"crud/models"
+	"crud/pkg/helper"
+)
+
+type UserRepo struct {
+	db querier
+}
+
+func NewUserRepo(db querier) *UserRepo {
+	return &UserRepo{
+		db: db,
+	}
+}
+
+func (f *UserRepo) Create(ctx context.Context, user *models.CreateUser) (string, error) {
+
+	var (
+		id    = uuid.New().String()
+		query string
+	)
+
+	query = `
+		INSERT INTO users(
+			user_id,
+			first_name,
+			last_name,
+			login,
+			password,
+			phone_number,
+			balance,
+			email,
+			updated_at
+		) VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP )
+	`
+
+	_, err := f.db.ExecContext(ctx, query,
+		id,
+		user.First_name,
+		user.Last_name,
+		user.Login,
+		user.Password,
+		user.Phone_number,
+		user.Balance,
+		sql.NullString{String: user.Email, Valid: user.Email != ""},
+	)
+
+	if err != nil {
+		return "", mapError(err, "user")
+	}
+
+	return id, nil
+}
+
+func (f *UserRepo) GetByPKey(ctx context.Context, pkey *models.UserPrimarKey) (*models.User, error) {
+
+	var (
+		id           sql.NullString
+		first_name   sql.NullString
+		last_name    sql.NullString
+		login        sql.NullString
+		password     sql.NullString
+		phone_number sql.NullString
+		balance      sql.NullFloat64
+		email        sql.NullString
+		verified     sql.NullBool
+		isAdmin      sql.NullBool
+		createdAt    sql.NullString
+		updatedAt    sql.NullString
+		version      sql.NullInt64
+		deletedAt    sql.NullString
+		erasedAt     sql.NullString
+	)
+
+	if len(pkey.Login) > 0 {
+
+		err := f.db.QueryRowContext(ctx, "SELECT user_id FROM users WHERE login = ?", pkey.Login).
+			Scan(&pkey.Id)
+
+		if err != nil {
+			return nil, mapError(err, "user")
+		}
+
+	}
+
+	if len(pkey.Email) > 0 {
+
+		err := f.db.QueryRowContext(ctx, "SELECT user_id FROM users WHERE email = ?", pkey.Email).
+			Scan(&pkey.Id)
+
+		if err != nil {
+			return nil, mapError(err, "user")
+		}
+
+	}
+
+	query := `
+		SELECT
+			user_id,
+			first_name,
+			last_name,
+			login,
+			password,
+			phone_number,
+			balance,
+			email,
+			email_verified_at IS NOT NULL,
+			is_admin,
+			created_at,
+			updated_at,
+			version,
+			deleted_at,
+			erased_at
+		FROM
+			users
+		WHERE user_id = ?
+	`
+
+	if !pkey.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+
+	err := f.db.QueryRowContext(ctx, query, pkey.Id).
+		Scan(
+			&id,
+			&first_name,
+			&last_name,
+			&login,
+			&password,
+			&phone_number,
+			&balance,
+			&email,
+			&verified,
+			&isAdmin,
+			&createdAt,
+			&updatedAt,
+			&version,
+			&deletedAt,
+			&erasedAt,
+		)
+
+	if err != nil {
+		return nil, mapError(err, "user")
+	}
+
+	return &models.User{
+		Id:            id.String,
+		First_name:    first_name.String,
+		Last_name:     last_name.String,
+		Login:         login.String,
+		Password:      password.String,
+		Phone_number:  phone_number.String,
+		Balance:       balance.Float64,
+		Email:         email.String,
+		EmailVerified: verified.Bool,
+		IsAdmin:       isAdmin.Bool,
+		CreatedAt:     createdAt.String,
+		UpdatedAt:     updatedAt.String,
+		Version:       version.Int64,
+		DeletedAt:     deletedAt.String,
+		ErasedAt:      erasedAt.String,
+	}, nil
+}
+
+func (f *UserRepo) GetList(ctx context.Context, req *models.GetListUserRequest) (*models.GetListUserResponse, error) {
+
+	var (
+		resp   = models.GetListUserResponse{}
+		offset = ""
+		limit  = " LIMIT -1"
+		where  = " WHERE deleted_at IS NULL"
+	)
+
+	if req.Limit > 0 {
+		limit = fmt.Sprintf(" LIMIT %d", req.Limit)
+	}
+
+	if req.Offset > 0 {
+		offset = fmt.Sprintf(" OFFSET %d", req.Offset)
+	}
+
+	if req.IncludeDeleted {
+		where = ""
+	}
+
+	query := `
+		SELECT
+			COUNT(*) OVER(),
+			user_id,
+			first_name,
+			last_name,
+			login,
+			password,
+			phone_number,
+			balance,
+			email,
+			email_verified_at IS NOT NULL,
+			is_admin,
+			created_at,
+			updated_at,
+			version,
+			deleted_at,
+			erased_at
+		FROM
+			users
+	` + where
+
+	query += limit + offset
+
+	rows, err := f.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, mapError(err, "user")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var (
+			id           sql.NullString
+			first_name   sql.NullString
+			last_name    sql.NullString
+			login        sql.NullString
+			password     sql.NullString
+			phone_number sql.NullString
+			balance      sql.NullFloat64
+			email        sql.NullString
+			verified     sql.NullBool
+			isAdmin      sql.NullBool
+			createdAt    sql.NullString
+			updatedAt    sql.NullString
+			version      sql.NullInt64
+			deletedAt    sql.NullString
+			erasedAt     sql.NullString
+		)
+
+		err := rows.Scan(
+			&resp.Count,
+			&id,
+			&first_name,
+			&last_name,
+			&login,
+			&password,
+			&phone_number,
+			&balance,
+			&email,
+			&verified,
+			&isAdmin,
+			&createdAt,
+			&updatedAt,
+			&version,
+			&deletedAt,
+			&erasedAt,
+		)
+
+		if err != nil {
+			return nil, mapError(err, "user")
+		}
+
+		resp.Users = append(resp.Users, &models.User{
+			Id:            id.String,
+			First_name:    first_name.String,
+			Last_name:     last_name.String,
+			Login:         login.String,
+			Password:      password.String,
+			Phone_number:  phone_number.String,
+			Balance:       balance.Float64,
+			Email:         email.String,
+			EmailVerified: verified.Bool,
+			IsAdmin:       isAdmin.Bool,
+			CreatedAt:     createdAt.String,
+			UpdatedAt:     updatedAt.String,
+			Version:       version.Int64,
+			DeletedAt:     deletedAt.String,
+			ErasedAt:      erasedAt.String,
+		})
+
+	}
+
+	return &resp, rows.Err()
+}
+
+func (f *UserRepo) Update(ctx context.Context, req *models.UpdateUser) (int64, error) {
+	return f.Patch(ctx, &models.PatchUser{
+		Id:           req.Id,
+		First_name:   &req.First_name,
+		Last_name:    &req.Last_name,
+		Login:        &req.Login,
+		Password:     &req.Password,
+		Phone_number: &req.Phone_number,
+		Balance:      &req.Balance,
+		Version:      req.Version,
+	})
+}
+
+func (f *UserRepo) Patch(ctx context.Context, req *models.PatchUser) (int64, error) {
+
+	q := helper.NewUpdateQuery("users", helper.Question)
+
+	if req.First_name != nil {
+		q.Set("first_name", *req.First_name)
+	}
+	if req.Last_name != nil {
+		q.Set("last_name", *req.Last_name)
+	}
+	if req.Login != nil {
+		q.Set("login", *req.Login)
+	}
+	if req.Password != nil {
+		q.Set("password", *req.Password)
+	}
+	if req.Phone_number != nil {
+		q.Set("phone_number", *req.Phone_number)
+	}
+	if req.Balance != nil {
+		q.Set("balance", *req.Balance)
+	}
+	if req.IsAdmin != nil {
+		q.Set("is_admin", *req.IsAdmin)
+	}
+	if req.EmailVerified {
+		q.SetExpr("email_verified_at", "CURRENT_TIMESTAMP")
+	}
+
+	q.SetExpr("updated_at", "CURRENT_TIMESTAMP").
+		SetExpr("version", "version + 1").
+		Where("user_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
+	}
+
+	query, args := q.Build()
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *UserRepo) UpdateBalance(ctx context.Context, req *models.UpdateBalance) (int64, error) {
+
+	query := `
+		UPDATE
+			users
+		SET
+			balance = balance + ?,
+			version = version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND balance + ? >= 0
+	`
+
+	result, err := f.db.ExecContext(ctx, query, req.Amount, req.Id, req.Amount)
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *UserRepo) Delete(ctx context.Context, req *models.UserPrimarKey) (int64, error) {
+
+	q := helper.NewUpdateQuery("users", helper.Question).
+		SetExpr("deleted_at", "CURRENT_TIMESTAMP").
+		SetExpr("updated_at", "CURRENT_TIMESTAMP").
+		SetExpr("version", "version + 1").
+		Where("user_id", req.Id).
+		WhereNull("deleted_at", true)
+
+	if req.Version > 0 {
+		q.Where("version", req.Version)
+	}
+
+	query, args := q.Build()
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *UserRepo) Restore(ctx context.Context, req *models.UserPrimarKey) (int64, error) {
+
+	query, args := helper.NewUpdateQuery("users", helper.Question).
+		SetExpr("deleted_at", "NULL").
+		SetExpr("updated_at", "CURRENT_TIMESTAMP").
+		SetExpr("version", "version + 1").
+		Where("user_id", req.Id).
+		WhereNull("deleted_at", false).
+		Build()
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *UserRepo) Erase(ctx context.Context, req *models.EraseUser) (int64, error) {
+
+	query, args := helper.NewUpdateQuery("users", helper.Question).
+		Set("first_name", req.First_name).
+		Set("last_name", req.Last_name).
+		Set("login", req.Login).
+		Set("password", "").
+		Set("phone_number", "").
+		SetExpr("email", "NULL").
+		SetExpr("email_verified_at", "NULL").
+		Set("is_admin", false).
+		SetExpr("erased_at", "CURRENT_TIMESTAMP").
+		SetExpr("updated_at", "CURRENT_TIMESTAMP").
+		SetExpr("version", "version + 1").
+		Where("user_id", req.Id).
+		WhereNull("erased_at", true).
+		Build()
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+
+	return result.RowsAffected()
+}
+
+func (f *UserRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+
+	query := `
+		DELETE FROM users
+		WHERE deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM orders WHERE orders.user_id = users.user_id)
+	`
+
+	result, err := f.db.ExecContext(ctx, query, before.UTC().Format(timestampLayout))
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+
+	return result.RowsAffected()
+}
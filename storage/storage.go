@@ -2,23 +2,49 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"crud/models"
 )
 
 type StorageI interface {
 	CloseDB()
+	Ping(ctx context.Context) error
+	// SchemaVersion returns the applied migration version of the database
+	// and the latest version known to the binary.
+	SchemaVersion(ctx context.Context) (current, latest uint, err error)
+	// WithTx runs fn inside a transaction. The StorageI passed to fn must be
+	// used for every call that belongs to the transaction; it is committed
+	// when fn returns nil and rolled back otherwise. Nested calls reuse the
+	// outer transaction.
+	WithTx(ctx context.Context, fn func(tx StorageI) error) error
 	Book() BookRepoI
 	User() UserRepoI
 	Order() OrderRepoI
+	TOTP() TOTPRepoI
+	APIKey() APIKeyRepoI
+	Identity() IdentityRepoI
+	Audit() AuditRepoI
 }
 
+// Every write bumps the row version. Update, Patch and Delete only affect
+// the row if it still has the Version of the request, when one is set.
+//
+// Books, users and orders are soft deleted: Delete sets deleted_at, reads
+// and writes skip deleted rows unless IncludeDeleted is set, and Restore
+// clears it again. Purge removes the rows deleted before a time for good.
 type BookRepoI interface {
 	Create(ctx context.Context, req *models.CreateBook) (string, error)
 	GetByPKey(ctx context.Context, req *models.BookPrimarKey) (*models.Book, error)
 	GetList(ctx context.Context, req *models.GetListBookRequest) (*models.GetListBookResponse, error)
 	Update(ctx context.Context, req *models.UpdateBook) (int64, error)
-	Delete(ctx context.Context, req *models.BookPrimarKey) error
+	// Patch writes only the non-nil fields of req.
+	Patch(ctx context.Context, req *models.PatchBook) (int64, error)
+	Delete(ctx context.Context, req *models.BookPrimarKey) (int64, error)
+	// Restore affects no rows when the book is missing or not deleted.
+	Restore(ctx context.Context, req *models.BookPrimarKey) (int64, error)
+	// Purge skips books still referenced by an order.
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
 
 type UserRepoI interface {
@@ -26,7 +52,21 @@ type UserRepoI interface {
 	GetByPKey(ctx context.Context, req *models.UserPrimarKey) (*models.User, error)
 	GetList(ctx context.Context, req *models.GetListUserRequest) (*models.GetListUserResponse, error)
 	Update(ctx context.Context, req *models.UpdateUser) (int64, error)
-	Delete(ctx context.Context, req *models.UserPrimarKey) error
+	// Patch writes only the non-nil fields of req.
+	Patch(ctx context.Context, req *models.PatchUser) (int64, error)
+	// UpdateBalance adds req.Amount to the balance. It affects no rows when
+	// the user does not exist or the balance would become negative. Deleted
+	// users are included, so a refund to them is not lost.
+	UpdateBalance(ctx context.Context, req *models.UpdateBalance) (int64, error)
+	Delete(ctx context.Context, req *models.UserPrimarKey) (int64, error)
+	// Restore affects no rows when the user is missing or not deleted.
+	Restore(ctx context.Context, req *models.UserPrimarKey) (int64, error)
+	// Erase replaces the personal data of the user, deleted or not, revokes
+	// its admin flag and keeps the row so its orders stay valid. It affects no rows when the
+	// user is missing or already erased.
+	Erase(ctx context.Context, req *models.EraseUser) (int64, error)
+	// Purge skips users still referenced by an order.
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
 
 type OrderRepoI interface {
@@ -34,5 +74,60 @@ type OrderRepoI interface {
 	GetByPKey(ctx context.Context, req *models.OrderPrimarKey) (*models.Order, error)
 	GetList(ctx context.Context, req *models.GetListOrderRequest) (*models.GetListOrderResponse, error)
 	Update(ctx context.Context, req *models.UpdateOrder) (int64, error)
-	Delete(ctx context.Context, req *models.OrderPrimarKey) error
+	Delete(ctx context.Context, req *models.OrderPrimarKey) (int64, error)
+	// Restore affects no rows when the order is missing or not deleted.
+	Restore(ctx context.Context, req *models.OrderPrimarKey) (int64, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type TOTPRepoI interface {
+	// Create stores a pending secret, replacing the user's previous one and
+	// its recovery codes.
+	Create(ctx context.Context, req *models.CreateTOTP) error
+	GetByPKey(ctx context.Context, req *models.TOTPPrimarKey) (*models.TOTP, error)
+	// Enable confirms a pending secret. It affects no rows when the secret
+	// is missing or already enabled.
+	Enable(ctx context.Context, req *models.TOTPPrimarKey) (int64, error)
+	// UseStep records an accepted code. It affects no rows when a code of
+	// the same or a later step was accepted before, i.e. on a replay.
+	UseStep(ctx context.Context, req *models.UseTOTPStep) (int64, error)
+	// SetRecoveryCodes replaces the recovery codes of the user.
+	SetRecoveryCodes(ctx context.Context, req *models.SetRecoveryCodes) error
+	// UseRecoveryCode spends a recovery code. It affects no rows when the
+	// code is unknown or was used before.
+	UseRecoveryCode(ctx context.Context, req *models.UseRecoveryCode) (int64, error)
+	// Delete removes the secret and the recovery codes.
+	Delete(ctx context.Context, req *models.TOTPPrimarKey) (int64, error)
+}
+
+type APIKeyRepoI interface {
+	Create(ctx context.Context, req *models.CreateAPIKey) (string, error)
+	// GetByPKey looks the key up by Id, or by Prefix when Id is empty.
+	GetByPKey(ctx context.Context, req *models.APIKeyPrimarKey) (*models.APIKey, error)
+	GetList(ctx context.Context, req *models.GetListAPIKeyRequest) (*models.GetListAPIKeyResponse, error)
+	// Touch sets the last use of the key to now. It affects no rows when a
+	// use was recorded within req.Interval.
+	Touch(ctx context.Context, req *models.TouchAPIKey) (int64, error)
+	Delete(ctx context.Context, req *models.APIKeyPrimarKey) (int64, error)
+}
+
+// IdentityRepoI links users to their accounts at external identity
+// providers.
+type IdentityRepoI interface {
+	Create(ctx context.Context, req *models.CreateIdentity) error
+	GetByPKey(ctx context.Context, req *models.IdentityPrimarKey) (*models.Identity, error)
+	GetList(ctx context.Context, req *models.GetListIdentityRequest) (*models.GetListIdentityResponse, error)
+	// Unlink removes every identity linked to the user.
+	Unlink(ctx context.Context, req *models.UserPrimarKey) (int64, error)
+}
+
+type AuditRepoI interface {
+	Create(ctx context.Context, req *models.CreateAuditLog) error
+	// GetList returns the newest entries first.
+	GetList(ctx context.Context, req *models.GetListAuditLogRequest) (*models.GetListAuditLogResponse, error)
+	// DeleteBefore removes the entries created before the time.
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+	// Redact clears the before and after of the entries about the entity
+	// and the IP of the entries made by the actor.
+	Redact(ctx context.Context, req *models.RedactAuditLog) (int64, error)
 }
@@ -0,0 +1,1033 @@
+// Package storagetest is a conformance suite for storage.StorageI
+// implementations. Every backend is expected to pass Run against an empty,
+// freshly migrated database.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"crud/models"
+	"crud/pkg/errs"
+	"crud/storage"
+)
+
+// Run executes the suite. newStorage must return a store backed by an empty
+// database; it is called once per subtest.
+func Run(t *testing.T, newStorage func(t *testing.T) storage.StorageI) {
+	t.Run("Book", func(t *testing.T) { testBook(t, newStorage(t)) })
+	t.Run("User", func(t *testing.T) { testUser(t, newStorage(t)) })
+	t.Run("Order", func(t *testing.T) { testOrder(t, newStorage(t)) })
+	t.Run("TOTP", func(t *testing.T) { testTOTP(t, newStorage(t)) })
+	t.Run("APIKey", func(t *testing.T) { testAPIKey(t, newStorage(t)) })
+	t.Run("Identity", func(t *testing.T) { testIdentity(t, newStorage(t)) })
+	t.Run("Audit", func(t *testing.T) { testAudit(t, newStorage(t)) })
+	t.Run("Tx", func(t *testing.T) { testTx(t, newStorage(t)) })
+}
+
+func testBook(t *testing.T, store storage.StorageI) {
+	ctx := context.Background()
+
+	id, err := store.Book().Create(ctx, &models.CreateBook{Title: "Norwegian Wood", Author: "Murakami", Price: 1500, ISBN: "978-0-375-70402-4"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	book, err := store.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("GetByPKey: %v", err)
+	}
+	if book.Id != id || book.Title != "Norwegian Wood" || book.Author != "Murakami" || book.Price != 1500 || book.ISBN != "978-0-375-70402-4" {
+		t.Errorf("GetByPKey = %+v", book)
+	}
+	if book.CreatedAt == "" || book.UpdatedAt == "" {
+		t.Errorf("GetByPKey timestamps not set: %+v", book)
+	}
+	if book.Version != 1 {
+		t.Errorf("GetByPKey version = %d, want 1", book.Version)
+	}
+
+	rowsAffected, err := store.Book().Update(ctx, &models.UpdateBook{Id: id, Title: "Kafka on the Shore", Author: "Murakami", Price: 2000})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if rowsAffected != 1 {
+		t.Errorf("Update rows affected = %d, want 1", rowsAffected)
+	}
+
+	book, err = store.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("GetByPKey after update: %v", err)
+	}
+	if book.Title != "Kafka on the Shore" || book.Price != 2000 || book.ISBN != "" {
+		t.Errorf("GetByPKey after update = %+v", book)
+	}
+
+	isbn := "0-306-40615-2"
+	rowsAffected, err = store.Book().Patch(ctx, &models.PatchBook{Id: id, ISBN: &isbn})
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if rowsAffected != 1 {
+		t.Errorf("Patch rows affected = %d, want 1", rowsAffected)
+	}
+
+	book, err = store.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("GetByPKey after patch: %v", err)
+	}
+	if book.Title != "Kafka on the Shore" || book.Price != 2000 || book.ISBN != isbn || book.Version != 3 {
+		t.Errorf("GetByPKey after patch = %+v", book)
+	}
+
+	rowsAffected, err = store.Book().Patch(ctx, &models.PatchBook{Id: id, ISBN: &isbn, Version: 2})
+	if err != nil || rowsAffected != 0 {
+		t.Errorf("Patch stale version = %d, %v; want 0 rows", rowsAffected, err)
+	}
+
+	rowsAffected, err = store.Book().Update(ctx, &models.UpdateBook{Id: newID(t, store), Title: "missing"})
+	if err != nil {
+		t.Fatalf("Update missing: %v", err)
+	}
+	if rowsAffected != 0 {
+		t.Errorf("Update missing rows affected = %d, want 0", rowsAffected)
+	}
+
+	for _, title := range []string{"A", "B"} {
+		if _, err := store.Book().Create(ctx, &models.CreateBook{Title: title, Price: 1}); err != nil {
+			t.Fatalf("Create %s: %v", title, err)
+		}
+	}
+
+	checkList(t, "Book", func(limit, offset int32) (int32, int, error) {
+		resp, err := store.Book().GetList(ctx, &models.GetListBookRequest{Limit: limit, Offset: offset})
+		if err != nil {
+			return 0, 0, err
+		}
+		return resp.Count, len(resp.Books), nil
+	}, 3)
+
+	rowsAffected, err = store.Book().Delete(ctx, &models.BookPrimarKey{Id: id, Version: 2})
+	if err != nil || rowsAffected != 0 {
+		t.Errorf("Delete stale version = %d, %v; want 0 rows", rowsAffected, err)
+	}
+
+	rowsAffected, err = store.Book().Delete(ctx, &models.BookPrimarKey{Id: id, Version: 3})
+	if err != nil || rowsAffected != 1 {
+		t.Fatalf("Delete = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	_, err = store.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id})
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey after delete = %v, want %s", err, errs.CodeNotFound)
+	}
+
+	book, err = store.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id, IncludeDeleted: true})
+	if err != nil || book.DeletedAt == "" {
+		t.Errorf("GetByPKey deleted with IncludeDeleted = %+v, %v", book, err)
+	}
+
+	resp, err := store.Book().GetList(ctx, &models.GetListBookRequest{})
+	if err != nil || resp.Count != 2 {
+		t.Errorf("GetList after delete = %+v, %v; want count 2", resp, err)
+	}
+
+	resp, err = store.Book().GetList(ctx, &models.GetListBookRequest{IncludeDeleted: true})
+	if err != nil || resp.Count != 3 {
+		t.Errorf("GetList with IncludeDeleted = %+v, %v; want count 3", resp, err)
+	}
+
+	title := "deleted"
+	rowsAffected, err = store.Book().Patch(ctx, &models.PatchBook{Id: id, Title: &title})
+	if err != nil || rowsAffected != 0 {
+		t.Errorf("Patch deleted = %d, %v; want 0 rows", rowsAffected, err)
+	}
+
+	rowsAffected, err = store.Book().Delete(ctx, &models.BookPrimarKey{Id: id})
+	if err != nil || rowsAffected != 0 {
+		t.Errorf("Delete deleted = %d, %v; want 0 rows", rowsAffected, err)
+	}
+
+	rowsAffected, err = store.Book().Restore(ctx, &models.BookPrimarKey{Id: id})
+	if err != nil || rowsAffected != 1 {
+		t.Fatalf("Restore = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	rowsAffected, err = store.Book().Restore(ctx, &models.BookPrimarKey{Id: id})
+	if err != nil || rowsAffected != 0 {
+		t.Errorf("Restore not deleted = %d, %v; want 0 rows", rowsAffected, err)
+	}
+
+	book, err = store.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id})
+	if err != nil || book.DeletedAt != "" || book.Title != "Kafka on the Shore" {
+		t.Errorf("GetByPKey after restore = %+v, %v", book, err)
+	}
+
+	if _, err = store.Book().Delete(ctx, &models.BookPrimarKey{Id: id}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	rowsAffected, err = store.Book().Purge(ctx, time.Now().Add(-time.Hour))
+	if err != nil || rowsAffected != 0 {
+		t.Errorf("Purge before the delete = %d, %v; want 0 rows", rowsAffected, err)
+	}
+
+	rowsAffected, err = store.Book().Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil || rowsAffected != 1 {
+		t.Errorf("Purge = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	_, err = store.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id, IncludeDeleted: true})
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey after purge = %v, want %s", err, errs.CodeNotFound)
+	}
+}
+
+func testUser(t *testing.T, store storage.StorageI) {
+	ctx := context.Background()
+
+	id := createUser(t, store, "samandar")
+
+	user, err := store.User().GetByPKey(ctx, &models.UserPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("GetByPKey: %v", err)
+	}
+	if user.Id != id || user.Login != "samandar" || user.Phone_number != "997191323" || user.Balance != 5000 {
+		t.Errorf("GetByPKey = %+v", user)
+	}
+
+	user, err = store.User().GetByPKey(ctx, &models.UserPrimarKey{Login: "samandar"})
+	if err != nil {
+		t.Fatalf("GetByPKey by login: %v", err)
+	}
+	if user.Id != id {
+		t.Errorf("GetByPKey by login id = %q, want %q", user.Id, id)
+	}
+
+	_, err = store.User().GetByPKey(ctx, &models.UserPrimarKey{Login: "nobody"})
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey unknown login = %v, want %s", err, errs.CodeNotFound)
+	}
+
+	_, err = store.User().Create(ctx, &models.CreateUser{First_name: "a", Last_name: "b", Login: "samandar", Password: "x", Phone_number: "1"})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("Create with duplicate login = %v, want %s", err, errs.CodeConflict)
+	}
+
+	registered, err := store.User().Create(ctx, &models.CreateUser{First_name: "a", Last_name: "b", Login: "registered", Password: "x", Phone_number: "1", Email: "a@example.com"})
+	if err != nil {
+		t.Fatalf("Create with email: %v", err)
+	}
+
+	user, err = store.User().GetByPKey(ctx, &models.UserPrimarKey{Email: "a@example.com"})
+	if err != nil {
+		t.Fatalf("GetByPKey by email: %v", err)
+	}
+	if user.Id != registered || user.EmailVerified {
+		t.Errorf("GetByPKey by email = %+v, want %s unverified", user, registered)
+	}
+
+	_, err = store.User().Create(ctx, &models.CreateUser{First_name: "a", Last_name: "b", Login: "other", Password: "x", Phone_number: "1", Email: "a@example.com"})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("Create with duplicate email = %v, want %s", err, errs.CodeConflict)
+	}
+
+	rowsAffected, err := store.User().Patch(ctx, &models.PatchUser{Id: registered, EmailVerified: true})
+	if err != nil || rowsAffected != 1 {
+		t.Fatalf("Patch EmailVerified = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	user, err = store.User().GetByPKey(ctx, &models.UserPrimarKey{Id: registered})
+	if err != nil {
+		t.Fatalf("GetByPKey after verification: %v", err)
+	}
+	if !user.EmailVerified || user.Email != "a@example.com" {
+		t.Errorf("GetByPKey after verification = %+v", user)
+	}
+
+	_, err = store.User().Delete(ctx, &models.UserPrimarKey{Id: registered})
+	if err != nil {
+		t.Fatalf("Delete registered: %v", err)
+	}
+
+	rowsAffected, err = store.User().Update(ctx, &models.UpdateUser{
+		Id:           id,
+		First_name:   "Samandar",
+		Last_name:    "Foziljonov",
+		Login:        "samandevop",
+		Password:     "secret",
+		Phone_number: "991234567",
+		Balance:      100,
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if rowsAffected != 1 {
+		t.Errorf("Update rows affected = %d, want 1", rowsAffected)
+	}
+
+	user, err = store.User().GetByPKey(ctx, &models.UserPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("GetByPKey after update: %v", err)
+	}
+	if user.Login != "samandevop" || user.Balance != 100 {
+		t.Errorf("GetByPKey after update = %+v", user)
+	}
+
+	firstName := "Saman"
+	rowsAffected, err = store.User().Patch(ctx, &models.PatchUser{Id: id, First_name: &firstName})
+	if err != nil || rowsAffected != 1 {
+		t.Fatalf("Patch = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	user, err = store.User().GetByPKey(ctx, &models.UserPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("GetByPKey after patch: %v", err)
+	}
+	if user.First_name != "Saman" || user.Last_name != "Foziljonov" || user.Password != "secret" || user.Balance != 100 {
+		t.Errorf("GetByPKey after patch = %+v", user)
+	}
+
+	rowsAffected, err = store.User().UpdateBalance(ctx, &models.UpdateBalance{Id: id, Amount: -40})
+	if err != nil || rowsAffected != 1 {
+		t.Fatalf("UpdateBalance = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	rowsAffected, err = store.User().UpdateBalance(ctx, &models.UpdateBalance{Id: id, Amount: -61})
+	if err != nil || rowsAffected != 0 {
+		t.Errorf("UpdateBalance below zero = %d, %v; want 0 rows", rowsAffected, err)
+	}
+
+	user, err = store.User().GetByPKey(ctx, &models.UserPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("GetByPKey after UpdateBalance: %v", err)
+	}
+	if user.Balance != 60 {
+		t.Errorf("balance = %v, want 60", user.Balance)
+	}
+
+	createUser(t, store, "second")
+	createUser(t, store, "third")
+
+	checkList(t, "User", func(limit, offset int32) (int32, int, error) {
+		resp, err := store.User().GetList(ctx, &models.GetListUserRequest{Limit: limit, Offset: offset})
+		if err != nil {
+			return 0, 0, err
+		}
+		return resp.Count, len(resp.Users), nil
+	}, 3)
+
+	_, err = store.User().Delete(ctx, &models.UserPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err = store.User().GetByPKey(ctx, &models.UserPrimarKey{Id: id})
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey after delete = %v, want %s", err, errs.CodeNotFound)
+	}
+
+	_, err = store.User().GetByPKey(ctx, &models.UserPrimarKey{Login: "samandevop"})
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey by login after delete = %v, want %s", err, errs.CodeNotFound)
+	}
+
+	// The login stays taken until the user is purged.
+	_, err = store.User().Create(ctx, &models.CreateUser{First_name: "a", Last_name: "b", Login: "samandevop", Password: "x", Phone_number: "1"})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("Create with the login of a deleted user = %v, want %s", err, errs.CodeConflict)
+	}
+
+	resp, err := store.User().GetList(ctx, &models.GetListUserRequest{IncludeDeleted: true})
+	if err != nil || resp.Count != 4 {
+		t.Errorf("GetList with IncludeDeleted = %+v, %v; want count 4", resp, err)
+	}
+
+	rowsAffected, err = store.User().Restore(ctx, &models.UserPrimarKey{Id: id})
+	if err != nil || rowsAffected != 1 {
+		t.Fatalf("Restore = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	user, err = store.User().GetByPKey(ctx, &models.UserPrimarKey{Login: "samandevop"})
+	if err != nil || user.Id != id || user.Balance != 60 {
+		t.Errorf("GetByPKey after restore = %+v, %v", user, err)
+	}
+
+	if _, err = store.User().Delete(ctx, &models.UserPrimarKey{Id: id}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	rowsAffected, err = store.User().Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil || rowsAffected != 2 {
+		t.Errorf("Purge = %d, %v; want 2 rows", rowsAffected, err)
+	}
+
+	if _, err = store.User().Create(ctx, &models.CreateUser{First_name: "a", Last_name: "b", Login: "samandevop", Password: "x", Phone_number: "1"}); err != nil {
+		t.Errorf("Create with the login of a purged user: %v", err)
+	}
+}
+
+func testOrder(t *testing.T, store storage.StorageI) {
+	ctx := context.Background()
+
+	userID := createUser(t, store, "reader")
+
+	cheap, err := store.Book().Create(ctx, &models.CreateBook{Title: "Cheap", Price: 100})
+	if err != nil {
+		t.Fatalf("Create book: %v", err)
+	}
+	expensive, err := store.Book().Create(ctx, &models.CreateBook{Title: "Expensive", Price: 900})
+	if err != nil {
+		t.Fatalf("Create book: %v", err)
+	}
+
+	id, err := store.Order().Create(ctx, &models.CreateOrder{User_id: userID, Book_id: cheap, Payed: 100})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	order, err := store.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("GetByPKey: %v", err)
+	}
+	if order.Id != id || order.User_id != userID || order.Book_id != cheap || order.Payed != 100 {
+		t.Errorf("GetByPKey = %+v", order)
+	}
+	if order.CreatedAt == "" || order.UpdatedAt == "" {
+		t.Errorf("GetByPKey timestamps not set: %+v", order)
+	}
+
+	_, err = store.Order().Create(ctx, &models.CreateOrder{User_id: userID, Book_id: newID(t, store), Payed: 100})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("Create with unknown book = %v, want %s", err, errs.CodeConflict)
+	}
+
+	rowsAffected, err := store.Order().Update(ctx, &models.UpdateOrder{Id: id, User_id: userID, Book_id: expensive, Payed: 900})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if rowsAffected != 1 {
+		t.Errorf("Update rows affected = %d, want 1", rowsAffected)
+	}
+
+	order, err = store.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("GetByPKey after update: %v", err)
+	}
+	if order.Book_id != expensive || order.Payed != 900 {
+		t.Errorf("GetByPKey after update = %+v", order)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := store.Order().Create(ctx, &models.CreateOrder{User_id: userID, Book_id: cheap, Payed: 100}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	resp, err := store.Order().GetList(ctx, &models.GetListOrderRequest{})
+	if err != nil {
+		t.Fatalf("GetList: %v", err)
+	}
+	for _, o := range resp.Orders {
+		if o.FullName != "Samandar Foziljonov" || (o.Title != "Cheap" && o.Title != "Expensive") {
+			t.Errorf("GetList order = %+v", o)
+		}
+	}
+
+	if o := resp.Orders[0]; o.Id == "" || o.Book_id == "" {
+		t.Errorf("GetList order ids not set: %+v", o)
+	}
+
+	resp, err = store.Order().GetList(ctx, &models.GetListOrderRequest{User_id: userID})
+	if err != nil || resp.Count != 3 {
+		t.Errorf("GetList of the user = %+v, %v; want count 3", resp, err)
+	}
+
+	resp, err = store.Order().GetList(ctx, &models.GetListOrderRequest{User_id: createUser(t, store, "other")})
+	if err != nil || resp.Count != 0 {
+		t.Errorf("GetList of another user = %+v, %v; want count 0", resp, err)
+	}
+
+	checkList(t, "Order", func(limit, offset int32) (int32, int, error) {
+		resp, err := store.Order().GetList(ctx, &models.GetListOrderRequest{Limit: limit, Offset: offset})
+		if err != nil {
+			return 0, 0, err
+		}
+		return resp.Count, len(resp.Orders), nil
+	}, 3)
+
+	rowsAffected, err = store.Book().Delete(ctx, &models.BookPrimarKey{Id: cheap})
+	if err != nil || rowsAffected != 1 {
+		t.Errorf("Delete referenced book = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	rowsAffected, err = store.Book().Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil || rowsAffected != 0 {
+		t.Errorf("Purge referenced book = %d, %v; want 0 rows", rowsAffected, err)
+	}
+
+	_, err = store.Order().Delete(ctx, &models.OrderPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err = store.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: id})
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey after delete = %v, want %s", err, errs.CodeNotFound)
+	}
+
+	order, err = store.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: id, IncludeDeleted: true})
+	if err != nil || order.DeletedAt == "" {
+		t.Errorf("GetByPKey deleted with IncludeDeleted = %+v, %v", order, err)
+	}
+
+	resp, err = store.Order().GetList(ctx, &models.GetListOrderRequest{})
+	if err != nil || resp.Count != 2 {
+		t.Errorf("GetList after delete = %+v, %v; want count 2", resp, err)
+	}
+
+	resp, err = store.Order().GetList(ctx, &models.GetListOrderRequest{IncludeDeleted: true})
+	if err != nil || resp.Count != 3 {
+		t.Errorf("GetList with IncludeDeleted = %+v, %v; want count 3", resp, err)
+	}
+
+	rowsAffected, err = store.Order().Update(ctx, &models.UpdateOrder{Id: id, User_id: userID, Book_id: expensive, Payed: 900})
+	if err != nil || rowsAffected != 0 {
+		t.Errorf("Update deleted = %d, %v; want 0 rows", rowsAffected, err)
+	}
+
+	rowsAffected, err = store.Order().Restore(ctx, &models.OrderPrimarKey{Id: id})
+	if err != nil || rowsAffected != 1 {
+		t.Fatalf("Restore = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	if _, err = store.Order().GetByPKey(ctx, &models.OrderPrimarKey{Id: id}); err != nil {
+		t.Errorf("GetByPKey after restore: %v", err)
+	}
+
+	if _, err = store.Order().Delete(ctx, &models.OrderPrimarKey{Id: id}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	rowsAffected, err = store.Order().Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil || rowsAffected != 1 {
+		t.Errorf("Purge = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	// Erasing the user keeps its row, so its orders stay valid.
+	erase := &models.EraseUser{Id: userID, First_name: "Erased", Last_name: "User", Login: "erased-" + userID}
+
+	rowsAffected, err = store.User().Erase(ctx, erase)
+	if err != nil || rowsAffected != 1 {
+		t.Fatalf("Erase = %d, %v; want 1 row", rowsAffected, err)
+	}
+
+	rowsAffected, err = store.User().Erase(ctx, erase)
+	if err != nil || rowsAffected != 0 {
+		t.Errorf("Erase again = %d, %v; want 0 rows", rowsAffected, err)
+	}
+
+	user, err := store.User().GetByPKey(ctx, &models.UserPrimarKey{Id: userID})
+	if err != nil {
+		t.Fatalf("GetByPKey erased user: %v", err)
+	}
+	if user.Login != erase.Login || user.Password != "" || user.Phone_number != "" || user.Email != "" || user.ErasedAt == "" || user.Balance != 5000 {
+		t.Errorf("GetByPKey erased user = %+v", user)
+	}
+
+	resp, err = store.Order().GetList(ctx, &models.GetListOrderRequest{User_id: userID})
+	if err != nil || resp.Count != 2 || resp.Orders[0].FullName != "Erased User" {
+		t.Errorf("GetList of the erased user = %+v, %v; want count 2", resp, err)
+	}
+}
+
+func testTOTP(t *testing.T, store storage.StorageI) {
+	ctx := context.Background()
+
+	userID, err := store.User().Create(ctx, &models.CreateUser{First_name: "Samandar", Last_name: "Foziljonov", Login: "samandar", Password: "secret", Phone_number: "997191323"})
+	if err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	pkey := &models.TOTPPrimarKey{User_id: userID}
+
+	if _, err = store.TOTP().GetByPKey(ctx, pkey); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey missing = %v, want %s", err, errs.CodeNotFound)
+	}
+
+	if err = store.TOTP().Create(ctx, &models.CreateTOTP{User_id: userID, Secret: "OLD"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err = store.TOTP().SetRecoveryCodes(ctx, &models.SetRecoveryCodes{User_id: userID, CodeHashes: []string{"a", "b"}}); err != nil {
+		t.Fatalf("SetRecoveryCodes: %v", err)
+	}
+	if err = store.TOTP().Create(ctx, &models.CreateTOTP{User_id: userID, Secret: "NEW"}); err != nil {
+		t.Fatalf("Create again: %v", err)
+	}
+
+	totp, err := store.TOTP().GetByPKey(ctx, pkey)
+	if err != nil {
+		t.Fatalf("GetByPKey: %v", err)
+	}
+	if totp.Secret != "NEW" || totp.Enabled || totp.LastStep != 0 || totp.RecoveryCodesLeft != 0 {
+		t.Errorf("GetByPKey after Create again = %+v", totp)
+	}
+
+	for i, want := range []int64{1, 0} {
+		rowsAffected, err := store.TOTP().Enable(ctx, pkey)
+		if err != nil {
+			t.Fatalf("Enable: %v", err)
+		}
+		if rowsAffected != want {
+			t.Errorf("Enable #%d rows affected = %d, want %d", i+1, rowsAffected, want)
+		}
+	}
+
+	for _, c := range []struct {
+		step int64
+		want int64
+	}{{10, 1}, {10, 0}, {9, 0}, {11, 1}} {
+		rowsAffected, err := store.TOTP().UseStep(ctx, &models.UseTOTPStep{User_id: userID, Step: c.step})
+		if err != nil {
+			t.Fatalf("UseStep(%d): %v", c.step, err)
+		}
+		if rowsAffected != c.want {
+			t.Errorf("UseStep(%d) rows affected = %d, want %d", c.step, rowsAffected, c.want)
+		}
+	}
+
+	if err = store.TOTP().SetRecoveryCodes(ctx, &models.SetRecoveryCodes{User_id: userID, CodeHashes: []string{"a", "b", "c"}}); err != nil {
+		t.Fatalf("SetRecoveryCodes: %v", err)
+	}
+
+	for _, c := range []struct {
+		hash string
+		want int64
+	}{{"a", 1}, {"a", 0}, {"z", 0}} {
+		rowsAffected, err := store.TOTP().UseRecoveryCode(ctx, &models.UseRecoveryCode{User_id: userID, CodeHash: c.hash})
+		if err != nil {
+			t.Fatalf("UseRecoveryCode(%s): %v", c.hash, err)
+		}
+		if rowsAffected != c.want {
+			t.Errorf("UseRecoveryCode(%s) rows affected = %d, want %d", c.hash, rowsAffected, c.want)
+		}
+	}
+
+	totp, err = store.TOTP().GetByPKey(ctx, pkey)
+	if err != nil {
+		t.Fatalf("GetByPKey after enable: %v", err)
+	}
+	if !totp.Enabled || totp.LastStep != 11 || totp.RecoveryCodesLeft != 2 {
+		t.Errorf("GetByPKey after enable = %+v", totp)
+	}
+
+	rowsAffected, err := store.TOTP().Delete(ctx, pkey)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if rowsAffected != 1 {
+		t.Errorf("Delete rows affected = %d, want 1", rowsAffected)
+	}
+
+	// Purging the user removes its secret and recovery codes as well.
+	store.TOTP().Create(ctx, &models.CreateTOTP{User_id: userID, Secret: "NEW"})
+	store.TOTP().SetRecoveryCodes(ctx, &models.SetRecoveryCodes{User_id: userID, CodeHashes: []string{"a"}})
+
+	purgeUser(t, store, userID)
+	if _, err = store.TOTP().GetByPKey(ctx, pkey); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey after purging the user = %v, want %s", err, errs.CodeNotFound)
+	}
+}
+
+func testAPIKey(t *testing.T, store storage.StorageI) {
+	ctx := context.Background()
+
+	userID, err := store.User().Create(ctx, &models.CreateUser{First_name: "Samandar", Last_name: "Foziljonov", Login: "samandar", Password: "secret", Phone_number: "997191323"})
+	if err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+
+	id, err := store.APIKey().Create(ctx, &models.CreateAPIKey{Name: "export", Scopes: []string{"books:read", "orders:read"}, Prefix: "abc", KeyHash: "hash", Created_by: userID, ExpiresIn: time.Hour})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	key, err := store.APIKey().GetByPKey(ctx, &models.APIKeyPrimarKey{Prefix: "abc"})
+	if err != nil {
+		t.Fatalf("GetByPKey by prefix: %v", err)
+	}
+	if key.Id != id || key.Name != "export" || key.KeyHash != "hash" || len(key.Scopes) != 2 || key.Scopes[1] != "orders:read" || key.Created_by != userID {
+		t.Errorf("GetByPKey = %+v", key)
+	}
+	if key.ExpiresAt == "" || key.Expired || key.LastUsedAt != "" || key.CreatedAt == "" {
+		t.Errorf("GetByPKey timestamps = %+v", key)
+	}
+
+	_, err = store.APIKey().Create(ctx, &models.CreateAPIKey{Name: "dup", Scopes: []string{"books:read"}, Prefix: "abc", KeyHash: "other"})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("Create duplicate prefix = %v, want %s", err, errs.CodeConflict)
+	}
+
+	// A key with a negative lifetime is already expired, and one without
+	// a lifetime never expires.
+	expiredID, err := store.APIKey().Create(ctx, &models.CreateAPIKey{Name: "old", Scopes: []string{"books:read"}, Prefix: "def", KeyHash: "hash", ExpiresIn: -time.Hour})
+	if err != nil {
+		t.Fatalf("Create expired: %v", err)
+	}
+	if key, err = store.APIKey().GetByPKey(ctx, &models.APIKeyPrimarKey{Id: expiredID}); err != nil || !key.Expired {
+		t.Errorf("GetByPKey expired = %+v, %v", key, err)
+	}
+	if _, err = store.APIKey().Create(ctx, &models.CreateAPIKey{Name: "forever", Scopes: []string{"books:read"}, Prefix: "ghi", KeyHash: "hash"}); err != nil {
+		t.Fatalf("Create without expiry: %v", err)
+	}
+	if key, err = store.APIKey().GetByPKey(ctx, &models.APIKeyPrimarKey{Prefix: "ghi"}); err != nil || key.Expired || key.ExpiresAt != "" {
+		t.Errorf("GetByPKey without expiry = %+v, %v", key, err)
+	}
+
+	for i, want := range []int64{1, 0} {
+		rowsAffected, err := store.APIKey().Touch(ctx, &models.TouchAPIKey{Id: id, Interval: time.Hour})
+		if err != nil {
+			t.Fatalf("Touch: %v", err)
+		}
+		if rowsAffected != want {
+			t.Errorf("Touch #%d rows affected = %d, want %d", i+1, rowsAffected, want)
+		}
+	}
+	if key, err = store.APIKey().GetByPKey(ctx, &models.APIKeyPrimarKey{Id: id}); err != nil || key.LastUsedAt == "" {
+		t.Errorf("GetByPKey after Touch = %+v, %v", key, err)
+	}
+
+	checkList(t, "APIKey", func(limit, offset int32) (int32, int, error) {
+		resp, err := store.APIKey().GetList(ctx, &models.GetListAPIKeyRequest{Limit: limit, Offset: offset})
+		if err != nil {
+			return 0, 0, err
+		}
+		return resp.Count, len(resp.APIKeys), nil
+	}, 3)
+
+	// Purging the creator keeps the key.
+	purgeUser(t, store, userID)
+	if key, err = store.APIKey().GetByPKey(ctx, &models.APIKeyPrimarKey{Id: id}); err != nil || key.Created_by != "" {
+		t.Errorf("GetByPKey after purging the creator = %+v, %v", key, err)
+	}
+
+	rowsAffected, err := store.APIKey().Delete(ctx, &models.APIKeyPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if rowsAffected != 1 {
+		t.Errorf("Delete rows affected = %d, want 1", rowsAffected)
+	}
+	if _, err = store.APIKey().GetByPKey(ctx, &models.APIKeyPrimarKey{Id: id}); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey after Delete = %v, want %s", err, errs.CodeNotFound)
+	}
+}
+
+func testIdentity(t *testing.T, store storage.StorageI) {
+	ctx := context.Background()
+
+	userID, err := store.User().Create(ctx, &models.CreateUser{First_name: "Samandar", Last_name: "Foziljonov", Login: "samandar", Password: "secret", Phone_number: "997191323"})
+	if err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+
+	pkey := &models.IdentityPrimarKey{Issuer: "https://idp.example.com", Subject: "248289761001"}
+
+	err = store.Identity().Create(ctx, &models.CreateIdentity{Issuer: pkey.Issuer, Subject: pkey.Subject, User_id: userID, Email: "samandar@example.com"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	identity, err := store.Identity().GetByPKey(ctx, pkey)
+	if err != nil {
+		t.Fatalf("GetByPKey: %v", err)
+	}
+	if identity.User_id != userID || identity.Email != "samandar@example.com" || identity.CreatedAt == "" {
+		t.Errorf("GetByPKey = %+v", identity)
+	}
+
+	err = store.Identity().Create(ctx, &models.CreateIdentity{Issuer: pkey.Issuer, Subject: pkey.Subject, User_id: userID})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("Create duplicate = %v, want %s", err, errs.CodeConflict)
+	}
+
+	_, err = store.Identity().GetByPKey(ctx, &models.IdentityPrimarKey{Issuer: "https://other.example.com", Subject: pkey.Subject})
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey of another issuer = %v, want %s", err, errs.CodeNotFound)
+	}
+
+	other, err := store.User().Create(ctx, &models.CreateUser{First_name: "a", Last_name: "b", Login: "other", Password: "secret", Phone_number: "997191323"})
+	if err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+
+	for _, req := range []*models.CreateIdentity{
+		{Issuer: "https://other.example.com", Subject: pkey.Subject, User_id: userID},
+		{Issuer: pkey.Issuer, Subject: "other", User_id: other},
+	} {
+		if err = store.Identity().Create(ctx, req); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	list, err := store.Identity().GetList(ctx, &models.GetListIdentityRequest{User_id: userID})
+	if err != nil || list.Count != 2 || len(list.Identities) != 2 {
+		t.Fatalf("GetList = %+v, %v; want 2", list, err)
+	}
+
+	rowsAffected, err := store.Identity().Unlink(ctx, &models.UserPrimarKey{Id: other})
+	if err != nil || rowsAffected != 1 {
+		t.Errorf("Unlink = %d, %v; want 1 row", rowsAffected, err)
+	}
+	if _, err = store.Identity().GetByPKey(ctx, &models.IdentityPrimarKey{Issuer: pkey.Issuer, Subject: "other"}); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey after Unlink = %v, want %s", err, errs.CodeNotFound)
+	}
+
+	// Purging the user unlinks it.
+	purgeUser(t, store, userID)
+	if _, err = store.Identity().GetByPKey(ctx, pkey); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey after purging the user = %v, want %s", err, errs.CodeNotFound)
+	}
+}
+
+func testAudit(t *testing.T, store storage.StorageI) {
+	ctx := context.Background()
+
+	var (
+		actorID = "8d1f6f5e-6b5a-4b8e-9a3e-3c1c7a7f0b11"
+		bookID  = "1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed"
+		userID  = "6ec0bd7f-11c0-43da-975e-2a8ad9ebae0b"
+	)
+
+	entries := []*models.CreateAuditLog{
+		{Actor_id: actorID, Action: "create", EntityType: "book", Entity_id: bookID, After: []byte(`{"title":"Go"}`), IP: "10.0.0.1", RequestID: "req-1"},
+		{Actor_id: actorID, Action: "update", EntityType: "book", Entity_id: bookID, Before: []byte(`{"price":10}`), After: []byte(`{"price":12}`)},
+		{Action: "create", EntityType: "user", Entity_id: userID, After: []byte(`{"login":"samandar"}`)},
+	}
+	for _, entry := range entries {
+		if err := store.Audit().Create(ctx, entry); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	resp, err := store.Audit().GetList(ctx, &models.GetListAuditLogRequest{EntityType: "book", Entity_id: bookID})
+	if err != nil {
+		t.Fatalf("GetList: %v", err)
+	}
+	if resp.Count != 2 || len(resp.AuditLogs) != 2 {
+		t.Fatalf("GetList by entity count = %d/%d, want 2", resp.Count, len(resp.AuditLogs))
+	}
+	// Newest first.
+	if got := resp.AuditLogs[0]; got.Action != "update" || string(got.After) != `{"price": 12}` && string(got.After) != `{"price":12}` || got.Actor_id != actorID || got.CreatedAt == "" {
+		t.Errorf("GetList[0] = %+v", got)
+	}
+	if got := resp.AuditLogs[1]; got.Before != nil || got.IP != "10.0.0.1" || got.RequestID != "req-1" {
+		t.Errorf("GetList[1] = %+v", got)
+	}
+
+	for name, tc := range map[string]struct {
+		req  models.GetListAuditLogRequest
+		want int32
+	}{
+		"actor":  {models.GetListAuditLogRequest{Actor_id: actorID}, 2},
+		"action": {models.GetListAuditLogRequest{Action: "create"}, 2},
+		"from":   {models.GetListAuditLogRequest{From: time.Now().Add(-time.Hour)}, 3},
+		"to":     {models.GetListAuditLogRequest{To: time.Now().Add(-time.Hour)}, 0},
+		"paged":  {models.GetListAuditLogRequest{Limit: 1, Offset: 1}, 3},
+	} {
+		resp, err := store.Audit().GetList(ctx, &tc.req)
+		if err != nil {
+			t.Fatalf("GetList %s: %v", name, err)
+		}
+		if resp.Count != tc.want {
+			t.Errorf("GetList %s count = %d, want %d", name, resp.Count, tc.want)
+		}
+	}
+
+	rowsAffected, err := store.Audit().Redact(ctx, &models.RedactAuditLog{EntityType: "book", Entity_id: bookID, Actor_id: actorID})
+	if err != nil || rowsAffected != 4 {
+		t.Fatalf("Redact = %d, %v; want 4 rows", rowsAffected, err)
+	}
+
+	resp, err = store.Audit().GetList(ctx, &models.GetListAuditLogRequest{})
+	if err != nil {
+		t.Fatalf("GetList after Redact: %v", err)
+	}
+	for _, got := range resp.AuditLogs {
+		redacted := got.Entity_id == bookID
+		if redacted != (got.Before == nil && got.After == nil) || got.IP != "" {
+			t.Errorf("GetList after Redact = %+v", got)
+		}
+	}
+
+	rowsAffected, err = store.Audit().DeleteBefore(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteBefore: %v", err)
+	}
+	if rowsAffected != 0 {
+		t.Errorf("DeleteBefore an hour ago rows affected = %d, want 0", rowsAffected)
+	}
+
+	rowsAffected, err = store.Audit().DeleteBefore(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteBefore: %v", err)
+	}
+	if rowsAffected != 3 {
+		t.Errorf("DeleteBefore in an hour rows affected = %d, want 3", rowsAffected)
+	}
+}
+
+func testTx(t *testing.T, store storage.StorageI) {
+	ctx := context.Background()
+
+	var id string
+
+	err := store.WithTx(ctx, func(tx storage.StorageI) error {
+		var err error
+		id, err = tx.Book().Create(ctx, &models.CreateBook{Title: "committed", Price: 1})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx commit: %v", err)
+	}
+
+	if _, err = store.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id}); err != nil {
+		t.Errorf("GetByPKey committed book: %v", err)
+	}
+
+	rollback := errors.New("rollback")
+
+	err = store.WithTx(ctx, func(tx storage.StorageI) error {
+		var err error
+		id, err = tx.Book().Create(ctx, &models.CreateBook{Title: "rolled back", Price: 1})
+		if err != nil {
+			return err
+		}
+
+		return tx.WithTx(ctx, func(tx storage.StorageI) error {
+			return rollback
+		})
+	})
+	if err != rollback {
+		t.Fatalf("WithTx rollback = %v, want %v", err, rollback)
+	}
+
+	_, err = store.Book().GetByPKey(ctx, &models.BookPrimarKey{Id: id})
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey rolled back book = %v, want %s", err, errs.CodeNotFound)
+	}
+
+	// A panic in fn, recovered by the caller, rolls back as well and
+	// leaves no transaction holding a connection.
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("WithTx did not pass the panic on")
+			}
+		}()
+
+		store.WithTx(ctx, func(tx storage.StorageI) error {
+			id, err = tx.Book().Create(ctx, &models.CreateBook{Title: "panicked", Price: 1})
+			if err != nil {
+				return err
+			}
+			panic("fn panicked")
+		})
+	}()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err = store.Book().GetByPKey(timeoutCtx, &models.BookPrimarKey{Id: id})
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByPKey book of a panicked transaction = %v, want %s", err, errs.CodeNotFound)
+	}
+}
+
+// checkList verifies limit/offset handling of a GetList call against a table
+// holding exactly total rows.
+func checkList(t *testing.T, name string, list func(limit, offset int32) (int32, int, error), total int32) {
+	t.Helper()
+
+	cases := []struct {
+		limit, offset int32
+		want          int
+	}{
+		{0, 0, int(total)},
+		{2, 0, 2},
+		{2, 2, int(total) - 2},
+		{0, 1, int(total) - 1},
+	}
+
+	for _, c := range cases {
+		count, n, err := list(c.limit, c.offset)
+		if err != nil {
+			t.Fatalf("%s GetList(limit=%d, offset=%d): %v", name, c.limit, c.offset, err)
+		}
+		if count != total || n != c.want {
+			t.Errorf("%s GetList(limit=%d, offset=%d) = count %d, %d rows; want count %d, %d rows",
+				name, c.limit, c.offset, count, n, total, c.want)
+		}
+	}
+}
+
+func createUser(t *testing.T, store storage.StorageI, login string) string {
+	t.Helper()
+
+	id, err := store.User().Create(context.Background(), &models.CreateUser{
+		First_name:   "Samandar",
+		Last_name:    "Foziljonov",
+		Login:        login,
+		Password:     "samandevop",
+		Phone_number: "997191323",
+		Balance:      5000,
+	})
+	if err != nil {
+		t.Fatalf("Create user %s: %v", login, err)
+	}
+
+	return id
+}
+
+// newID returns an id that is guaranteed not to exist yet: the id of a book
+// that was created, deleted and purged again.
+func newID(t *testing.T, store storage.StorageI) string {
+	t.Helper()
+
+	ctx := context.Background()
+
+	id, err := store.Book().Create(ctx, &models.CreateBook{Title: "tmp"})
+	if err != nil {
+		t.Fatalf("Create book: %v", err)
+	}
+
+	_, err = store.Book().Delete(ctx, &models.BookPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("Delete book: %v", err)
+	}
+
+	_, err = store.Book().Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Purge books: %v", err)
+	}
+
+	return id
+}
+
+// purgeUser deletes the user and removes it for good.
+func purgeUser(t *testing.T, store storage.StorageI, id string) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	_, err := store.User().Delete(ctx, &models.UserPrimarKey{Id: id})
+	if err != nil {
+		t.Fatalf("Delete user: %v", err)
+	}
+
+	_, err = store.User().Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Purge users: %v", err)
+	}
+}